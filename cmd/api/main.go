@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"os"
@@ -16,6 +17,10 @@ import (
 	"github.com/fedorovmatvey/involta-test/internal/handler"
 	"github.com/fedorovmatvey/involta-test/internal/service"
 	"github.com/fedorovmatvey/involta-test/internal/storage"
+	"github.com/fedorovmatvey/involta-test/internal/version"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	_ "github.com/restream/reindexer/v3/bindings/cproto"
 )
 
@@ -43,16 +48,35 @@ func run() error {
 		return fmt.Errorf("config load: %w", err)
 	}
 
-	slog.Info("Starting application", "env", cfg.App.Env, "port", cfg.Server.Port)
+	logLevel := parseLogLevel(cfg.App.LogLevel)
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: logLevel})))
 
-	store, err := storage.New(cfg.Reindexer.DSN, cfg.Reindexer.Namespace)
+	slog.Info("Starting application", "env", cfg.App.Env, "port", cfg.Server.Port, "version", version.Version, "commit", version.Commit)
+
+	indexes := make([]storage.Index, 0, len(cfg.Reindexer.Indexes))
+	for _, idx := range cfg.Reindexer.Indexes {
+		indexes = append(indexes, storage.Index{Field: idx.Field, Type: idx.Type, Collate: idx.Collate})
+	}
+
+	nsOpts := storage.NamespaceOptions{
+		InMemory:              cfg.Reindexer.Options.InMemory,
+		DropOnIndexesConflict: cfg.Reindexer.Options.DropOnIndexesConflict,
+		DropOnFileFormatError: cfg.Reindexer.Options.DropOnFileFormatError,
+		DisableObjCache:       cfg.Reindexer.Options.DisableObjCache,
+		ObjCacheSize:          cfg.Reindexer.Options.ObjCacheSize,
+	}
+
+	store, err := storage.New(cfg.Reindexer.DSN, cfg.Reindexer.Namespace, indexes, cfg.Reindexer.AutoMigrate, nsOpts)
 	if err != nil {
 		return fmt.Errorf("storage init: %w", err)
 	}
 
+	// A reconnecting store replaces the connection it was given, so close
+	// whichever connection is current at shutdown.
+	var storeCloser io.Closer = store
 	defer func() {
 		slog.Info("Closing storage connection...")
-		if err := store.Close(); err != nil {
+		if err := storeCloser.Close(); err != nil {
 			slog.Error("Failed to close storage", "error", err)
 		}
 	}()
@@ -65,14 +89,112 @@ func run() error {
 	}
 	slog.Info("Storage connection established")
 
-	documentCache := cache.New(cfg.Cache.TTL, cfg.Cache.CleanupInterval, cfg.Cache.Capacity)
+	documentCache, err := newCache(cfg.Cache)
+	if err != nil {
+		return fmt.Errorf("cache init: %w", err)
+	}
 	defer func() {
 		slog.Info("Stopping cache cleanup...")
 		documentCache.Stop()
 	}()
 
-	srv := service.New(store, documentCache)
-	h := handler.New(srv)
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+
+	var documentStore storage.DocumentStore
+	documentStore, storeCloser = withReconnect(cfg.Reindexer, store, cfg.Reindexer.DSN, indexes, nsOpts)
+	if cfg.Reindexer.ReadDSN != "" {
+		// The replica is read-only, so indexes are left to the primary.
+		replica, err := storage.New(cfg.Reindexer.ReadDSN, cfg.Reindexer.Namespace, nil, false, nsOpts)
+		if err != nil {
+			return fmt.Errorf("read replica init: %w", err)
+		}
+		replicaStore, replicaCloser := withReconnect(cfg.Reindexer, replica, cfg.Reindexer.ReadDSN, nil, nsOpts)
+		defer func() {
+			slog.Info("Closing read replica connection...")
+			if err := replicaCloser.Close(); err != nil {
+				slog.Error("Failed to close read replica", "error", err)
+			}
+		}()
+		documentStore = storage.NewReadSplit(documentStore, replicaStore)
+		slog.Info("Routing document reads to the read replica")
+	}
+	// Timed sits inside the breaker so fast-failed calls do not skew latencies.
+	documentStore = storage.NewTimed(documentStore, registry)
+	if cfg.Reindexer.BreakerThreshold > 0 {
+		documentStore = storage.NewCircuitBreaker(documentStore, cfg.Reindexer.BreakerThreshold, cfg.Reindexer.BreakerCooldown)
+	}
+
+	if cfg.Server.EmptyList != "200" && cfg.Server.EmptyList != "204" {
+		return fmt.Errorf("server config: unknown empty_list status %q", cfg.Server.EmptyList)
+	}
+	if err := handler.CheckIDFormat(cfg.Server.IDFormat); err != nil {
+		return fmt.Errorf("server config: %w", err)
+	}
+
+	fields, err := fieldPolicy(cfg.Validation)
+	if err != nil {
+		return fmt.Errorf("validation config: %w", err)
+	}
+
+	srv := service.New(documentStore, documentCache,
+		service.WithLimits(service.Limits{
+			MaxTitleLength:       cfg.Validation.MaxTitleLength,
+			MaxDescriptionLength: cfg.Validation.MaxDescriptionLength,
+			MaxItemNameLength:    cfg.Validation.MaxItemNameLength,
+			MaxItemValueLength:   cfg.Validation.MaxItemValueLength,
+			MaxItemContentLength: cfg.Validation.MaxItemContentLength,
+			TruncateItems:        cfg.Validation.OversizedItems == "truncate",
+			MaxItemSort:          cfg.Validation.MaxItemSort,
+			MaxBatchSize:         cfg.Validation.MaxBatchSize,
+			MaxTags:              cfg.Validation.MaxTags,
+			MaxTagLength:         cfg.Validation.MaxTagLength,
+		}),
+		service.WithFieldPolicy(fields),
+		service.WithMetrics(registry),
+		service.WithRevalidation(cfg.Cache.RevalidateAfter),
+		service.WithProcessTimeout(cfg.App.ProcessTimeout),
+		service.WithBatchConcurrency(cfg.App.BatchConcurrency),
+		service.WithListTimings(cfg.App.ListTimings),
+		service.WithWarmup(cfg.Cache.WarmupConcurrency, cfg.Cache.WarmupTimeout),
+	)
+	h := handler.New(srv,
+		handler.WithDebugErrors(cfg.App.Env != "production"),
+		handler.WithEnv(cfg.App.Env),
+		handler.WithBodyLogging(debugBodyLogLimit(logLevel)),
+		handler.WithCacheControl(cacheMaxAge(cfg)),
+		handler.WithRequestIDHeader(cfg.Server.RequestIDHeader),
+		handler.WithJSONContentType(cfg.Server.RequireJSON, cfg.Server.AllowJSONParams),
+		handler.WithIDFormat(cfg.Server.IDFormat),
+		handler.WithLogSampling(cfg.App.LogSampleRate, cfg.App.LogSlowRequestAfter),
+		handler.WithMaxConcurrentRequests(cfg.Server.MaxConcurrentRequests),
+		handler.WithMaxURLLength(cfg.Server.MaxURLLength),
+		handler.WithRetryAfter(cfg.Server.RetryAfter, cfg.Server.RetryAfterJitter),
+		handler.WithStrictPagination(cfg.Server.StrictPagination),
+		handler.WithEmptyListNoContent(cfg.Server.EmptyList == "204"),
+		handler.WithSwagger(cfg.App.SwaggerEnabled()),
+		handler.WithCompression(cfg.Server.Compression, cfg.Server.CompressionLevel),
+		handler.WithMetricsHandler(promhttp.HandlerFor(registry, promhttp.HandlerOpts{})),
+	)
+
+	if cfg.App.SeedFile != "" {
+		if _, err := srv.Seed(ctx, cfg.App.SeedFile); err != nil {
+			slog.Error("Failed to seed documents", "path", cfg.App.SeedFile, "error", err)
+		}
+	}
+
+	if cfg.Cache.Warmup {
+		h.SetReady(false)
+		go func() {
+			slog.Info("Warming up cache...", "size", cfg.Cache.WarmupSize)
+			loaded, err := srv.Warmup(ctx, cfg.Cache.WarmupSize)
+			if err != nil {
+				slog.Error("Cache warmup failed", "loaded", loaded, "error", err)
+			}
+			h.SetReady(true)
+			slog.Info("Cache warmup finished", "loaded", loaded)
+		}()
+	}
 
 	router := h.InitRoutes()
 
@@ -101,7 +223,7 @@ func run() error {
 		slog.Info("Shutting down server...")
 	}
 
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	shutdownCtx, shutdownCancel := shutdownContext(cfg.Server.ShutdownTimeout)
 	defer shutdownCancel()
 
 	if err := httpServer.Shutdown(shutdownCtx); err != nil {
@@ -111,3 +233,105 @@ func run() error {
 	slog.Info("Server stopped gracefully")
 	return nil
 }
+
+func parseLogLevel(value string) slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(value)); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+// debugBodyLogLimit enables body logging only when running at debug level.
+func debugBodyLogLimit(level slog.Level) int {
+	if level <= slog.LevelDebug {
+		return 4096
+	}
+	return 0
+}
+
+// cacheMaxAge is the Cache-Control max-age for document reads, defaulting to
+// the cache TTL so clients do not hold responses longer than the server does.
+func cacheMaxAge(cfg *config.Config) time.Duration {
+	if cfg.Server.CacheMaxAge > 0 {
+		return cfg.Server.CacheMaxAge
+	}
+	return cfg.Cache.TTL
+}
+
+// shutdownContext bounds how long in-flight requests may drain. It is derived
+// from a fresh context because the signal context is already cancelled.
+func shutdownContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+// withReconnect wraps store, connected to dsn, so that it reconnects when the
+// connection drops, if reconnecting is configured. The returned closer closes
+// whichever connection is current.
+func withReconnect(cfg config.ReindexerConfig, store *storage.Storage, dsn string, indexes []storage.Index, nsOpts storage.NamespaceOptions) (storage.DocumentStore, io.Closer) {
+	if cfg.ReconnectAttempts <= 0 {
+		return store, store
+	}
+
+	reconnecting := storage.NewReconnecting(store, func() (storage.DocumentStore, error) {
+		next, err := storage.New(dsn, cfg.Namespace, indexes, false, nsOpts)
+		if err != nil {
+			return nil, err
+		}
+		return next, nil
+	}, cfg.ReconnectAttempts, cfg.ReconnectBackoff, cfg.ReconnectMaxBackoff)
+	return reconnecting, reconnecting
+}
+
+func fieldPolicy(cfg config.ValidationConfig) (service.FieldPolicy, error) {
+	if cfg.DisallowedFields != "strip" && cfg.DisallowedFields != "reject" {
+		return service.FieldPolicy{}, fmt.Errorf("unknown disallowed_fields mode %q", cfg.DisallowedFields)
+	}
+	policy := service.FieldPolicy{
+		Allowed: cfg.AllowedFields,
+		Denied:  cfg.DeniedFields,
+		Reject:  cfg.DisallowedFields == "reject",
+	}
+	return policy, policy.Validate()
+}
+
+func newCache(cfg config.CacheConfig) (cache.DocumentCache, error) {
+	switch cfg.Type {
+	case "", "memory":
+		policy := cache.EvictionPolicy(cfg.EvictionPolicy)
+		if policy != cache.EvictionRandom && policy != cache.EvictionNoAdmit {
+			return nil, fmt.Errorf("unknown cache eviction policy %q", cfg.EvictionPolicy)
+		}
+		persistPath := cfg.PersistPath
+		if persistPath != "" && cfg.InvalidationURL != "" {
+			// A reloaded cache missed every invalidation published while the
+			// instance was down and would serve documents changed elsewhere.
+			slog.Warn("Cache persistence is disabled with cache invalidation", "persist_path", persistPath)
+			persistPath = ""
+		}
+		memory := cache.New(cfg.TTL, cfg.CleanupInterval, cfg.Capacity, cfg.TTLJitterPercent,
+			cache.WithTTLBounds(cfg.MinTTL, cfg.MaxTTL), cache.WithStaleGrace(cfg.StaleGrace),
+			cache.WithAutoCapacity(cfg.AutoCapacity, cfg.EntrySize), cache.WithCleanupBatchSize(cfg.CleanupBatch),
+			cache.WithEvictionPolicy(policy), cache.WithPersistPath(persistPath))
+		if cfg.InvalidationURL == "" {
+			return memory, nil
+		}
+
+		bus, err := cache.NewRedisBus(cfg.InvalidationURL)
+		if err != nil {
+			memory.Stop()
+			return nil, err
+		}
+		invalidating, err := cache.NewInvalidating(memory, bus)
+		if err != nil {
+			bus.Close()
+			memory.Stop()
+			return nil, err
+		}
+		return invalidating, nil
+	case "redis":
+		return cache.NewRedis(cfg.RedisURL, cache.ClampTTL(cfg.TTL, cfg.MinTTL, cfg.MaxTTL))
+	default:
+		return nil, fmt.Errorf("unknown cache type %q", cfg.Type)
+	}
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShutdownContext_UsesConfiguredTimeout(t *testing.T) {
+	before := time.Now()
+	ctx, cancel := shutdownContext(5 * time.Second)
+	defer cancel()
+
+	deadline, ok := ctx.Deadline()
+	assert.True(t, ok)
+	assert.WithinDuration(t, before.Add(5*time.Second), deadline, 100*time.Millisecond)
+	assert.NoError(t, ctx.Err())
+}
@@ -0,0 +1,138 @@
+package apperror
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Resource string
+
+const (
+	ResourceDocument    Resource = "document"
+	ResourceItem        Resource = "item"
+	ResourceSecondLevel Resource = "second_level"
+)
+
+// Machine-readable error codes sent in the "code" field of error responses.
+// Typed errors derive theirs from the resource where it applies, e.g.
+// "document_not_found".
+const (
+	CodeBadRequest           = "bad_request"
+	CodeNotFound             = "not_found"
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodeConflict             = "conflict"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeValidationFailed     = "validation_failed"
+	CodeInvalidParameter     = "invalid_parameter"
+	CodeRateLimited          = "rate_limited"
+	CodeInternal             = "internal_error"
+	CodeUnavailable          = "unavailable"
+)
+
+// NotFoundError reports a missing resource together with the ID that was
+// looked up.
+type NotFoundError struct {
+	Resource Resource
+	ID       string
+}
+
+func NotFound(resource Resource, id string) error {
+	return &NotFoundError{Resource: resource, ID: id}
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s %q not found", e.Resource, e.ID)
+}
+
+// Message is the client-facing description, e.g. "second level not found".
+func (e *NotFoundError) Message() string {
+	return strings.ReplaceAll(string(e.Resource), "_", " ") + " not found"
+}
+
+// Code is the machine-readable error code, e.g. "second_level_not_found".
+func (e *NotFoundError) Code() string {
+	return string(e.Resource) + "_not_found"
+}
+
+// ConflictError reports a resource that already exists under the given ID.
+type ConflictError struct {
+	Resource Resource
+	ID       string
+}
+
+func Conflict(resource Resource, id string) error {
+	return &ConflictError{Resource: resource, ID: id}
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s %q already exists", e.Resource, e.ID)
+}
+
+// Message is the client-facing description, e.g. "document already exists".
+func (e *ConflictError) Message() string {
+	return strings.ReplaceAll(string(e.Resource), "_", " ") + " already exists"
+}
+
+// Code is the machine-readable error code, e.g. "document_already_exists".
+func (e *ConflictError) Code() string {
+	return string(e.Resource) + "_already_exists"
+}
+
+// ValidationError reports a field that violates a configured constraint.
+type ValidationError struct {
+	Field   string
+	Limit   int
+	Message string
+}
+
+func Validation(field string, limit int, message string) error {
+	return &ValidationError{Field: field, Limit: limit, Message: message}
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+func (e *ValidationError) Code() string {
+	return CodeValidationFailed
+}
+
+// InvalidParameterError reports a request parameter that cannot be used, such
+// as an unknown sort field.
+type InvalidParameterError struct {
+	Parameter string
+	Message   string
+}
+
+func InvalidParameter(parameter, message string) error {
+	return &InvalidParameterError{Parameter: parameter, Message: message}
+}
+
+func (e *InvalidParameterError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Parameter, e.Message)
+}
+
+func (e *InvalidParameterError) Code() string {
+	return CodeInvalidParameter
+}
+
+// UnavailableError reports a dependency that is refusing calls for now, such
+// as storage behind an open circuit breaker. RetryAfter is how long until it
+// may accept calls again.
+type UnavailableError struct {
+	Message    string
+	RetryAfter time.Duration
+}
+
+func Unavailable(message string, retryAfter time.Duration) error {
+	return &UnavailableError{Message: message, RetryAfter: retryAfter}
+}
+
+func (e *UnavailableError) Error() string {
+	return e.Message
+}
+
+func (e *UnavailableError) Code() string {
+	return CodeUnavailable
+}
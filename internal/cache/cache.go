@@ -1,14 +1,64 @@
 package cache
 
 import (
+	"log/slog"
+	"math/rand/v2"
 	"sync"
 	"time"
 
 	"github.com/fedorovmatvey/involta-test/internal/model"
 )
 
+// DocumentCache is implemented by every cache backend.
+type DocumentCache interface {
+	Get(id string) (*model.Document, bool)
+	Set(id string, doc *model.Document)
+	Delete(id string)
+	Stop()
+}
+
+// StaleReader is implemented by caches that keep expired entries for a grace
+// period so they can be served when the source of truth is unavailable.
+type StaleReader interface {
+	GetStale(id string) (*model.Document, bool)
+}
+
+// AgeReader is implemented by caches that know how long ago an entry was
+// stored.
+type AgeReader interface {
+	GetWithAge(id string) (*model.Document, time.Duration, bool)
+}
+
+// Sizer is implemented by caches that can count their entries.
+type Sizer interface {
+	Size() int
+}
+
+// EvictReason says why an entry left the cache.
+type EvictReason string
+
+const (
+	EvictCapacity EvictReason = "capacity"
+	EvictExpired  EvictReason = "expired"
+	EvictDeleted  EvictReason = "deleted"
+)
+
+// EvictionPolicy decides what happens when a new entry arrives at a full
+// cache.
+type EvictionPolicy string
+
+const (
+	// EvictionRandom evicts an arbitrary entry to make room.
+	EvictionRandom EvictionPolicy = "random"
+	// EvictionNoAdmit keeps the existing entries and drops the new one, so
+	// cold documents cannot push out hot ones. Reads still go to storage. An
+	// expired entry found on the way is removed to make room instead.
+	EvictionNoAdmit EvictionPolicy = "no-admit"
+)
+
 type cacheItem struct {
 	document  *model.Document
+	storedAt  time.Time
 	expiresAt time.Time
 }
 
@@ -16,19 +66,45 @@ type Cache struct {
 	mu              sync.RWMutex
 	items           map[string]*cacheItem
 	ttl             time.Duration
+	staleGrace      time.Duration
+	jitterPercent   int
 	capacity        int
 	cleanupInterval time.Duration
-	stopCleanup     chan struct{}
+	// cleanupBatchSize bounds how many entries cleanup checks per lock hold;
+	// zero checks them all at once.
+	cleanupBatchSize int
+	stopCleanup      chan struct{}
+	// onEvict is called outside the lock for every removed entry.
+	onEvict func(id string, reason EvictReason)
+	// auto is set when capacity follows available memory.
+	auto *autoSizing
+	// evictionPolicy is EvictionRandom unless set otherwise.
+	evictionPolicy EvictionPolicy
+	// persistPath is where Stop saves the cache and New reloads it from;
+	// empty disables persistence.
+	persistPath string
 }
 
-func New(ttl, cleanupInterval time.Duration, capacity int) *Cache {
+// New creates a cache whose entries live for ttl, randomly shifted by up to
+// jitterPercent of ttl in either direction so that entries set together do
+// not expire together.
+func New(ttl, cleanupInterval time.Duration, capacity, jitterPercent int, opts ...Option) *Cache {
 	c := &Cache{
 		items:           make(map[string]*cacheItem),
 		ttl:             ttl,
+		jitterPercent:   jitterPercent,
 		capacity:        capacity,
 		cleanupInterval: cleanupInterval,
 		stopCleanup:     make(chan struct{}),
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.persistPath != "" {
+		if err := c.load(); err != nil {
+			slog.Error("Failed to load cache from disk, starting empty", "path", c.persistPath, "error", err)
+		}
+	}
 
 	go c.startCleanup()
 
@@ -45,44 +121,158 @@ func (c *Cache) Get(id string) (*model.Document, bool) {
 	}
 
 	if time.Now().After(item.expiresAt) {
-		c.mu.Lock()
-		item, exists = c.items[id]
-		if exists && time.Now().After(item.expiresAt) {
-			delete(c.items, id)
-		}
+		return c.expire(id)
+	}
+
+	return item.document, true
+}
+
+// expire removes id if it is still expired under the write lock. A Set may
+// have refreshed the entry since the read lock was released, in which case
+// the fresh document is returned as a hit.
+func (c *Cache) expire(id string) (*model.Document, bool) {
+	c.mu.Lock()
+
+	item, exists := c.items[id]
+	if !exists {
 		c.mu.Unlock()
 		return nil, false
 	}
+	now := time.Now()
+	if !now.After(item.expiresAt) {
+		c.mu.Unlock()
+		return item.document, true
+	}
+
+	evicted := c.pastGrace(item, now)
+	if evicted {
+		delete(c.items, id)
+	}
+	c.mu.Unlock()
 
+	if evicted {
+		c.notifyEvict(id, EvictExpired)
+	}
+	return nil, false
+}
+
+// GetWithAge is Get that also reports how long ago the entry was stored.
+func (c *Cache) GetWithAge(id string) (*model.Document, time.Duration, bool) {
+	c.mu.RLock()
+	item, exists := c.items[id]
+	c.mu.RUnlock()
+
+	if !exists || time.Now().After(item.expiresAt) {
+		doc, ok := c.Get(id)
+		return doc, 0, ok
+	}
+	return item.document, time.Since(item.storedAt), true
+}
+
+// GetStale returns an entry even if it has expired, as long as it is within
+// the stale grace period.
+func (c *Cache) GetStale(id string) (*model.Document, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	item, exists := c.items[id]
+	if !exists || c.pastGrace(item, time.Now()) {
+		return nil, false
+	}
 	return item.document, true
 }
 
+// pastGrace reports whether an entry may no longer be served even as stale.
+func (c *Cache) pastGrace(item *cacheItem, now time.Time) bool {
+	return now.After(item.expiresAt.Add(c.staleGrace))
+}
+
 func (c *Cache) Set(id string, doc *model.Document) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
 
+	now := time.Now()
+	evicted, reason := "", EvictCapacity
 	if _, exists := c.items[id]; !exists && c.capacity > 0 && len(c.items) >= c.capacity {
-		c.evictRandom()
+		if c.evictionPolicy == EvictionNoAdmit {
+			evicted, reason = c.evictExpired(now), EvictExpired
+			if evicted == "" {
+				c.mu.Unlock()
+				return
+			}
+		} else {
+			evicted = c.evictRandom()
+		}
 	}
 
 	c.items[id] = &cacheItem{
 		document:  doc,
-		expiresAt: time.Now().Add(c.ttl),
+		storedAt:  now,
+		expiresAt: now.Add(c.entryTTL()),
+	}
+	c.mu.Unlock()
+
+	if evicted != "" {
+		c.notifyEvict(evicted, reason)
 	}
 }
 
-func (c *Cache) evictRandom() {
+func (c *Cache) entryTTL() time.Duration {
+	if c.jitterPercent <= 0 || c.ttl <= 0 {
+		return c.ttl
+	}
+
+	delta := c.ttl * time.Duration(c.jitterPercent) / 100
+	if delta <= 0 {
+		return c.ttl
+	}
+	return c.ttl - delta + time.Duration(rand.Int64N(int64(2*delta)+1))
+}
+
+// expiredScanLimit bounds how many entries evictExpired looks at, so refusing
+// a new entry in a cache full of live ones stays cheap.
+const expiredScanLimit = 64
+
+// evictExpired removes an expired entry and returns its key, or returns ""
+// when none of the entries it looked at has expired. Cleanup would remove the
+// entry eventually, but it may run less often than entries expire.
+func (c *Cache) evictExpired(now time.Time) string {
+	scanned := 0
+	for key, item := range c.items {
+		if now.After(item.expiresAt) {
+			delete(c.items, key)
+			return key
+		}
+		if scanned++; scanned >= expiredScanLimit {
+			break
+		}
+	}
+	return ""
+}
+
+// evictRandom removes an arbitrary entry and returns its key.
+func (c *Cache) evictRandom() string {
 	for key := range c.items {
 		delete(c.items, key)
-		return
+		return key
 	}
+	return ""
 }
 
 func (c *Cache) Delete(id string) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
-
+	_, exists := c.items[id]
 	delete(c.items, id)
+	c.mu.Unlock()
+
+	if exists {
+		c.notifyEvict(id, EvictDeleted)
+	}
+}
+
+func (c *Cache) notifyEvict(id string, reason EvictReason) {
+	if c.onEvict != nil {
+		c.onEvict(id, reason)
+	}
 }
 
 func (c *Cache) Clear() {
@@ -100,38 +290,75 @@ func (c *Cache) startCleanup() {
 		select {
 		case <-ticker.C:
 			c.cleanup()
+			c.resize()
 		case <-c.stopCleanup:
 			return
 		}
 	}
 }
 
+// cleanup removes entries past their stale grace. With a batch size set, the
+// keys are copied once and then checked and removed batchSize at a time, so
+// readers wait for at most one batch instead of a scan of the whole cache.
 func (c *Cache) cleanup() {
-	keysToDelete := make([]string, 0)
 	now := time.Now()
 
-	c.mu.RLock()
-	for key, item := range c.items {
-		if now.After(item.expiresAt) {
-			keysToDelete = append(keysToDelete, key)
+	if c.cleanupBatchSize <= 0 {
+		keysToDelete := make([]string, 0)
+		c.mu.RLock()
+		for key, item := range c.items {
+			if c.pastGrace(item, now) {
+				keysToDelete = append(keysToDelete, key)
+			}
 		}
+		c.mu.RUnlock()
+
+		c.removeExpired(keysToDelete, now)
+		return
+	}
+
+	c.mu.RLock()
+	keys := make([]string, 0, len(c.items))
+	for key := range c.items {
+		keys = append(keys, key)
 	}
 	c.mu.RUnlock()
 
-	if len(keysToDelete) > 0 {
-		c.mu.Lock()
-		for _, key := range keysToDelete {
-			item, exists := c.items[key]
-			if exists && now.After(item.expiresAt) {
-				delete(c.items, key)
-			}
+	for start := 0; start < len(keys); start += c.cleanupBatchSize {
+		c.removeExpired(keys[start:min(start+c.cleanupBatchSize, len(keys))], now)
+	}
+}
+
+// removeExpired deletes those of keys that are still past grace at now.
+func (c *Cache) removeExpired(keys []string, now time.Time) {
+	if len(keys) == 0 {
+		return
+	}
+
+	evicted := make([]string, 0, len(keys))
+	c.mu.Lock()
+	for _, key := range keys {
+		item, exists := c.items[key]
+		if exists && c.pastGrace(item, now) {
+			delete(c.items, key)
+			evicted = append(evicted, key)
 		}
-		c.mu.Unlock()
+	}
+	c.mu.Unlock()
+
+	for _, key := range evicted {
+		c.notifyEvict(key, EvictExpired)
 	}
 }
 
+// Stop ends cleanup and, with a persist path set, saves the cache to disk.
 func (c *Cache) Stop() {
 	close(c.stopCleanup)
+	if c.persistPath != "" {
+		if err := c.save(); err != nil {
+			slog.Error("Failed to save cache to disk", "path", c.persistPath, "error", err)
+		}
+	}
 }
 
 func (c *Cache) Size() int {
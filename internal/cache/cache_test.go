@@ -0,0 +1,319 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fedorovmatvey/involta-test/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache_Set_JitteredTTL(t *testing.T) {
+	ttl := time.Hour
+	c := New(ttl, time.Hour, 0, 10)
+	defer c.Stop()
+
+	before := time.Now()
+	for i := 0; i < 20; i++ {
+		id := fmt.Sprintf("doc-%d", i)
+		c.Set(id, &model.Document{ID: id})
+	}
+	after := time.Now()
+
+	minExpiry := before.Add(ttl - 6*time.Minute)
+	maxExpiry := after.Add(ttl + 6*time.Minute)
+	expiries := make(map[time.Time]bool)
+
+	for _, item := range c.items {
+		assert.False(t, item.expiresAt.Before(minExpiry))
+		assert.False(t, item.expiresAt.After(maxExpiry))
+		expiries[item.expiresAt] = true
+	}
+	assert.Greater(t, len(expiries), 1)
+}
+
+func TestCache_Set_NoJitter(t *testing.T) {
+	c := New(time.Hour, time.Hour, 0, 0)
+	defer c.Stop()
+
+	assert.Equal(t, time.Hour, c.entryTTL())
+}
+
+func TestCache_New_TTLBounds(t *testing.T) {
+	tests := []struct {
+		name string
+		ttl  time.Duration
+		want time.Duration
+	}{
+		{"too small", time.Millisecond, time.Second},
+		{"too large", 72 * time.Hour, 24 * time.Hour},
+		{"in range", 15 * time.Minute, 15 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(tt.ttl, time.Hour, 0, 0, WithTTLBounds(time.Second, 24*time.Hour))
+			defer c.Stop()
+
+			assert.Equal(t, tt.want, c.ttl)
+		})
+	}
+}
+
+func TestClampTTL_ZeroBoundsDisabled(t *testing.T) {
+	assert.Equal(t, time.Millisecond, ClampTTL(time.Millisecond, 0, 0))
+}
+
+func TestCache_Expire_RefreshedEntryIsHit(t *testing.T) {
+	c := New(time.Hour, time.Hour, 0, 0)
+	defer c.Stop()
+
+	// Get saw an expired entry, then a concurrent Set refreshed it before
+	// the write lock was taken.
+	c.Set("doc-1", &model.Document{ID: "doc-1", Title: "fresh"})
+
+	doc, ok := c.expire("doc-1")
+
+	assert.True(t, ok)
+	assert.Equal(t, "fresh", doc.Title)
+	assert.Contains(t, c.items, "doc-1")
+}
+
+func TestCache_Get_ConcurrentRefreshSurvivesExpiry(t *testing.T) {
+	c := New(time.Hour, time.Hour, 0, 0)
+	defer c.Stop()
+
+	for i := 0; i < 1000; i++ {
+		c.mu.Lock()
+		c.items["doc-1"] = &cacheItem{document: &model.Document{ID: "doc-1"}, expiresAt: time.Now().Add(-time.Second)}
+		c.mu.Unlock()
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			c.Set("doc-1", &model.Document{ID: "doc-1", Title: "fresh"})
+		}()
+		c.Get("doc-1")
+		<-done
+
+		doc, ok := c.Get("doc-1")
+		assert.True(t, ok)
+		assert.Equal(t, "fresh", doc.Title)
+	}
+}
+
+func TestCache_GetStale_WithinGrace(t *testing.T) {
+	c := New(time.Hour, time.Hour, 0, 0, WithStaleGrace(time.Hour))
+	defer c.Stop()
+
+	c.Set("doc-1", &model.Document{ID: "doc-1"})
+	c.items["doc-1"].expiresAt = time.Now().Add(-time.Minute)
+
+	_, ok := c.Get("doc-1")
+	assert.False(t, ok)
+
+	doc, ok := c.GetStale("doc-1")
+	assert.True(t, ok)
+	assert.Equal(t, "doc-1", doc.ID)
+
+	c.items["doc-1"].expiresAt = time.Now().Add(-2 * time.Hour)
+	_, ok = c.GetStale("doc-1")
+	assert.False(t, ok)
+}
+
+func TestCache_GetStale_NoGraceDropsExpired(t *testing.T) {
+	c := New(time.Hour, time.Hour, 0, 0)
+	defer c.Stop()
+
+	c.Set("doc-1", &model.Document{ID: "doc-1"})
+	c.items["doc-1"].expiresAt = time.Now().Add(-time.Minute)
+
+	_, ok := c.Get("doc-1")
+	assert.False(t, ok)
+	_, ok = c.GetStale("doc-1")
+	assert.False(t, ok)
+	assert.NotContains(t, c.items, "doc-1")
+}
+
+func TestCache_GetWithAge(t *testing.T) {
+	c := New(time.Hour, time.Hour, 0, 0)
+	defer c.Stop()
+
+	c.Set("doc-1", &model.Document{ID: "doc-1"})
+	c.items["doc-1"].storedAt = time.Now().Add(-time.Minute)
+
+	doc, age, ok := c.GetWithAge("doc-1")
+
+	assert.True(t, ok)
+	assert.Equal(t, "doc-1", doc.ID)
+	assert.GreaterOrEqual(t, age, time.Minute)
+
+	_, _, ok = c.GetWithAge("missing")
+	assert.False(t, ok)
+}
+
+func TestCache_OnEvict_Reasons(t *testing.T) {
+	var mu sync.Mutex
+	evicted := make(map[string]EvictReason)
+	c := New(time.Hour, time.Hour, 2, 0, WithOnEvict(func(id string, reason EvictReason) {
+		mu.Lock()
+		defer mu.Unlock()
+		evicted[id] = reason
+	}))
+	defer c.Stop()
+
+	c.Set("a", &model.Document{ID: "a"})
+	c.Set("b", &model.Document{ID: "b"})
+	c.Set("c", &model.Document{ID: "c"})
+	assert.Len(t, evicted, 1)
+	for _, reason := range evicted {
+		assert.Equal(t, EvictCapacity, reason)
+	}
+	clear(evicted)
+
+	c.Delete("c")
+	c.Delete("missing")
+	assert.Equal(t, map[string]EvictReason{"c": EvictDeleted}, evicted)
+	clear(evicted)
+
+	c.capacity = 0
+	c.Set("d", &model.Document{ID: "d"})
+	c.Set("e", &model.Document{ID: "e"})
+	c.items["d"].expiresAt = time.Now().Add(-time.Minute)
+	_, ok := c.Get("d")
+	assert.False(t, ok)
+	c.items["e"].expiresAt = time.Now().Add(-time.Minute)
+	c.cleanup()
+	assert.Equal(t, map[string]EvictReason{"d": EvictExpired, "e": EvictExpired}, evicted)
+}
+
+func TestCache_Set_NoAdmitWhenFull(t *testing.T) {
+	var evicted []string
+	c := New(time.Hour, time.Hour, 2, 0, WithEvictionPolicy(EvictionNoAdmit),
+		WithOnEvict(func(id string, reason EvictReason) { evicted = append(evicted, id) }))
+	defer c.Stop()
+
+	c.Set("a", &model.Document{ID: "a"})
+	c.Set("b", &model.Document{ID: "b"})
+	c.Set("c", &model.Document{ID: "c"})
+
+	_, ok := c.Get("c")
+	assert.False(t, ok)
+	assert.Equal(t, 2, c.Size())
+	assert.Empty(t, evicted)
+
+	c.Set("a", &model.Document{ID: "a", Title: "updated"})
+	doc, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, "updated", doc.Title)
+	_, ok = c.Get("b")
+	assert.True(t, ok)
+
+	c.Delete("b")
+	c.Set("c", &model.Document{ID: "c"})
+	_, ok = c.Get("c")
+	assert.True(t, ok)
+}
+
+func TestCache_Set_NoAdmitReplacesExpired(t *testing.T) {
+	evicted := make(map[string]EvictReason)
+	c := New(time.Hour, time.Hour, 2, 0, WithEvictionPolicy(EvictionNoAdmit),
+		WithOnEvict(func(id string, reason EvictReason) { evicted[id] = reason }))
+	defer c.Stop()
+
+	c.Set("a", &model.Document{ID: "a"})
+	c.Set("b", &model.Document{ID: "b"})
+	c.items["a"].expiresAt = time.Now().Add(-time.Minute)
+
+	c.Set("c", &model.Document{ID: "c"})
+
+	_, ok := c.Get("c")
+	assert.True(t, ok, "the expired entry should make room")
+	_, ok = c.Get("b")
+	assert.True(t, ok)
+	assert.Equal(t, 2, c.Size())
+	assert.Equal(t, map[string]EvictReason{"a": EvictExpired}, evicted)
+}
+
+func TestCache_OnEvict_NilIsSafe(t *testing.T) {
+	c := New(time.Hour, time.Hour, 1, 0)
+	defer c.Stop()
+
+	c.Set("a", &model.Document{ID: "a"})
+	c.Set("b", &model.Document{ID: "b"})
+	c.Delete("b")
+
+	assert.Equal(t, 0, c.Size())
+}
+
+func fillExpired(c *Cache, expired, live int) {
+	for i := 0; i < expired+live; i++ {
+		id := fmt.Sprintf("doc-%d", i)
+		c.Set(id, &model.Document{ID: id})
+		if i < expired {
+			c.items[id].expiresAt = time.Now().Add(-time.Minute)
+		}
+	}
+}
+
+func TestCache_Cleanup_Batched(t *testing.T) {
+	c := New(time.Hour, time.Hour, 0, 0, WithCleanupBatchSize(64))
+	defer c.Stop()
+	fillExpired(c, 1000, 10)
+
+	c.cleanup()
+
+	assert.Equal(t, 10, c.Size())
+	for i := 1000; i < 1010; i++ {
+		_, ok := c.Get(fmt.Sprintf("doc-%d", i))
+		assert.True(t, ok)
+	}
+}
+
+func TestCache_Cleanup_BatchedReadsProgress(t *testing.T) {
+	c := New(time.Hour, time.Hour, 0, 0, WithCleanupBatchSize(100))
+	defer c.Stop()
+	fillExpired(c, 200000, 1)
+	live := "doc-200000"
+
+	done := make(chan struct{})
+	go func() {
+		c.cleanup()
+		close(done)
+	}()
+
+	// Best effort: with the lock released between batches, reads keep being
+	// served while the pass runs.
+	reads := 0
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+			_, ok := c.Get(live)
+			assert.True(t, ok)
+			reads++
+		}
+	}
+
+	assert.Positive(t, reads)
+	assert.Equal(t, 1, c.Size())
+}
+
+func BenchmarkCache_Cleanup(b *testing.B) {
+	for _, batch := range []int{0, 1000} {
+		b.Run(fmt.Sprintf("batch=%d", batch), func(b *testing.B) {
+			c := New(time.Hour, time.Hour, 0, 0, WithCleanupBatchSize(batch))
+			defer c.Stop()
+
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				fillExpired(c, 50000, 50000)
+				b.StartTimer()
+				c.cleanup()
+			}
+		})
+	}
+}
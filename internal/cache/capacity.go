@@ -0,0 +1,129 @@
+package cache
+
+import (
+	"math"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// autoSizing sizes a cache created with capacity 0 to a fraction of the
+// memory available to the process.
+type autoSizing struct {
+	fraction  float64
+	entrySize int
+	available func() uint64
+}
+
+// WithAutoCapacity makes a cache created with capacity 0 hold as many entries
+// of roughly entrySize bytes as fit in fraction of the available memory. The
+// capacity is recalculated on every cleanup tick.
+func WithAutoCapacity(fraction float64, entrySize int) Option {
+	return func(c *Cache) {
+		if fraction <= 0 || entrySize <= 0 || c.capacity > 0 {
+			return
+		}
+		c.auto = &autoSizing{fraction: fraction, entrySize: entrySize, available: availableMemory}
+		c.capacity = c.auto.capacity()
+	}
+}
+
+func (a *autoSizing) capacity() int {
+	return autoCapacity(a.available(), a.fraction, a.entrySize)
+}
+
+// autoCapacity returns how many entries of entrySize bytes fit in fraction of
+// available bytes. It is at least 1 so that an auto-sized cache never becomes
+// unbounded.
+func autoCapacity(available uint64, fraction float64, entrySize int) int {
+	budget := float64(available) * min(fraction, 1)
+	entries := budget / float64(entrySize)
+	if entries > math.MaxInt32 {
+		return math.MaxInt32
+	}
+	return max(int(entries), 1)
+}
+
+// Sources of the memory limit on Linux, in the order they are consulted.
+const (
+	cgroupV2MemoryMax   = "/sys/fs/cgroup/memory.max"
+	cgroupV1MemoryLimit = "/sys/fs/cgroup/memory/memory.limit_in_bytes"
+	procMeminfo         = "/proc/meminfo"
+)
+
+// cgroupUnlimited is the smallest cgroup v1 limit treated as no limit; v1
+// reports an unlimited group as a page-aligned value near MaxInt64.
+const cgroupUnlimited = 1 << 62
+
+// availableMemory is the memory the process may use: the soft memory limit
+// (GOMEMLIMIT) when one is set, then the container's cgroup limit, then the
+// host's MemAvailable. Off Linux it falls back to the memory the runtime has
+// obtained from the OS.
+func availableMemory() uint64 {
+	if available, ok := memoryLimit(debug.SetMemoryLimit(-1), os.ReadFile); ok {
+		return available
+	}
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.Sys
+}
+
+// memoryLimit looks up the limit from softLimit and the files read by
+// readFile. It reports false when none of them sets one.
+func memoryLimit(softLimit int64, readFile func(name string) ([]byte, error)) (uint64, bool) {
+	if softLimit > 0 && softLimit < math.MaxInt64 {
+		return uint64(softLimit), true
+	}
+
+	for _, name := range []string{cgroupV2MemoryMax, cgroupV1MemoryLimit} {
+		data, err := readFile(name)
+		if err != nil {
+			continue
+		}
+		// cgroup v2 writes "max" when the group has no limit
+		limit, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+		if err == nil && limit > 0 && limit < cgroupUnlimited {
+			return limit, true
+		}
+	}
+
+	data, err := readFile(procMeminfo)
+	if err != nil {
+		return 0, false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "MemAvailable:" {
+			continue
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		return kb * 1024, true
+	}
+	return 0, false
+}
+
+// resize recalculates an auto-sized capacity and evicts entries that no
+// longer fit.
+func (c *Cache) resize() {
+	if c.auto == nil {
+		return
+	}
+	capacity := c.auto.capacity()
+
+	c.mu.Lock()
+	c.capacity = capacity
+	var evicted []string
+	for len(c.items) > c.capacity {
+		evicted = append(evicted, c.evictRandom())
+	}
+	c.mu.Unlock()
+
+	for _, id := range evicted {
+		c.notifyEvict(id, EvictCapacity)
+	}
+}
@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/fedorovmatvey/involta-test/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutoCapacity(t *testing.T) {
+	tests := []struct {
+		name      string
+		available uint64
+		fraction  float64
+		entrySize int
+		want      int
+	}{
+		{"quarter of 1MiB in 1KiB entries", 1 << 20, 0.25, 1024, 256},
+		{"fraction above one is capped", 1 << 20, 2, 1024, 1024},
+		{"too little memory keeps one entry", 100, 0.1, 4096, 1},
+		{"huge memory is capped", math.MaxUint64, 1, 1, math.MaxInt32},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, autoCapacity(tt.available, tt.fraction, tt.entrySize))
+		})
+	}
+}
+
+func TestCache_AutoCapacity_Resize(t *testing.T) {
+	c := New(time.Hour, time.Hour, 0, 0, WithAutoCapacity(0.5, 100))
+	defer c.Stop()
+	assert.Positive(t, c.capacity)
+
+	available := uint64(1000)
+	c.auto.available = func() uint64 { return available }
+	c.resize()
+	assert.Equal(t, 5, c.capacity)
+
+	for i := 0; i < 8; i++ {
+		id := fmt.Sprintf("doc-%d", i)
+		c.Set(id, &model.Document{ID: id})
+	}
+	assert.Equal(t, 5, c.Size())
+
+	available = 400
+	c.resize()
+	assert.Equal(t, 2, c.capacity)
+	assert.Equal(t, 2, c.Size())
+}
+
+func TestCache_AutoCapacity_FixedCapacityWins(t *testing.T) {
+	c := New(time.Hour, time.Hour, 10, 0, WithAutoCapacity(0.5, 100))
+	defer c.Stop()
+
+	assert.Nil(t, c.auto)
+	assert.Equal(t, 10, c.capacity)
+}
+
+func TestMemoryLimit(t *testing.T) {
+	meminfo := "MemTotal:       16000000 kB\nMemFree:         1000000 kB\nMemAvailable:    8000000 kB\n"
+	files := func(contents map[string]string) func(string) ([]byte, error) {
+		return func(name string) ([]byte, error) {
+			data, ok := contents[name]
+			if !ok {
+				return nil, os.ErrNotExist
+			}
+			return []byte(data), nil
+		}
+	}
+
+	tests := []struct {
+		name      string
+		softLimit int64
+		files     map[string]string
+		want      uint64
+		ok        bool
+	}{
+		{"soft limit wins", 1 << 30, map[string]string{cgroupV2MemoryMax: "536870912\n"}, 1 << 30, true},
+		{"unset soft limit", math.MaxInt64, map[string]string{cgroupV2MemoryMax: "536870912\n"}, 512 << 20, true},
+		{"cgroup v1 limit", 0, map[string]string{cgroupV1MemoryLimit: "268435456\n", procMeminfo: meminfo}, 256 << 20, true},
+		{"unlimited cgroup v2 uses meminfo", 0, map[string]string{cgroupV2MemoryMax: "max\n", procMeminfo: meminfo}, 8000000 * 1024, true},
+		{"unlimited cgroup v1 uses meminfo", 0, map[string]string{cgroupV1MemoryLimit: "9223372036854771712\n", procMeminfo: meminfo}, 8000000 * 1024, true},
+		{"no sources", 0, map[string]string{}, 0, false},
+		{"meminfo without MemAvailable", 0, map[string]string{procMeminfo: "MemTotal: 1 kB\n"}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := memoryLimit(tt.softLimit, files(tt.files))
+
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
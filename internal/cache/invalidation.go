@@ -0,0 +1,223 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/fedorovmatvey/involta-test/internal/model"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	invalidationChannel = "documents:invalidate"
+	// invalidationQueueSize is how many deletions may wait to be published.
+	// Bulk requests delete up to a batch of documents at once.
+	invalidationQueueSize = 10000
+	// maxPublishBatch caps the IDs sent in one message.
+	maxPublishBatch = 500
+)
+
+// Bus delivers invalidated document IDs to every subscribed instance.
+type Bus interface {
+	Publish(ctx context.Context, ids []string) error
+	// Subscribe registers handler and returns once the subscription is
+	// active. Delivery stops when ctx is cancelled.
+	Subscribe(ctx context.Context, handler func(id string)) error
+	Close() error
+}
+
+// InvalidatingCache wraps a local cache and broadcasts deletions over a Bus so
+// that other instances drop their copies too. Deletions are applied locally
+// at once and published in the background, batching the IDs that queue up
+// while a publish is in flight, so a bulk request is not held up by the bus.
+type InvalidatingCache struct {
+	DocumentCache
+	bus     Bus
+	cancel  context.CancelFunc
+	pending chan string
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+func NewInvalidating(c DocumentCache, bus Bus) (*InvalidatingCache, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	if err := bus.Subscribe(ctx, c.Delete); err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to subscribe to invalidations: %w", err)
+	}
+
+	ic := &InvalidatingCache{
+		DocumentCache: c,
+		bus:           bus,
+		cancel:        cancel,
+		pending:       make(chan string, invalidationQueueSize),
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	go ic.publishLoop()
+	return ic, nil
+}
+
+// Delete removes id from the local cache and queues its invalidation. When
+// the queue is full the invalidation is dropped and other instances keep
+// their copies until the TTL runs out.
+func (c *InvalidatingCache) Delete(id string) {
+	c.DocumentCache.Delete(id)
+
+	select {
+	case c.pending <- id:
+	default:
+		log.Printf("Invalidation queue is full, dropping invalidation for %s", id)
+	}
+}
+
+// publishLoop publishes queued invalidations until Stop, then flushes what is
+// left.
+func (c *InvalidatingCache) publishLoop() {
+	defer close(c.done)
+	for {
+		select {
+		case id := <-c.pending:
+			c.publish(c.drain([]string{id}))
+		case <-c.stop:
+			for batch := c.drain(nil); len(batch) > 0; batch = c.drain(nil) {
+				c.publish(batch)
+			}
+			return
+		}
+	}
+}
+
+// drain adds queued IDs to batch without waiting, up to maxPublishBatch.
+func (c *InvalidatingCache) drain(batch []string) []string {
+	for len(batch) < maxPublishBatch {
+		select {
+		case id := <-c.pending:
+			batch = append(batch, id)
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+func (c *InvalidatingCache) publish(ids []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	if err := c.bus.Publish(ctx, ids); err != nil {
+		log.Printf("Failed to publish invalidation for %d document(s): %v", len(ids), err)
+	}
+}
+
+// GetWithAge forwards to the wrapped cache, reporting a zero age when it does
+// not track one.
+func (c *InvalidatingCache) GetWithAge(id string) (*model.Document, time.Duration, bool) {
+	if aged, ok := c.DocumentCache.(AgeReader); ok {
+		return aged.GetWithAge(id)
+	}
+	doc, ok := c.DocumentCache.Get(id)
+	return doc, 0, ok
+}
+
+// Size forwards to the wrapped cache, reporting zero when it cannot count its
+// entries.
+func (c *InvalidatingCache) Size() int {
+	if sizer, ok := c.DocumentCache.(Sizer); ok {
+		return sizer.Size()
+	}
+	return 0
+}
+
+// GetStale forwards to the wrapped cache when it keeps stale entries.
+func (c *InvalidatingCache) GetStale(id string) (*model.Document, bool) {
+	if stale, ok := c.DocumentCache.(StaleReader); ok {
+		return stale.GetStale(id)
+	}
+	return nil, false
+}
+
+// Stop publishes the invalidations still queued, then closes the bus and the
+// wrapped cache.
+func (c *InvalidatingCache) Stop() {
+	close(c.stop)
+	<-c.done
+	c.cancel()
+	if err := c.bus.Close(); err != nil {
+		log.Printf("Failed to close invalidation bus: %v", err)
+	}
+	c.DocumentCache.Stop()
+}
+
+// RedisBus implements Bus on top of Redis pub/sub.
+type RedisBus struct {
+	client *redis.Client
+}
+
+func NewRedisBus(url string) (*RedisBus, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse redis url: %w", err)
+	}
+
+	return &RedisBus{client: redis.NewClient(opts)}, nil
+}
+
+// Publish sends ids as one message holding a JSON array.
+func (b *RedisBus) Publish(ctx context.Context, ids []string) error {
+	payload, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+	return b.client.Publish(ctx, invalidationChannel, payload).Err()
+}
+
+// decodeInvalidation returns the IDs in a message. A payload that is not a
+// JSON array is a single ID, as published before IDs were batched.
+func decodeInvalidation(payload string) []string {
+	var ids []string
+	if err := json.Unmarshal([]byte(payload), &ids); err != nil {
+		return []string{payload}
+	}
+	return ids
+}
+
+func (b *RedisBus) Subscribe(ctx context.Context, handler func(id string)) error {
+	pubsub := b.client.Subscribe(ctx, invalidationChannel)
+
+	receiveCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if _, err := pubsub.Receive(receiveCtx); err != nil {
+		pubsub.Close()
+		return err
+	}
+
+	go func() {
+		defer pubsub.Close()
+
+		messages := pubsub.Channel()
+		for {
+			select {
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				for _, id := range decodeInvalidation(msg.Payload) {
+					handler(id)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (b *RedisBus) Close() error {
+	return b.client.Close()
+}
@@ -0,0 +1,150 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/fedorovmatvey/involta-test/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// MemoryBus delivers messages synchronously to every subscriber.
+type MemoryBus struct {
+	mu       sync.Mutex
+	handlers []func(id string)
+}
+
+func (b *MemoryBus) Publish(ctx context.Context, ids []string) error {
+	b.mu.Lock()
+	handlers := append([]func(string){}, b.handlers...)
+	b.mu.Unlock()
+
+	for _, h := range handlers {
+		for _, id := range ids {
+			h(id)
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBus) Subscribe(ctx context.Context, handler func(id string)) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+	return nil
+}
+
+func (b *MemoryBus) Close() error { return nil }
+
+func TestInvalidatingCache_PropagatesDelete(t *testing.T) {
+	bus := &MemoryBus{}
+
+	first, err := NewInvalidating(New(time.Hour, time.Hour, 0, 0), bus)
+	assert.NoError(t, err)
+	defer first.Stop()
+	second, err := NewInvalidating(New(time.Hour, time.Hour, 0, 0), bus)
+	assert.NoError(t, err)
+	defer second.Stop()
+
+	doc := &model.Document{ID: "doc-1"}
+	first.Set(doc.ID, doc)
+	second.Set(doc.ID, doc)
+
+	first.Delete(doc.ID)
+
+	_, ok := first.Get(doc.ID)
+	assert.False(t, ok, "the local delete is synchronous")
+	assert.Eventually(t, func() bool {
+		_, ok := second.Get(doc.ID)
+		return !ok
+	}, time.Second, time.Millisecond)
+}
+
+// BlockingBus holds every publish until release is closed and records the
+// batches it was given.
+type BlockingBus struct {
+	MemoryBus
+	release chan struct{}
+	batches chan []string
+}
+
+func (b *BlockingBus) Publish(ctx context.Context, ids []string) error {
+	b.batches <- ids
+	<-b.release
+	return nil
+}
+
+func TestInvalidatingCache_PublishesInBackgroundBatches(t *testing.T) {
+	bus := &BlockingBus{release: make(chan struct{}), batches: make(chan []string, 10)}
+	c, err := NewInvalidating(New(time.Hour, time.Hour, 0, 0), bus)
+	assert.NoError(t, err)
+
+	c.Set("doc-0", &model.Document{ID: "doc-0"})
+	c.Delete("doc-0")
+	assert.Equal(t, []string{"doc-0"}, <-bus.batches)
+
+	// The bus is stuck on the first publish; deletes must not wait for it.
+	deleted := make(chan struct{})
+	go func() {
+		defer close(deleted)
+		for _, id := range []string{"doc-1", "doc-2", "doc-3"} {
+			c.Delete(id)
+		}
+	}()
+	select {
+	case <-deleted:
+	case <-time.After(time.Second):
+		t.Fatal("Delete blocked on the bus")
+	}
+
+	close(bus.release)
+	assert.Equal(t, []string{"doc-1", "doc-2", "doc-3"}, <-bus.batches)
+	c.Stop()
+}
+
+func TestInvalidatingCache_ForwardsSize(t *testing.T) {
+	c, err := NewInvalidating(New(time.Hour, time.Hour, 0, 0), &MemoryBus{})
+	assert.NoError(t, err)
+	defer c.Stop()
+
+	c.Set("doc-1", &model.Document{ID: "doc-1"})
+	c.Set("doc-2", &model.Document{ID: "doc-2"})
+
+	var sizer Sizer = c
+	assert.Equal(t, 2, sizer.Size())
+}
+
+func TestRedisBus_DeliversToSubscribers(t *testing.T) {
+	mr := miniredis.RunT(t)
+
+	publisher, err := NewRedisBus("redis://" + mr.Addr())
+	assert.NoError(t, err)
+	defer publisher.Close()
+	subscriber, err := NewRedisBus("redis://" + mr.Addr())
+	assert.NoError(t, err)
+	defer subscriber.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	received := make(chan string, 1)
+	assert.NoError(t, subscriber.Subscribe(ctx, func(id string) { received <- id }))
+	assert.NoError(t, publisher.Publish(ctx, []string{"doc-1", "doc-2"}))
+
+	for _, want := range []string{"doc-1", "doc-2"} {
+		select {
+		case id := <-received:
+			assert.Equal(t, want, id)
+		case <-time.After(time.Second):
+			t.Fatal("invalidation was not delivered")
+		}
+	}
+}
+
+func TestDecodeInvalidation(t *testing.T) {
+	assert.Equal(t, []string{"doc-1", "doc-2"}, decodeInvalidation(`["doc-1","doc-2"]`))
+	assert.Equal(t, []string{"doc-1"}, decodeInvalidation("doc-1"))
+}
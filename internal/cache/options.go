@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"log/slog"
+	"time"
+)
+
+// Option configures optional Cache behavior.
+type Option func(*Cache)
+
+// WithTTLBounds clamps the configured TTL into [minTTL, maxTTL]. A zero bound
+// is not enforced.
+func WithTTLBounds(minTTL, maxTTL time.Duration) Option {
+	return func(c *Cache) {
+		c.ttl = ClampTTL(c.ttl, minTTL, maxTTL)
+	}
+}
+
+// WithStaleGrace keeps expired entries for grace so GetStale can still serve
+// them, e.g. while storage is down.
+func WithStaleGrace(grace time.Duration) Option {
+	return func(c *Cache) {
+		c.staleGrace = grace
+	}
+}
+
+// WithOnEvict registers fn to be called with the ID and reason of every entry
+// that is evicted for capacity, expires, or is deleted. fn runs without the
+// cache lock held.
+func WithOnEvict(fn func(id string, reason EvictReason)) Option {
+	return func(c *Cache) {
+		c.onEvict = fn
+	}
+}
+
+// WithCleanupBatchSize makes cleanup release the lock after every size
+// entries, so large caches do not stall reads during a pass.
+func WithCleanupBatchSize(size int) Option {
+	return func(c *Cache) {
+		c.cleanupBatchSize = size
+	}
+}
+
+// WithEvictionPolicy sets what Set does once the cache is at capacity. The
+// default is EvictionRandom. Shrinking an auto-sized cache evicts under
+// either policy.
+func WithEvictionPolicy(policy EvictionPolicy) Option {
+	return func(c *Cache) {
+		c.evictionPolicy = policy
+	}
+}
+
+// ClampTTL returns ttl limited to [minTTL, maxTTL], logging a warning when the
+// value had to be changed. A zero bound is not enforced.
+func ClampTTL(ttl, minTTL, maxTTL time.Duration) time.Duration {
+	if minTTL > 0 && ttl < minTTL {
+		slog.Warn("Cache TTL below minimum, clamping", "ttl", ttl, "min", minTTL)
+		return minTTL
+	}
+	if maxTTL > 0 && ttl > maxTTL {
+		slog.Warn("Cache TTL above maximum, clamping", "ttl", ttl, "max", maxTTL)
+		return maxTTL
+	}
+	return ttl
+}
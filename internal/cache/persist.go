@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fedorovmatvey/involta-test/internal/model"
+)
+
+// persistedEntry is the on-disk form of a cache entry. Documents are
+// gob-encoded, like in RedisCache, so fields hidden from JSON survive.
+type persistedEntry struct {
+	ID        string
+	Document  *model.Document
+	StoredAt  time.Time
+	ExpiresAt time.Time
+}
+
+// WithPersistPath makes Stop save the cache to path and New reload the entries
+// from it that have not expired yet. Entries keep their original expiry.
+func WithPersistPath(path string) Option {
+	return func(c *Cache) {
+		c.persistPath = path
+	}
+}
+
+// load fills the cache from persistPath. A missing file is not an error, so
+// the first start with persistence enabled begins empty.
+func (c *Cache) load() error {
+	f, err := os.Open(c.persistPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open cache file: %w", err)
+	}
+	defer f.Close()
+
+	var entries []persistedEntry
+	if err := gob.NewDecoder(f).Decode(&entries); err != nil {
+		return fmt.Errorf("failed to decode cache file: %w", err)
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, entry := range entries {
+		if c.capacity > 0 && len(c.items) >= c.capacity {
+			break
+		}
+		if entry.Document == nil || now.After(entry.ExpiresAt) {
+			continue
+		}
+		c.items[entry.ID] = &cacheItem{
+			document:  entry.Document,
+			storedAt:  entry.StoredAt,
+			expiresAt: entry.ExpiresAt,
+		}
+	}
+	return nil
+}
+
+// save writes the unexpired entries to persistPath. It writes a temporary
+// file first and renames it, so a crash mid-write keeps the previous file.
+func (c *Cache) save() error {
+	now := time.Now()
+	c.mu.RLock()
+	entries := make([]persistedEntry, 0, len(c.items))
+	for id, item := range c.items {
+		if now.After(item.expiresAt) {
+			continue
+		}
+		entries = append(entries, persistedEntry{
+			ID:        id,
+			Document:  item.document,
+			StoredAt:  item.storedAt,
+			ExpiresAt: item.expiresAt,
+		})
+	}
+	c.mu.RUnlock()
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.persistPath), filepath.Base(c.persistPath)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create cache file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := gob.NewEncoder(tmp).Encode(entries); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to encode cache file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.persistPath); err != nil {
+		return fmt.Errorf("failed to replace cache file: %w", err)
+	}
+	slog.Info("Saved cache to disk", "path", c.persistPath, "entries", len(entries))
+	return nil
+}
@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fedorovmatvey/involta-test/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache_Persist_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.gob")
+
+	c := New(time.Hour, time.Hour, 0, 0, WithPersistPath(path))
+	c.Set("doc-1", &model.Document{ID: "doc-1", Title: "first", Internal: "kept"})
+	c.Set("doc-2", &model.Document{ID: "doc-2"})
+	c.mu.Lock()
+	c.items["doc-2"].expiresAt = time.Now().Add(-time.Minute)
+	expiresAt := c.items["doc-1"].expiresAt
+	c.mu.Unlock()
+	c.Stop()
+
+	loaded := New(time.Hour, time.Hour, 0, 0, WithPersistPath(path))
+	defer loaded.Stop()
+
+	doc, ok := loaded.Get("doc-1")
+	require.True(t, ok)
+	assert.Equal(t, "first", doc.Title)
+	assert.Equal(t, "kept", doc.Internal)
+	assert.True(t, expiresAt.Equal(loaded.items["doc-1"].expiresAt))
+	_, ok = loaded.Get("doc-2")
+	assert.False(t, ok)
+	assert.Equal(t, 1, loaded.Size())
+}
+
+func TestCache_Persist_SkipsExpiredOnLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.gob")
+
+	c := New(50*time.Millisecond, time.Hour, 0, 0, WithPersistPath(path))
+	c.Set("doc-1", &model.Document{ID: "doc-1"})
+	c.Stop()
+	time.Sleep(60 * time.Millisecond)
+
+	loaded := New(time.Hour, time.Hour, 0, 0, WithPersistPath(path))
+	defer loaded.Stop()
+
+	assert.Equal(t, 0, loaded.Size())
+}
+
+func TestCache_Persist_MissingOrCorruptFile(t *testing.T) {
+	dir := t.TempDir()
+
+	c := New(time.Hour, time.Hour, 0, 0, WithPersistPath(filepath.Join(dir, "missing.gob")))
+	assert.Equal(t, 0, c.Size())
+	c.Stop()
+
+	corrupt := filepath.Join(dir, "corrupt.gob")
+	require.NoError(t, os.WriteFile(corrupt, []byte("not gob"), 0o600))
+	c = New(time.Hour, time.Hour, 0, 0, WithPersistPath(corrupt))
+	defer c.Stop()
+	assert.Equal(t, 0, c.Size())
+}
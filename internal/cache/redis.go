@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/fedorovmatvey/involta-test/internal/model"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	redisKeyPrefix = "document:"
+	redisTimeout   = time.Second
+)
+
+// RedisCache stores documents in Redis so that every instance shares the
+// same cache. Documents are gob-encoded to keep fields hidden from JSON.
+type RedisCache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func NewRedis(url string, ttl time.Duration) (*RedisCache, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse redis url: %w", err)
+	}
+
+	return &RedisCache{
+		client: redis.NewClient(opts),
+		ttl:    ttl,
+	}, nil
+}
+
+func (c *RedisCache) Get(id string) (*model.Document, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	data, err := c.client.Get(ctx, redisKeyPrefix+id).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			log.Printf("Failed to get document %s from redis: %v", id, err)
+		}
+		return nil, false
+	}
+
+	var doc model.Document
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&doc); err != nil {
+		log.Printf("Failed to decode cached document %s: %v", id, err)
+		return nil, false
+	}
+
+	return &doc, true
+}
+
+func (c *RedisCache) Set(id string, doc *model.Document) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(doc); err != nil {
+		log.Printf("Failed to encode document %s for redis: %v", id, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	if err := c.client.Set(ctx, redisKeyPrefix+id, buf.Bytes(), c.ttl).Err(); err != nil {
+		log.Printf("Failed to set document %s in redis: %v", id, err)
+	}
+}
+
+func (c *RedisCache) Delete(id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	if err := c.client.Del(ctx, redisKeyPrefix+id).Err(); err != nil {
+		log.Printf("Failed to delete document %s from redis: %v", id, err)
+	}
+}
+
+// Ping checks that Redis is reachable.
+func (c *RedisCache) Ping(ctx context.Context) error {
+	return c.client.Ping(ctx).Err()
+}
+
+func (c *RedisCache) Stop() {
+	if err := c.client.Close(); err != nil {
+		log.Printf("Failed to close redis client: %v", err)
+	}
+}
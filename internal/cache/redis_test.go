@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/fedorovmatvey/involta-test/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRedis(t *testing.T) (*RedisCache, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+
+	c, err := NewRedis("redis://"+mr.Addr(), time.Minute)
+	assert.NoError(t, err)
+	t.Cleanup(c.Stop)
+
+	return c, mr
+}
+
+func TestRedisCache_SetGet(t *testing.T) {
+	c, _ := newTestRedis(t)
+	doc := &model.Document{
+		ID:       "doc-1",
+		Title:    "title",
+		Internal: "secret",
+		Items:    []model.FirstLevelItem{{ID: "item-1", MetaData: "meta"}},
+	}
+
+	c.Set(doc.ID, doc)
+	got, ok := c.Get(doc.ID)
+
+	assert.True(t, ok)
+	assert.Equal(t, "title", got.Title)
+	assert.Equal(t, "secret", got.Internal)
+	assert.Equal(t, "meta", got.Items[0].MetaData)
+}
+
+func TestRedisCache_Miss(t *testing.T) {
+	c, _ := newTestRedis(t)
+
+	_, ok := c.Get("missing")
+
+	assert.False(t, ok)
+}
+
+func TestRedisCache_Delete(t *testing.T) {
+	c, mr := newTestRedis(t)
+	c.Set("doc-1", &model.Document{ID: "doc-1"})
+
+	c.Delete("doc-1")
+
+	assert.False(t, mr.Exists(redisKeyPrefix+"doc-1"))
+	_, ok := c.Get("doc-1")
+	assert.False(t, ok)
+}
+
+func TestRedisCache_TTL(t *testing.T) {
+	c, mr := newTestRedis(t)
+	c.Set("doc-1", &model.Document{ID: "doc-1"})
+
+	assert.Equal(t, time.Minute, mr.TTL(redisKeyPrefix+"doc-1"))
+
+	mr.FastForward(time.Minute + time.Second)
+	_, ok := c.Get("doc-1")
+	assert.False(t, ok)
+}
@@ -2,43 +2,188 @@ package config
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
 type Config struct {
-	Server    ServerConfig      `yaml:"server"`
-	Reindexer ReindexerConfig   `yaml:"reindexer"`
-	Cache     CacheConfig       `yaml:"cache"`
-	App       ApplicationConfig `yaml:"app"`
+	Server     ServerConfig      `yaml:"server"`
+	Reindexer  ReindexerConfig   `yaml:"reindexer"`
+	Cache      CacheConfig       `yaml:"cache"`
+	Validation ValidationConfig  `yaml:"validation"`
+	App        ApplicationConfig `yaml:"app"`
 }
 
 type ServerConfig struct {
-	Port         int           `yaml:"port" env:"SERVER_PORT" env-default:"8080"`
-	ReadTimeout  time.Duration `yaml:"read_timeout" env:"SERVER_READ_TIMEOUT" env-default:"10s"`
-	WriteTimeout time.Duration `yaml:"write_timeout" env:"SERVER_WRITE_TIMEOUT" env-default:"10s"`
-	IdleTimeout  time.Duration `yaml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT" env-default:"60s"`
+	Port                  int           `yaml:"port" env:"SERVER_PORT" env-default:"8080"`
+	ReadTimeout           time.Duration `yaml:"read_timeout" env:"SERVER_READ_TIMEOUT" env-default:"10s"`
+	WriteTimeout          time.Duration `yaml:"write_timeout" env:"SERVER_WRITE_TIMEOUT" env-default:"10s"`
+	IdleTimeout           time.Duration `yaml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT" env-default:"60s"`
+	ShutdownTimeout       time.Duration `yaml:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT" env-default:"30s"`
+	CacheMaxAge           time.Duration `yaml:"cache_max_age" env:"SERVER_CACHE_MAX_AGE" env-default:"0s"`
+	RequestIDHeader       string        `yaml:"request_id_header" env:"SERVER_REQUEST_ID_HEADER" env-default:"X-Request-Id"`
+	RequireJSON           bool          `yaml:"require_json" env:"SERVER_REQUIRE_JSON" env-default:"true"`
+	AllowJSONParams       bool          `yaml:"allow_json_params" env:"SERVER_ALLOW_JSON_PARAMS" env-default:"true"`
+	IDFormat              string        `yaml:"id_format" env:"SERVER_ID_FORMAT"`
+	MaxConcurrentRequests int           `yaml:"max_concurrent_requests" env:"SERVER_MAX_CONCURRENT_REQUESTS" env-default:"0"`
+	MaxURLLength          int           `yaml:"max_url_length" env:"SERVER_MAX_URL_LENGTH" env-default:"8192"`
+	RetryAfter            time.Duration `yaml:"retry_after" env:"SERVER_RETRY_AFTER" env-default:"1s"`
+	RetryAfterJitter      time.Duration `yaml:"retry_after_jitter" env:"SERVER_RETRY_AFTER_JITTER" env-default:"2s"`
+	// EmptyList is "200" to answer empty list pages with an empty list or
+	// "204" to answer them with No Content.
+	EmptyList string `yaml:"empty_list" env:"SERVER_EMPTY_LIST" env-default:"200"`
+	// StrictPagination answers 400 to out-of-range page and per_page values
+	// instead of clamping them.
+	StrictPagination bool   `yaml:"strict_pagination" env:"SERVER_STRICT_PAGINATION" env-default:"false"`
+	Compression      string `yaml:"compression" env:"SERVER_COMPRESSION"`
+	CompressionLevel int    `yaml:"compression_level" env:"SERVER_COMPRESSION_LEVEL" env-default:"5"`
 }
 
 type ReindexerConfig struct {
-	DSN       string `yaml:"dsn" env:"REINDEXER_DSN" env-required:"true"`
-	Namespace string `yaml:"namespace" env:"REINDEXER_NAMESPACE" env-default:"documents"`
+	DSN string `yaml:"dsn" env:"REINDEXER_DSN"`
+	// DSNFile names a file holding the DSN, such as a mounted Docker or
+	// Kubernetes secret. When set it takes precedence over DSN.
+	DSNFile string `yaml:"dsn_file" env:"REINDEXER_DSN_FILE"`
+	// ReadDSN points at a read-only replica that serves list queries.
+	// Writes and reads that are cached go to DSN; without ReadDSN everything
+	// does.
+	ReadDSN     string        `yaml:"read_dsn" env:"REINDEXER_READ_DSN"`
+	Namespace   string        `yaml:"namespace" env:"REINDEXER_NAMESPACE" env-default:"documents"`
+	Indexes     []IndexConfig `yaml:"indexes"`
+	AutoMigrate bool          `yaml:"auto_migrate" env:"REINDEXER_AUTO_MIGRATE" env-default:"false"`
+	// BreakerThreshold consecutive storage failures open the circuit breaker
+	// for BreakerCooldown. Zero disables the breaker.
+	BreakerThreshold int           `yaml:"breaker_threshold" env:"REINDEXER_BREAKER_THRESHOLD" env-default:"0"`
+	BreakerCooldown  time.Duration `yaml:"breaker_cooldown" env:"REINDEXER_BREAKER_COOLDOWN" env-default:"10s"`
+	// ReconnectAttempts dials made to restore a dropped connection before the
+	// failed call gives up. Zero disables reconnecting.
+	ReconnectAttempts   int                    `yaml:"reconnect_attempts" env:"REINDEXER_RECONNECT_ATTEMPTS" env-default:"0"`
+	ReconnectBackoff    time.Duration          `yaml:"reconnect_backoff" env:"REINDEXER_RECONNECT_BACKOFF" env-default:"200ms"`
+	ReconnectMaxBackoff time.Duration          `yaml:"reconnect_max_backoff" env:"REINDEXER_RECONNECT_MAX_BACKOFF" env-default:"5s"`
+	Options             NamespaceOptionsConfig `yaml:"namespace_options"`
+}
+
+type NamespaceOptionsConfig struct {
+	InMemory              bool `yaml:"in_memory" env:"REINDEXER_NS_IN_MEMORY" env-default:"false"`
+	DropOnIndexesConflict bool `yaml:"drop_on_indexes_conflict" env:"REINDEXER_NS_DROP_ON_INDEXES_CONFLICT" env-default:"false"`
+	DropOnFileFormatError bool `yaml:"drop_on_file_format_error" env:"REINDEXER_NS_DROP_ON_FILE_FORMAT_ERROR" env-default:"false"`
+	DisableObjCache       bool `yaml:"disable_obj_cache" env:"REINDEXER_NS_DISABLE_OBJ_CACHE" env-default:"false"`
+	ObjCacheSize          int  `yaml:"obj_cache_size" env:"REINDEXER_NS_OBJ_CACHE_SIZE" env-default:"0"`
+}
+
+type IndexConfig struct {
+	Field   string `yaml:"field"`
+	Type    string `yaml:"type"`
+	Collate string `yaml:"collate"`
 }
 
 type CacheConfig struct {
-	TTL             time.Duration `yaml:"ttl" env:"CACHE_TTL" env-default:"15m"`
-	CleanupInterval time.Duration `yaml:"cleanup_interval" env:"CACHE_CLEANUP_INTERVAL" env-default:"30m"`
-	Capacity        int           `yaml:"capacity" env:"CACHE_CAPACITY" env-default:"1000"`
+	Type             string        `yaml:"type" env:"CACHE_TYPE" env-default:"memory"`
+	RedisURL         string        `yaml:"redis_url" env:"CACHE_REDIS_URL"`
+	InvalidationURL  string        `yaml:"invalidation_url" env:"CACHE_INVALIDATION_URL"`
+	TTL              time.Duration `yaml:"ttl" env:"CACHE_TTL" env-default:"15m"`
+	MinTTL           time.Duration `yaml:"min_ttl" env:"CACHE_MIN_TTL" env-default:"1s"`
+	MaxTTL           time.Duration `yaml:"max_ttl" env:"CACHE_MAX_TTL" env-default:"24h"`
+	StaleGrace       time.Duration `yaml:"stale_grace" env:"CACHE_STALE_GRACE" env-default:"0s"`
+	RevalidateAfter  time.Duration `yaml:"revalidate_after" env:"CACHE_REVALIDATE_AFTER" env-default:"0s"`
+	CleanupInterval  time.Duration `yaml:"cleanup_interval" env:"CACHE_CLEANUP_INTERVAL" env-default:"30m"`
+	CleanupBatch     int           `yaml:"cleanup_batch_size" env:"CACHE_CLEANUP_BATCH_SIZE" env-default:"0"`
+	Capacity         int           `yaml:"capacity" env:"CACHE_CAPACITY" env-default:"1000"`
+	EvictionPolicy   string        `yaml:"eviction_policy" env:"CACHE_EVICTION_POLICY" env-default:"random"`
+	AutoCapacity     float64       `yaml:"auto_capacity_fraction" env:"CACHE_AUTO_CAPACITY_FRACTION" env-default:"0"`
+	EntrySize        int           `yaml:"entry_size_estimate" env:"CACHE_ENTRY_SIZE_ESTIMATE" env-default:"4096"`
+	TTLJitterPercent int           `yaml:"ttl_jitter_percent" env:"CACHE_TTL_JITTER_PERCENT" env-default:"10"`
+	Warmup           bool          `yaml:"warmup" env:"CACHE_WARMUP" env-default:"false"`
+	WarmupSize       int           `yaml:"warmup_size" env:"CACHE_WARMUP_SIZE" env-default:"100"`
+	// WarmupConcurrency pages of documents are loaded at once; warmup stops
+	// after WarmupTimeout, keeping what it loaded. Zero timeout disables it.
+	WarmupConcurrency int           `yaml:"warmup_concurrency" env:"CACHE_WARMUP_CONCURRENCY" env-default:"4"`
+	WarmupTimeout     time.Duration `yaml:"warmup_timeout" env:"CACHE_WARMUP_TIMEOUT" env-default:"30s"`
+	// PersistPath is where the memory cache is saved on shutdown and
+	// reloaded from on startup; empty disables persistence. It is ignored
+	// with InvalidationURL, since a reloaded cache has missed invalidations.
+	PersistPath string `yaml:"persist_path" env:"CACHE_PERSIST_PATH"`
+}
+
+type ValidationConfig struct {
+	MaxTitleLength       int `yaml:"max_title_length" env:"VALIDATION_MAX_TITLE_LENGTH" env-default:"255"`
+	MaxDescriptionLength int `yaml:"max_description_length" env:"VALIDATION_MAX_DESCRIPTION_LENGTH" env-default:"10000"`
+	MaxItemNameLength    int `yaml:"max_item_name_length" env:"VALIDATION_MAX_ITEM_NAME_LENGTH" env-default:"255"`
+	MaxItemValueLength   int `yaml:"max_item_value_length" env:"VALIDATION_MAX_ITEM_VALUE_LENGTH" env-default:"10000"`
+	MaxItemContentLength int `yaml:"max_item_content_length" env:"VALIDATION_MAX_ITEM_CONTENT_LENGTH" env-default:"10000"`
+	MaxItemSort          int `yaml:"max_item_sort" env:"VALIDATION_MAX_ITEM_SORT" env-default:"1000000"`
+	MaxTags              int `yaml:"max_tags" env:"VALIDATION_MAX_TAGS" env-default:"50"`
+	MaxTagLength         int `yaml:"max_tag_length" env:"VALIDATION_MAX_TAG_LENGTH" env-default:"64"`
+	MaxBatchSize         int `yaml:"max_batch_size" env:"VALIDATION_MAX_BATCH_SIZE" env-default:"1000"`
+	// OversizedItems is "reject" to refuse writes with item values or
+	// contents over their limits, or "truncate" to store them and cut them
+	// when served.
+	OversizedItems string `yaml:"oversized_items" env:"VALIDATION_OVERSIZED_ITEMS" env-default:"reject"`
+	// AllowedFields, when set, lists the only document fields clients may
+	// set; DeniedFields lists fields they may not. DisallowedFields is
+	// "strip" to drop disallowed fields from requests or "reject" to fail
+	// them.
+	AllowedFields    []string `yaml:"allowed_fields" env:"VALIDATION_ALLOWED_FIELDS" env-separator:","`
+	DeniedFields     []string `yaml:"denied_fields" env:"VALIDATION_DENIED_FIELDS" env-separator:","`
+	DisallowedFields string   `yaml:"disallowed_fields" env:"VALIDATION_DISALLOWED_FIELDS" env-default:"strip"`
 }
 
 type ApplicationConfig struct {
-	Env      string `yaml:"env" env:"APP_ENV" env-default:"development"`
-	LogLevel string `yaml:"log_level" env:"LOG_LEVEL" env-default:"info"`
+	Env                 string        `yaml:"env" env:"APP_ENV" env-default:"development"`
+	LogLevel            string        `yaml:"log_level" env:"LOG_LEVEL" env-default:"info"`
+	LogSampleRate       int           `yaml:"log_sample_rate" env:"LOG_SAMPLE_RATE" env-default:"1"`
+	LogSlowRequestAfter time.Duration `yaml:"log_slow_request_after" env:"LOG_SLOW_REQUEST_AFTER" env-default:"1s"`
+	EnableSwagger       string        `yaml:"enable_swagger" env:"APP_ENABLE_SWAGGER"`
+	ProcessTimeout      time.Duration `yaml:"document_process_timeout" env:"APP_DOCUMENT_PROCESS_TIMEOUT" env-default:"0s"`
+	BatchConcurrency    int           `yaml:"batch_concurrency" env:"APP_BATCH_CONCURRENCY" env-default:"1"`
+	SeedFile            string        `yaml:"seed_file" env:"APP_SEED_FILE"`
+	ListTimings         bool          `yaml:"list_timings" env:"APP_LIST_TIMINGS" env-default:"false"`
+}
+
+// SwaggerEnabled reports whether API docs are served. Unless enable_swagger is
+// set to a boolean, they are served everywhere except production.
+func (c ApplicationConfig) SwaggerEnabled() bool {
+	if enabled, err := strconv.ParseBool(c.EnableSwagger); err == nil {
+		return enabled
+	}
+	return c.Env != "production"
 }
 
 func Load(path string) (*Config, error) {
+	cfg, err := read(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Reindexer.resolveDSN(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// resolveDSN reads the DSN from DSNFile when one is configured and checks
+// that a DSN is set either way.
+func (c *ReindexerConfig) resolveDSN() error {
+	if c.DSNFile != "" {
+		data, err := os.ReadFile(c.DSNFile)
+		if err != nil {
+			return fmt.Errorf("failed to read reindexer dsn file: %w", err)
+		}
+		c.DSN = strings.TrimSpace(string(data))
+	}
+
+	if c.DSN == "" {
+		return fmt.Errorf("reindexer dsn is required: set REINDEXER_DSN or REINDEXER_DSN_FILE")
+	}
+	return nil
+}
+
+func read(path string) (*Config, error) {
 	cfg := &Config{}
 
 	if path != "" {
@@ -51,7 +196,16 @@ func Load(path string) (*Config, error) {
 		}
 
 		if err := cleanenv.ReadConfig(path, cfg); err != nil {
-			return nil, fmt.Errorf("failed to read config file: %w", err)
+			if isStrict() {
+				return nil, fmt.Errorf("failed to read config file: %w", err)
+			}
+
+			// В нестрогом режиме битый файл не мешает запуску, если хватает ENV
+			slog.Warn("Failed to read config file, falling back to env", "path", path, "error", err)
+			cfg = &Config{}
+			if err := cleanenv.ReadEnv(cfg); err != nil {
+				return nil, fmt.Errorf("failed to read env config: %w", err)
+			}
 		}
 	} else {
 		if err := cleanenv.ReadEnv(cfg); err != nil {
@@ -61,3 +215,13 @@ func Load(path string) (*Config, error) {
 
 	return cfg, nil
 }
+
+// isStrict reports whether a malformed config file must fail Load. Strict mode
+// is the default and is disabled with CONFIG_STRICT=false.
+func isStrict() bool {
+	strict, err := strconv.ParseBool(os.Getenv("CONFIG_STRICT"))
+	if err != nil {
+		return true
+	}
+	return strict
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeMalformedConfig(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("server:\n  port: [unclosed\n"), 0o600))
+	return path
+}
+
+func TestLoad_MalformedFile_Strict(t *testing.T) {
+	t.Setenv("REINDEXER_DSN", "cproto://localhost:6534/test")
+	t.Setenv("CONFIG_STRICT", "")
+
+	cfg, err := Load(writeMalformedConfig(t))
+
+	assert.Error(t, err)
+	assert.Nil(t, cfg)
+}
+
+func TestLoad_MalformedFile_Lenient(t *testing.T) {
+	t.Setenv("REINDEXER_DSN", "cproto://localhost:6534/test")
+	t.Setenv("SERVER_PORT", "9090")
+	t.Setenv("CONFIG_STRICT", "false")
+
+	cfg, err := Load(writeMalformedConfig(t))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "cproto://localhost:6534/test", cfg.Reindexer.DSN)
+	assert.Equal(t, 9090, cfg.Server.Port)
+	assert.Equal(t, "documents", cfg.Reindexer.Namespace)
+}
+
+func writeDSNFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "dsn")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_DSNFile(t *testing.T) {
+	t.Setenv("REINDEXER_DSN", "")
+	t.Setenv("REINDEXER_DSN_FILE", writeDSNFile(t, "  cproto://secret:6534/db\n\n"))
+
+	cfg, err := Load("")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "cproto://secret:6534/db", cfg.Reindexer.DSN)
+}
+
+func TestLoad_DSNFile_TakesPrecedence(t *testing.T) {
+	t.Setenv("REINDEXER_DSN", "cproto://inline:6534/db")
+	t.Setenv("REINDEXER_DSN_FILE", writeDSNFile(t, "cproto://secret:6534/db\n"))
+
+	cfg, err := Load("")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "cproto://secret:6534/db", cfg.Reindexer.DSN)
+}
+
+func TestLoad_DSNFile_Errors(t *testing.T) {
+	tests := []struct {
+		name, dsn, file string
+	}{
+		{name: "missing file", dsn: "cproto://inline:6534/db", file: filepath.Join(t.TempDir(), "absent")},
+		{name: "empty file", dsn: "cproto://inline:6534/db", file: writeDSNFile(t, " \n")},
+		{name: "no dsn at all"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("REINDEXER_DSN", tt.dsn)
+			t.Setenv("REINDEXER_DSN_FILE", tt.file)
+
+			cfg, err := Load("")
+
+			assert.Error(t, err)
+			assert.Nil(t, cfg)
+		})
+	}
+}
+
+func TestApplicationConfig_SwaggerEnabled(t *testing.T) {
+	tests := []struct {
+		env, flag string
+		want      bool
+	}{
+		{"development", "", true},
+		{"production", "", false},
+		{"production", "true", true},
+		{"development", "false", false},
+	}
+
+	for _, tt := range tests {
+		cfg := ApplicationConfig{Env: tt.env, EnableSwagger: tt.flag}
+		assert.Equal(t, tt.want, cfg.SwaggerEnabled(), "env=%s flag=%q", tt.env, tt.flag)
+	}
+}
@@ -0,0 +1,69 @@
+package features
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+const Header = "X-Feature-Flags"
+
+// SortSecondLevel orders second-level items by ID when processing documents.
+const SortSecondLevel = "sort_second_level"
+
+type contextKey struct{}
+
+// Set is the collection of feature flags enabled for a request.
+type Set map[string]struct{}
+
+// Parse reads a comma-separated list of flag names. Names are trimmed and
+// lower-cased; empty entries are ignored.
+func Parse(value string) Set {
+	set := make(Set)
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "" {
+			set[name] = struct{}{}
+		}
+	}
+	return set
+}
+
+func (s Set) Enabled(name string) bool {
+	_, ok := s[name]
+	return ok
+}
+
+// String returns the flags in a stable order, suitable for use in cache keys.
+func (s Set) String() string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+func WithContext(ctx context.Context, set Set) context.Context {
+	return context.WithValue(ctx, contextKey{}, set)
+}
+
+// FromContext returns the flags stored in ctx, or an empty set.
+func FromContext(ctx context.Context) Set {
+	if set, ok := ctx.Value(contextKey{}).(Set); ok {
+		return set
+	}
+	return Set{}
+}
+
+// Middleware stores the flags from the X-Feature-Flags header in the request
+// context.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if value := r.Header.Get(Header); value != "" {
+			r = r.WithContext(WithContext(r.Context(), Parse(value)))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,36 @@
+package features
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	set := Parse(" Sort_Second_Level, ,beta ")
+
+	assert.True(t, set.Enabled(SortSecondLevel))
+	assert.True(t, set.Enabled("beta"))
+	assert.False(t, set.Enabled("gamma"))
+	assert.Equal(t, "beta,sort_second_level", set.String())
+}
+
+func TestFromContext_Empty(t *testing.T) {
+	assert.False(t, FromContext(context.Background()).Enabled(SortSecondLevel))
+}
+
+func TestMiddleware(t *testing.T) {
+	var got Set
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = FromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(Header, "sort_second_level")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.True(t, got.Enabled(SortSecondLevel))
+}
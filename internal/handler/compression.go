@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"compress/flate"
+	"io"
+	"log"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Supported response compression algorithms. gzip and deflate are always
+// offered as fallbacks; the configured algorithm is preferred when the
+// client accepts it.
+const (
+	compressionGzip    = "gzip"
+	compressionDeflate = "deflate"
+	compressionZstd    = "zstd"
+)
+
+// newCompressor returns a compressor that prefers algorithm at level, or nil
+// when algorithm is empty and compression is disabled.
+func newCompressor(algorithm string, level int) *middleware.Compressor {
+	if algorithm == "" {
+		return nil
+	}
+
+	compressor := middleware.NewCompressor(level)
+	switch algorithm {
+	case compressionGzip:
+	case compressionDeflate:
+		// Re-registering moves deflate ahead of gzip.
+		compressor.SetEncoder(compressionDeflate, encoderDeflate)
+	case compressionZstd:
+		compressor.SetEncoder(compressionZstd, encoderZstd)
+	default:
+		log.Printf("Unknown compression algorithm %q, using gzip", algorithm)
+	}
+	return compressor
+}
+
+func encoderDeflate(w io.Writer, level int) io.Writer {
+	dw, err := flate.NewWriter(w, level)
+	if err != nil {
+		return nil
+	}
+	return dw
+}
+
+func encoderZstd(w io.Writer, level int) io.Writer {
+	encoder, err := zstd.NewWriter(w,
+		zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)),
+		zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		return nil
+	}
+	return encoder
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fedorovmatvey/involta-test/internal/model"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+)
+
+func decompress(t *testing.T, encoding string, body io.Reader) string {
+	t.Helper()
+
+	var r io.Reader
+	switch encoding {
+	case "gzip":
+		gr, err := gzip.NewReader(body)
+		assert.NoError(t, err)
+		r = gr
+	case "deflate":
+		r = flate.NewReader(body)
+	case "zstd":
+		zr, err := zstd.NewReader(body)
+		assert.NoError(t, err)
+		defer zr.Close()
+		r = zr
+	default:
+		r = body
+	}
+
+	data, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	return string(data)
+}
+
+func TestHandler_Compression(t *testing.T) {
+	tests := []struct {
+		name      string
+		algorithm string
+		accept    string
+		encoding  string
+	}{
+		{"zstd preferred", "zstd", "gzip, deflate, zstd", "zstd"},
+		{"zstd falls back to gzip", "zstd", "gzip", "gzip"},
+		{"zstd falls back to identity", "zstd", "", ""},
+		{"gzip", "gzip", "deflate, gzip", "gzip"},
+		{"deflate preferred", "deflate", "gzip, deflate", "deflate"},
+		{"disabled", "", "gzip, zstd", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &MockService{docs: map[string]*model.Document{"doc-1": {ID: "doc-1", Title: "compressed"}}}
+			router := New(svc, WithCompression(tt.algorithm, 5)).InitRoutes()
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/documents/doc-1", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept-Encoding", tt.accept)
+			}
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tt.encoding, rec.Header().Get("Content-Encoding"))
+			assert.Contains(t, decompress(t, tt.encoding, rec.Body), `"title":"compressed"`)
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// decodeBody decodes a JSON request body into v, rejecting unknown fields
+// when strict. Its errors are safe to show to clients and point at the
+// offending position; an empty body wraps io.EOF.
+func decodeBody(body io.Reader, v interface{}, strict bool) error {
+	var read bytes.Buffer
+	dec := json.NewDecoder(io.TeeReader(body, &read))
+	if strict {
+		dec.DisallowUnknownFields()
+	}
+
+	err := dec.Decode(v)
+	if err == nil {
+		return nil
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, io.EOF):
+		return fmt.Errorf("invalid request body: body is empty: %w", err)
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return errors.New("invalid JSON: unexpected end of body")
+	case errors.As(err, &syntaxErr):
+		return fmt.Errorf("invalid JSON at %s: %s", position(read.Bytes(), syntaxErr.Offset), syntaxErr.Error())
+	case errors.As(err, &typeErr):
+		return fmt.Errorf("invalid JSON at %s: cannot unmarshal %s into field %s of type %s",
+			position(read.Bytes(), typeErr.Offset), typeErr.Value, typeErr.Field, typeErr.Type)
+	default:
+		return fmt.Errorf("invalid request body: %s", strings.TrimPrefix(err.Error(), "json: "))
+	}
+}
+
+// position renders a decoder offset into data as line, column and offset.
+func position(data []byte, offset int64) string {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+
+	prefix := data[:offset]
+	line := bytes.Count(prefix, []byte("\n")) + 1
+	column := max(int(offset)-bytes.LastIndexByte(prefix, '\n')-1, 1)
+	return fmt.Sprintf("line %d, column %d (offset %d)", line, column, offset)
+}
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fedorovmatvey/involta-test/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeBody_SyntaxError(t *testing.T) {
+	var req model.CreateDocumentRequest
+
+	err := decodeBody(strings.NewReader("{\n  \"title\": \"a\",\n  \"items\": [}\n}"), &req, true)
+
+	assert.EqualError(t, err,
+		"invalid JSON at line 3, column 13 (offset 31): invalid character '}' looking for beginning of value")
+}
+
+func TestDecodeBody_TypeError(t *testing.T) {
+	var req model.CreateDocumentRequest
+
+	err := decodeBody(strings.NewReader(`{"items":[{"sort":"first"}]}`), &req, true)
+
+	assert.EqualError(t, err,
+		"invalid JSON at line 1, column 25 (offset 25): cannot unmarshal string into field items.0.sort of type int")
+}
+
+func TestDecodeBody_OtherErrors(t *testing.T) {
+	var req model.CreateDocumentRequest
+
+	err := decodeBody(strings.NewReader(""), &req, true)
+	assert.True(t, errors.Is(err, io.EOF))
+
+	assert.EqualError(t, decodeBody(strings.NewReader(`{"title":`), &req, true),
+		"invalid JSON: unexpected end of body")
+	assert.EqualError(t, decodeBody(strings.NewReader(`{"colour":"red"}`), &req, true),
+		`invalid request body: unknown field "colour"`)
+	assert.NoError(t, decodeBody(strings.NewReader(`{"colour":"red"}`), &req, false))
+}
+
+func TestHandler_CreateDocument_DecodeErrorMessage(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/documents", strings.NewReader(`{"title":1}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	newTestRouter().ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, decodeResponse(t, rec)["error"], "cannot unmarshal number into field title of type string")
+}
@@ -3,11 +3,24 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"math"
 	"net/http"
+	"net/url"
+	"slices"
 	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
+	"github.com/fedorovmatvey/involta-test/internal/apperror"
+	"github.com/fedorovmatvey/involta-test/internal/features"
+	"github.com/fedorovmatvey/involta-test/internal/httpparam"
 	"github.com/fedorovmatvey/involta-test/internal/model"
+	"github.com/fedorovmatvey/involta-test/internal/version"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 
@@ -17,44 +30,221 @@ import (
 
 type documentService interface {
 	Create(ctx context.Context, req model.CreateDocumentRequest) (*model.Document, error)
-	GetByID(ctx context.Context, id string) (*model.Document, error)
+	CreateWithID(ctx context.Context, id string, req model.CreateDocumentRequest) (*model.Document, error)
+	GetByID(ctx context.Context, id string, fresh bool) (*model.Document, error)
+	ListItems(ctx context.Context, id string, params model.PaginationParams) (*model.ItemList, error)
+	Exists(ctx context.Context, id string) (*model.DocumentMeta, error)
+	Health(ctx context.Context) *model.HealthStatus
 	Update(ctx context.Context, id string, req model.UpdateDocumentRequest) (*model.Document, error)
+	Upsert(ctx context.Context, id string, req model.UpdateDocumentRequest) (*model.Document, bool, error)
+	Clone(ctx context.Context, id string, req model.CloneDocumentRequest) (*model.Document, error)
+	Diff(ctx context.Context, id string, req model.UpdateDocumentRequest) (*model.DocumentDiff, error)
 	Delete(ctx context.Context, id string) error
+	UpdateWhere(ctx context.Context, req model.UpdateWhereRequest) (*model.UpdateWhereResult, error)
+	Preload(ctx context.Context, req model.PreloadRequest) (*model.PreloadResult, error)
 	List(ctx context.Context, params model.PaginationParams) (*model.DocumentList, error)
+	ExplainList(ctx context.Context, params model.PaginationParams) (*model.QueryExplain, error)
+	Stream(ctx context.Context, params model.PaginationParams, fn func(doc *model.Document) error) error
+	CreateBatch(ctx context.Context, req model.BatchCreateRequest) ([]*model.Document, error)
+	Import(ctx context.Context, req model.ImportRequest) ([]*model.Document, error)
+	UpdateBatch(ctx context.Context, req model.BatchUpdateRequest) ([]*model.Document, error)
+	DeleteMany(ctx context.Context, req model.BatchDeleteRequest) (*model.BatchDeleteResult, error)
 }
 type Handler struct {
-	service documentService
+	service     documentService
+	ready       atomic.Bool
+	debugErrors bool
+	metrics     http.Handler
+	env         string
+	// bodyLogLimit enables body logging when positive.
+	bodyLogLimit int
+	cacheMaxAge  time.Duration
+	// requestIDHeader falls back to X-Request-Id when empty.
+	requestIDHeader string
+	enforceJSON     bool
+	jsonParams      bool
+	validID         func(id string) bool
+	// logSampleRate logs one of every N successful requests when above one.
+	logSampleRate    int
+	logSlowThreshold time.Duration
+	maxInFlight      int
+	// maxURLLength answers 414 to longer request URIs when positive.
+	maxURLLength int
+	// retryAfter is sent on overload rejections when positive, plus up to
+	// retryAfterJitter of random delay.
+	retryAfter       time.Duration
+	retryAfterJitter time.Duration
+	// emptyListNoContent answers 204 instead of 200 when a list page has
+	// no documents.
+	emptyListNoContent bool
+	// strictPagination rejects out-of-range page and per_page with 400
+	// instead of clamping them.
+	strictPagination bool
+	swagger          bool
+	compressor       *middleware.Compressor
+	// serializers holds the response formats besides JSON, by media type.
+	serializers map[string]Serializer
 }
 
-func New(service documentService) *Handler {
-	return &Handler{
+func New(service documentService, opts ...Option) *Handler {
+	h := &Handler{
 		service: service,
+		swagger: true,
 	}
+	h.ready.Store(true)
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// SetReady controls what /health/ready reports. It is cleared while the cache
+// warms up so load balancers hold traffic until warmup completes.
+func (h *Handler) SetReady(ready bool) {
+	h.ready.Store(ready)
 }
 
 func (h *Handler) InitRoutes() http.Handler {
 	r := chi.NewRouter()
 
-	r.Use(middleware.RequestID)
+	r.Use(h.requestID)
 	r.Use(middleware.RealIP)
-	r.Use(middleware.Logger) // Встроенный логгер chi очень удобен
-	r.Use(middleware.Recoverer)
+	r.Use(h.requestLogger()) // логгер chi с выборкой: ошибки и медленные запросы пишутся всегда
+	r.Use(h.recoverer)
+	r.Use(h.limitURLLength)
+	r.Use(h.limitConcurrency)
+	if h.compressor != nil {
+		r.Use(h.compressor.Handler)
+	}
+	r.Use(features.Middleware)
+	if h.bodyLogLimit > 0 {
+		r.Use(h.bodyLogger)
+	}
+	r.Use(h.negotiate)
+
+	// Set before any Route so the sub-routers inherit them.
+	r.NotFound(routeNotFound)
+	r.MethodNotAllowed(methodNotAllowed)
 
 	r.Get("/health", h.HealthCheck)
-	r.Get("/swagger/*", httpSwagger.WrapHandler)
+	r.Get("/health/ready", h.ReadinessCheck)
+	r.Get("/version", h.Version)
+	if h.swagger {
+		r.Get("/swagger/*", httpSwagger.WrapHandler)
+	}
+	if h.metrics != nil {
+		r.Handle("/metrics", h.metrics)
+	}
+
+	r.Route(documentsPath, func(r chi.Router) {
+		r.Use(h.cacheControl)
+		r.Use(h.requireJSON)
 
-	r.Route("/api/v1/documents", func(r chi.Router) {
 		r.Get("/", h.ListDocuments)
 		r.Post("/", h.CreateDocument)
+		r.Get("/stream", h.StreamDocuments)
+		r.Post("/batch", h.CreateDocumentsBatch)
+		r.Put("/batch", h.UpdateDocumentsBatch)
+		r.Post("/batch-delete", h.DeleteDocumentsBatch)
 
 		r.Route("/{id}", func(r chi.Router) {
+			r.Use(h.validateID)
+
 			r.Get("/", h.GetDocumentById)
+			r.Get("/items", h.ListDocumentItems)
+			r.Head("/", h.HeadDocument)
 			r.Put("/", h.UpdateDocument)
 			r.Delete("/", h.DeleteDocument)
+			r.Post("/clone", h.CloneDocument)
+			r.Post("/diff", h.DiffDocument)
 		})
 	})
 
-	return r
+	r.Route("/api/v1/admin", func(r chi.Router) {
+		r.Use(h.cacheControl)
+		r.Use(h.requireJSON)
+
+		r.Post("/documents/update", h.UpdateDocumentsWhere)
+		r.Post("/documents/import", h.ImportDocuments)
+		r.Post("/cache/preload", h.PreloadCache)
+		// План запроса раскрывает детали хранилища, поэтому только вне production
+		if h.env != "production" {
+			r.Get("/explain/documents", h.ExplainListDocuments)
+		}
+	})
+
+	// /ping is answered before the router so that frequent load balancer
+	// checks skip logging, metrics and the concurrency limit.
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/ping" && (req.Method == http.MethodGet || req.Method == http.MethodHead) {
+			h.Ping(w, req)
+			return
+		}
+		r.ServeHTTP(w, req)
+	})
+}
+
+// Ping answers load balancer checks with a plain "pong", without checking
+// dependencies.
+func (h *Handler) Ping(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.WriteString(w, "pong")
+}
+
+// routeNotFound answers unknown paths in the usual JSON error format instead of
+// chi's plain-text 404.
+func routeNotFound(w http.ResponseWriter, r *http.Request) {
+	respondError(w, http.StatusNotFound, "route not found")
+}
+
+// methodNotAllowed answers known paths requested with an unsupported method
+// and lists the supported ones in Allow, as RFC 9110 requires. chi only sets
+// Allow in its own handler, and Routes.Match reports the stub routes of mount
+// points as matching every method, so the routes are walked instead.
+func methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	if allowed := allowedMethods(chi.RouteContext(r.Context()).Routes, r.URL.Path); len(allowed) > 0 {
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+	}
+	respondError(w, http.StatusMethodNotAllowed, fmt.Sprintf("method %s is not allowed", r.Method))
+}
+
+// allowedMethods returns the sorted methods routes serves path with.
+func allowedMethods(routes chi.Routes, path string) []string {
+	var allowed []string
+	_ = chi.Walk(routes, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if routeMatches(route, path) && !slices.Contains(allowed, method) {
+			allowed = append(allowed, method)
+		}
+		return nil
+	})
+	slices.Sort(allowed)
+	return allowed
+}
+
+// routeMatches reports whether path fits a chi route pattern. Trailing
+// slashes are ignored because a sub-router's "/" route serves the mount path.
+func routeMatches(pattern, path string) bool {
+	patternParts := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathParts := strings.Split(strings.Trim(path, "/"), "/")
+	for i, part := range patternParts {
+		if part == "*" {
+			return true
+		}
+		if i >= len(pathParts) {
+			return false
+		}
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			if pathParts[i] == "" {
+				return false
+			}
+			continue
+		}
+		if part != pathParts[i] {
+			return false
+		}
+	}
+	return len(patternParts) == len(pathParts)
 }
 
 func (h *Handler) HealthCheck(w http.ResponseWriter, r *http.Request) {
@@ -63,6 +253,32 @@ func (h *Handler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+func (h *Handler) ReadinessCheck(w http.ResponseWriter, r *http.Request) {
+	if !h.ready.Load() {
+		respondJSON(w, http.StatusServiceUnavailable, map[string]string{
+			"status": "warming_up",
+		})
+		return
+	}
+
+	health := h.service.Health(r.Context())
+	if health.Status != model.HealthReady {
+		respondJSON(w, http.StatusServiceUnavailable, health)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, health)
+}
+
+func (h *Handler) Version(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, map[string]string{
+		"version":    version.Version,
+		"commit":     version.Commit,
+		"build_time": version.BuildTime,
+		"env":        h.env,
+	})
+}
+
 // ListDocuments retrieves a paginated list of documents
 // @Summary List Documents
 // @Description Get all documents with pagination and sorting
@@ -71,25 +287,193 @@ func (h *Handler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param page query int false "Page number" default(1)
 // @Param per_page query int false "Items per page" default(10)
+// @Param updated_since query string false "Only documents updated after this RFC3339 timestamp, newest first"
+// @Param sort query string false "Sort field (created_at, updated_at, title); prefix with - for descending"
+// @Param has_status query string false "Only documents with a second-level item in this status. Not indexed, so it scans the namespace"
+// @Param tag query string false "Only documents with this tag"
+// @Param fields query string false "Document fields to return, with nested fields in parentheses, e.g. id,title,items(id,sort,second_level(status))"
+// @Param envelope query bool false "false returns a bare array of documents with pagination in X-Total-Count, X-Page, X-Per-Page and X-Total-Pages headers" default(true)
 // @Success 200 {object} model.DocumentList
+// @Success 204 "No documents on the page, when the server is configured to answer empty lists with 204"
+// @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/v1/documents [get]
 func (h *Handler) ListDocuments(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	params := model.PaginationParams{
-		Page:    parseIntQuery(r, "page", 1),
-		PerPage: parseIntQuery(r, "per_page", 10),
+	params, err := h.parseListParams(r)
+	if err != nil {
+		respondServiceError(w, err, "invalid list parameters")
+		return
+	}
+	envelope, err := httpparam.Bool(r.URL.Query(), "envelope", true)
+	if err != nil {
+		respondServiceError(w, err, "invalid list parameters")
+		return
+	}
+	sel, err := parseSelection(r.URL.Query())
+	if err != nil {
+		respondServiceError(w, err, "invalid list parameters")
+		return
 	}
 
 	list, err := h.service.List(ctx, params)
 	if err != nil {
 		log.Printf("Failed to list documents: %v", err)
-		respondError(w, http.StatusInternalServerError, "failed to list documents")
+		respondServiceError(w, err, "failed to list documents")
 		return
 	}
 
-	respondJSON(w, http.StatusOK, list)
+	if len(list.Documents) == 0 && h.emptyListNoContent {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if !envelope {
+		respondDocumentArray(w, list, sel)
+		return
+	}
+	respondSelected(w, http.StatusOK, list, sel, "documents")
+}
+
+// respondDocumentArray writes list as a bare JSON array for clients that do
+// not understand the envelope, moving the pagination into headers.
+func respondDocumentArray(w http.ResponseWriter, list *model.DocumentList, sel selection) {
+	w.Header().Set("X-Total-Count", strconv.Itoa(list.Total))
+	w.Header().Set("X-Page", strconv.Itoa(list.Page))
+	w.Header().Set("X-Per-Page", strconv.Itoa(list.PerPage))
+	w.Header().Set("X-Total-Pages", strconv.Itoa(list.TotalPages))
+
+	documents := list.Documents
+	if documents == nil {
+		documents = []model.Document{}
+	}
+	respondSelected(w, http.StatusOK, documents, sel, "")
+}
+
+// StreamDocuments streams every matching document as a JSON array
+// @Summary Stream Documents
+// @Description Write all documents matching the filter as a JSON array, one document at a time, without pagination
+// @Tags documents
+// @Produce json
+// @Param updated_since query string false "Only documents updated after this RFC3339 timestamp, newest first"
+// @Param sort query string false "Sort field (created_at, updated_at, title); prefix with - for descending"
+// @Param has_status query string false "Only documents with a second-level item in this status. Not indexed, so it scans the namespace"
+// @Param tag query string false "Only documents with this tag"
+// @Success 200 {array} model.Document
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/documents/stream [get]
+func (h *Handler) StreamDocuments(w http.ResponseWriter, r *http.Request) {
+	params, err := h.parseListParams(r)
+	if err != nil {
+		respondServiceError(w, err, "invalid list parameters")
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+	started := false
+
+	err = h.service.Stream(r.Context(), params, func(doc *model.Document) error {
+		sep := ","
+		if !started {
+			// Заголовки отправляем только с первым документом, чтобы ошибки
+			// до начала потока ещё можно было вернуть обычным ответом
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			started, sep = true, "["
+		}
+		if _, err := io.WriteString(w, sep); err != nil {
+			return err
+		}
+		if err := enc.Encode(doc); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+
+	if err != nil {
+		log.Printf("Failed to stream documents: %v", err)
+		if !started {
+			respondServiceError(w, err, "failed to stream documents")
+		}
+		// Начатый поток обрываем: клиент получит невалидный JSON
+		return
+	}
+
+	if !started {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, "[")
+	}
+	io.WriteString(w, "]\n")
+}
+
+// sortValues are the accepted values of the sort parameter: each sortable
+// field, ascending or with a - prefix for descending.
+var sortValues = func() []string {
+	values := make([]string, 0, 2*len(model.SortFields))
+	for _, field := range model.SortFields {
+		values = append(values, field, "-"+field)
+	}
+	return values
+}()
+
+// parseListParams reads pagination, filtering and sorting from the query
+// string.
+func (h *Handler) parseListParams(r *http.Request) (model.PaginationParams, error) {
+	query := r.URL.Query()
+
+	params, err := h.parsePageParams(r)
+	if err != nil {
+		return params, err
+	}
+	params.UpdatedSince, err = httpparam.Time(query, "updated_since")
+	if err != nil {
+		return params, err
+	}
+
+	sort, err := httpparam.Enum(query, "sort", "", sortValues...)
+	if err != nil {
+		return params, err
+	}
+	params.SortBy = strings.TrimPrefix(sort, "-")
+	params.SortDesc = strings.HasPrefix(sort, "-")
+	params.HasStatus = query.Get("has_status")
+	params.Tag = strings.TrimSpace(query.Get("tag"))
+
+	return params, nil
+}
+
+// parsePageParams reads page and per_page from the query string. Values out
+// of range are clamped by the service unless strict pagination rejects them.
+func (h *Handler) parsePageParams(r *http.Request) (model.PaginationParams, error) {
+	query := r.URL.Query()
+
+	if h.strictPagination {
+		page, err := httpparam.IntRange(query, "page", 1, 1, math.MaxInt)
+		if err != nil {
+			return model.PaginationParams{}, err
+		}
+		perPage, err := httpparam.IntRange(query, "per_page", 10, 1, model.MaxPerPage)
+		if err != nil {
+			return model.PaginationParams{}, err
+		}
+		return model.PaginationParams{Page: page, PerPage: perPage}, nil
+	}
+
+	page, err := httpparam.Int(query, "page", 1)
+	if err != nil {
+		return model.PaginationParams{}, err
+	}
+	perPage, err := httpparam.Int(query, "per_page", 10)
+	if err != nil {
+		return model.PaginationParams{}, err
+	}
+	return model.PaginationParams{Page: page, PerPage: perPage}, nil
 }
 
 // CreateDocument creates a new document
@@ -100,27 +484,28 @@ func (h *Handler) ListDocuments(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param input body model.CreateDocumentRequest true "Document payload"
 // @Success 201 {object} model.Document
+// @Header 201 {string} Location "Path of the created document"
 // @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 422 {object} map[string]interface{}
 // @Router /api/v1/documents [post]
 func (h *Handler) CreateDocument(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	var req model.CreateDocumentRequest
-	dec := json.NewDecoder(r.Body)
-	dec.DisallowUnknownFields()
-
-	if err := dec.Decode(&req); err != nil {
-		respondError(w, http.StatusBadRequest, "invalid request body")
+	if err := decodeBody(r.Body, &req, true); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	doc, err := h.service.Create(ctx, req)
 	if err != nil {
 		log.Printf("Failed to create document: %v", err)
-		respondError(w, http.StatusInternalServerError, "failed to create document")
+		respondServiceError(w, err, "failed to create document")
 		return
 	}
 
+	setLocation(w, doc.ID)
 	respondJSON(w, http.StatusCreated, doc)
 }
 
@@ -130,7 +515,12 @@ func (h *Handler) CreateDocument(w http.ResponseWriter, r *http.Request) {
 // @Tags documents
 // @Produce json
 // @Param id path string true "Document ID"
+// @Param fresh query bool false "Bypass the cache and read from storage"
+// @Param depth query int false "1 returns first-level items without second_level, 2 returns everything" default(2)
+// @Param fields query string false "Fields to return, with nested fields in parentheses, e.g. id,title,items(id,sort)"
+// @Failure 400 {object} map[string]string
 // @Success 200 {object} model.Document
+// @Header 200 {string} X-Cache "STALE when served from an expired cache entry because storage failed"
 // @Failure 404 {object} map[string]string
 // @Router /api/v1/documents/{id} [get]
 func (h *Handler) GetDocumentById(w http.ResponseWriter, r *http.Request) {
@@ -140,25 +530,121 @@ func (h *Handler) GetDocumentById(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	doc, err := h.service.GetByID(r.Context(), id)
+	depth, err := httpparam.IntRange(r.URL.Query(), "depth", maxDepth, 1, maxDepth)
+	if err != nil {
+		respondServiceError(w, err, "invalid depth")
+		return
+	}
+	fresh, err := wantsFresh(r)
+	if err != nil {
+		respondServiceError(w, err, "invalid fresh")
+		return
+	}
+	sel, err := parseSelection(r.URL.Query())
+	if err != nil {
+		respondServiceError(w, err, "invalid fields")
+		return
+	}
+
+	doc, err := h.service.GetByID(r.Context(), id, fresh)
 	if err != nil {
 		log.Printf("Failed to get document: %v", err)
-		respondError(w, http.StatusNotFound, "document not found")
+		respondServiceError(w, err, "failed to get document")
 		return
 	}
 
-	respondJSON(w, http.StatusOK, doc)
+	setDocumentHeaders(w, doc.ID, doc.UpdatedAt)
+	if doc.Stale {
+		w.Header().Set("X-Cache", "STALE")
+	}
+	shaped, err := limitDepth(doc, depth)
+	if err != nil {
+		log.Printf("Failed to limit document depth: %v", err)
+		respondError(w, http.StatusInternalServerError, "failed to encode response")
+		return
+	}
+	respondSelected(w, http.StatusOK, shaped, sel, "")
+}
+
+// ListDocumentItems returns a page of a document's first-level items
+// @Summary List Document Items
+// @Description Get first-level items of a document, sorted by sort descending, one page at a time
+// @Tags documents
+// @Produce json
+// @Param id path string true "Document ID"
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page" default(10)
+// @Success 200 {object} model.ItemList
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/documents/{id}/items [get]
+func (h *Handler) ListDocumentItems(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	params, err := h.parsePageParams(r)
+	if err != nil {
+		respondServiceError(w, err, "invalid pagination parameters")
+		return
+	}
+
+	list, err := h.service.ListItems(r.Context(), id, params)
+	if err != nil {
+		log.Printf("Failed to list document items: %v", err)
+		respondServiceError(w, err, "failed to list document items")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, list)
+}
+
+// HeadDocument checks that a document exists
+// @Summary Check Document
+// @Description Check a document exists and return its ETag/Last-Modified without a body
+// @Tags documents
+// @Param id path string true "Document ID"
+// @Success 200
+// @Failure 404
+// @Router /api/v1/documents/{id} [head]
+func (h *Handler) HeadDocument(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	meta, err := h.service.Exists(r.Context(), id)
+	if err != nil {
+		var notFound *apperror.NotFoundError
+		if errors.As(err, &notFound) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		log.Printf("Failed to check document: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	setDocumentHeaders(w, meta.ID, meta.UpdatedAt)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
 }
 
 // UpdateDocument updates a document
 // @Summary Update Document
-// @Description Update fields of an existing document
+// @Description Update fields of an existing document. With If-None-Match: * the body is a create payload and the document is created under this ID instead. With upsert=true a missing document is created from the given fields.
 // @Tags documents
 // @Accept json
 // @Produce json
 // @Param id path string true "Document ID"
+// @Param If-None-Match header string false "Set to * to create the document only if the ID is free"
+// @Param upsert query bool false "Create the document if it does not exist"
 // @Param input body model.UpdateDocumentRequest true "Update payload"
 // @Success 200 {object} model.Document
+// @Success 201 {object} model.Document
+// @Header 200,201 {string} X-Resource-Created "true when the request created the document"
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 422 {object} map[string]interface{}
 // @Router /api/v1/documents/{id} [put]
 func (h *Handler) UpdateDocument(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
@@ -167,22 +653,154 @@ func (h *Handler) UpdateDocument(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if r.Header.Get("If-None-Match") == "*" {
+		h.createDocumentWithID(w, r, id)
+		return
+	}
+
+	upsert, err := httpparam.Bool(r.URL.Query(), "upsert", false)
+	if err != nil {
+		respondServiceError(w, err, "invalid upsert")
+		return
+	}
+
 	var req model.UpdateDocumentRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondError(w, http.StatusBadRequest, "invalid request body")
+	if err := decodeBody(r.Body, &req, false); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if upsert {
+		h.upsertDocument(w, r, id, req)
 		return
 	}
 
 	doc, err := h.service.Update(r.Context(), id, req)
 	if err != nil {
 		log.Printf("Failed to update document: %v", err)
-		respondError(w, http.StatusInternalServerError, "failed to update document")
+		respondServiceError(w, err, "failed to update document")
+		return
+	}
+
+	w.Header().Set(resourceCreatedHeader, "false")
+	respondJSON(w, http.StatusOK, doc)
+}
+
+// resourceCreatedHeader tells PUT clients whether the document was created.
+const resourceCreatedHeader = "X-Resource-Created"
+
+// upsertDocument is the create-or-update branch of PUT /documents/{id}.
+func (h *Handler) upsertDocument(w http.ResponseWriter, r *http.Request, id string, req model.UpdateDocumentRequest) {
+	doc, created, err := h.service.Upsert(r.Context(), id, req)
+	if err != nil {
+		log.Printf("Failed to upsert document %s: %v", id, err)
+		respondServiceError(w, err, "failed to upsert document")
 		return
 	}
 
+	w.Header().Set(resourceCreatedHeader, strconv.FormatBool(created))
+	if created {
+		setLocation(w, doc.ID)
+		respondJSON(w, http.StatusCreated, doc)
+		return
+	}
 	respondJSON(w, http.StatusOK, doc)
 }
 
+// createDocumentWithID is the create-only branch of PUT /documents/{id}.
+func (h *Handler) createDocumentWithID(w http.ResponseWriter, r *http.Request, id string) {
+	var req model.CreateDocumentRequest
+	if err := decodeBody(r.Body, &req, true); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	doc, err := h.service.CreateWithID(r.Context(), id, req)
+	if err != nil {
+		log.Printf("Failed to create document %s: %v", id, err)
+		respondServiceError(w, err, "failed to create document")
+		return
+	}
+
+	w.Header().Set(resourceCreatedHeader, "true")
+	setLocation(w, doc.ID)
+	respondJSON(w, http.StatusCreated, doc)
+}
+
+// CloneDocument duplicates a document
+// @Summary Clone Document
+// @Description Create a copy of a document with new IDs for it and all nested items
+// @Tags documents
+// @Accept json
+// @Produce json
+// @Param id path string true "Source document ID"
+// @Param input body model.CloneDocumentRequest false "Clone overrides"
+// @Success 201 {object} model.Document
+// @Header 201 {string} Location "Path of the created document"
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 422 {object} map[string]interface{}
+// @Router /api/v1/documents/{id}/clone [post]
+func (h *Handler) CloneDocument(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		respondError(w, http.StatusBadRequest, "document id is required")
+		return
+	}
+
+	var req model.CloneDocumentRequest
+	if err := decodeBody(r.Body, &req, false); err != nil && !errors.Is(err, io.EOF) {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	doc, err := h.service.Clone(r.Context(), id, req)
+	if err != nil {
+		log.Printf("Failed to clone document: %v", err)
+		respondServiceError(w, err, "failed to clone document")
+		return
+	}
+
+	setLocation(w, doc.ID)
+	respondJSON(w, http.StatusCreated, doc)
+}
+
+// DiffDocument compares an update payload with the stored document
+// @Summary Diff Document
+// @Description List the fields, including nested items, that applying the update would add, remove or change. Nothing is written.
+// @Tags documents
+// @Accept json
+// @Produce json
+// @Param id path string true "Document ID"
+// @Param input body model.UpdateDocumentRequest true "Proposed update"
+// @Success 200 {object} model.DocumentDiff
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 422 {object} map[string]interface{}
+// @Router /api/v1/documents/{id}/diff [post]
+func (h *Handler) DiffDocument(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		respondError(w, http.StatusBadRequest, "document id is required")
+		return
+	}
+
+	var req model.UpdateDocumentRequest
+	if err := decodeBody(r.Body, &req, false); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	diff, err := h.service.Diff(r.Context(), id, req)
+	if err != nil {
+		log.Printf("Failed to diff document %s: %v", id, err)
+		respondServiceError(w, err, "failed to diff document")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, diff)
+}
+
 // DeleteDocument deletes a document
 // @Summary Delete Document
 // @Description Remove a document by ID
@@ -209,28 +827,381 @@ func (h *Handler) DeleteDocument(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// CreateDocumentsBatch creates several documents at once
+// @Summary Batch Create Documents
+// @Description Create several documents; with atomic=true either all are stored or none
+// @Tags documents
+// @Accept json
+// @Produce json
+// @Param input body model.BatchCreateRequest true "Batch payload"
+// @Success 201 {array} model.Document
+// @Header 201 {string} Location "Path of the created document, set when the batch has one document"
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 422 {object} map[string]interface{}
+// @Router /api/v1/documents/batch [post]
+func (h *Handler) CreateDocumentsBatch(w http.ResponseWriter, r *http.Request) {
+	var req model.BatchCreateRequest
+	if err := decodeBody(r.Body, &req, true); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	docs, err := h.service.CreateBatch(r.Context(), req)
+	if err != nil {
+		log.Printf("Failed to create documents batch: %v", err)
+		respondServiceError(w, err, "failed to create documents")
+		return
+	}
+
+	if len(docs) == 1 {
+		setLocation(w, docs[0].ID)
+	}
+	respondJSON(w, http.StatusCreated, docs)
+}
+
+// UpdateDocumentsBatch updates several documents at once
+// @Summary Batch Update Documents
+// @Description Update several documents; with atomic=true either all are updated or none
+// @Tags documents
+// @Accept json
+// @Produce json
+// @Param input body model.BatchUpdateRequest true "Batch payload"
+// @Success 200 {array} model.Document
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 422 {object} map[string]interface{}
+// @Router /api/v1/documents/batch [put]
+func (h *Handler) UpdateDocumentsBatch(w http.ResponseWriter, r *http.Request) {
+	var req model.BatchUpdateRequest
+	if err := decodeBody(r.Body, &req, false); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	docs, err := h.service.UpdateBatch(r.Context(), req)
+	if err != nil {
+		log.Printf("Failed to update documents batch: %v", err)
+		respondServiceError(w, err, "failed to update documents")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, docs)
+}
+
+// DeleteDocumentsBatch deletes several documents at once
+// @Summary Batch Delete Documents
+// @Description Delete the documents with the given IDs and report the IDs that did not exist
+// @Tags documents
+// @Accept json
+// @Produce json
+// @Param input body model.BatchDeleteRequest true "Document IDs"
+// @Success 200 {object} model.BatchDeleteResult
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/documents/batch-delete [post]
+func (h *Handler) DeleteDocumentsBatch(w http.ResponseWriter, r *http.Request) {
+	var req model.BatchDeleteRequest
+	if err := decodeBody(r.Body, &req, false); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := h.service.DeleteMany(r.Context(), req)
+	if err != nil {
+		log.Printf("Failed to delete documents batch: %v", err)
+		respondServiceError(w, err, "failed to delete documents")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, result)
+}
+
+// maxDepth is the nesting depth of a full document: first- and second-level
+// items.
+const maxDepth = 2
+
+// limitDepth shapes doc for the response down to depth levels of items. At
+// depth 1 the items lose their second_level key rather than reporting it as
+// null, which the stored model cannot express without changing every other
+// response, so the shallow form is built from the encoded document.
+func limitDepth(doc *model.Document, depth int) (interface{}, error) {
+	if depth >= maxDepth {
+		return doc, nil
+	}
+
+	generic, err := toGeneric(doc)
+	if err != nil {
+		return nil, err
+	}
+	object, _ := generic.(map[string]interface{})
+	items, _ := object["items"].([]interface{})
+	for _, item := range items {
+		if item, ok := item.(map[string]interface{}); ok {
+			delete(item, "second_level")
+		}
+	}
+	return generic, nil
+}
+
+// UpdateDocumentsWhere bulk-updates documents matching a filter
+// @Summary Bulk Update Documents
+// @Description Set fields on every document matching the filter without loading them
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param input body model.UpdateWhereRequest true "Filter and changes"
+// @Success 200 {object} model.UpdateWhereResult
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/admin/documents/update [post]
+func (h *Handler) UpdateDocumentsWhere(w http.ResponseWriter, r *http.Request) {
+	var req model.UpdateWhereRequest
+	if err := decodeBody(r.Body, &req, true); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := h.service.UpdateWhere(r.Context(), req)
+	if err != nil {
+		log.Printf("Failed to bulk update documents: %v", err)
+		respondServiceError(w, err, "failed to update documents")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, result)
+}
+
+// ImportDocuments creates documents migrated from another system
+// @Summary Import Documents
+// @Description Create documents keeping the created_at and updated_at they carry. A missing created_at or updated_at defaults to the other, and both default to the import time. With atomic=true either all are stored or none
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param input body model.ImportRequest true "Documents to import"
+// @Success 201 {array} model.Document
+// @Failure 400 {object} map[string]string
+// @Failure 422 {object} map[string]interface{}
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/admin/documents/import [post]
+func (h *Handler) ImportDocuments(w http.ResponseWriter, r *http.Request) {
+	var req model.ImportRequest
+	if err := decodeBody(r.Body, &req, true); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	docs, err := h.service.Import(r.Context(), req)
+	if err != nil {
+		log.Printf("Failed to import documents: %v", err)
+		respondServiceError(w, err, "failed to import documents")
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, docs)
+}
+
+// PreloadCache loads the given documents into the cache
+// @Summary Preload Cache
+// @Description Fetch the given documents from storage in one query and cache them
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param input body model.PreloadRequest true "Document IDs"
+// @Success 200 {object} model.PreloadResult
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/admin/cache/preload [post]
+func (h *Handler) PreloadCache(w http.ResponseWriter, r *http.Request) {
+	var req model.PreloadRequest
+	if err := decodeBody(r.Body, &req, true); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := h.service.Preload(r.Context(), req)
+	if err != nil {
+		log.Printf("Failed to preload cache: %v", err)
+		respondServiceError(w, err, "failed to preload cache")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, result)
+}
+
+// ExplainListDocuments returns the storage plan of a list query
+// @Summary Explain List Query
+// @Description Run the list query with explain enabled and return the plan and timings. Not available in production.
+// @Tags admin
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page" default(10)
+// @Param updated_since query string false "Only documents updated after this RFC3339 timestamp"
+// @Param sort query string false "Sort field (created_at, updated_at, title); prefix with - for descending"
+// @Param has_status query string false "Only documents with a second-level item in this status. Not indexed, so it scans the namespace"
+// @Param tag query string false "Only documents with this tag"
+// @Success 200 {object} model.QueryExplain
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/admin/explain/documents [get]
+func (h *Handler) ExplainListDocuments(w http.ResponseWriter, r *http.Request) {
+	params, err := h.parseListParams(r)
+	if err != nil {
+		respondServiceError(w, err, "invalid list parameters")
+		return
+	}
+
+	explain, err := h.service.ExplainList(r.Context(), params)
+	if err != nil {
+		log.Printf("Failed to explain list query: %v", err)
+		respondServiceError(w, err, "failed to explain list query")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, explain)
+}
+
+// documentsPath is the collection path new documents are located under.
+const documentsPath = "/api/v1/documents"
+
+func setLocation(w http.ResponseWriter, id string) {
+	w.Header().Set("Location", documentsPath+"/"+url.PathEscape(id))
+}
+
+func setDocumentHeaders(w http.ResponseWriter, id string, updatedAt time.Time) {
+	w.Header().Set("ETag", documentETag(id, updatedAt))
+	w.Header().Set("Last-Modified", updatedAt.UTC().Format(http.TimeFormat))
+}
+
+func documentETag(id string, updatedAt time.Time) string {
+	return fmt.Sprintf(`"%s-%x"`, id, updatedAt.UnixNano())
+}
+
+// respondJSON writes data with the serializer negotiated for the request,
+// which is JSON unless the client asked for another registered format.
 func respondJSON(w http.ResponseWriter, status int, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
+	serializer := serializerFor(w)
+	w.Header().Set("Content-Type", serializer.MediaType())
+	if status >= http.StatusBadRequest {
+		w.Header().Set("Cache-Control", "no-store")
+	}
 	w.WriteHeader(status)
-	if err := json.NewEncoder(w).Encode(data); err != nil {
+	if err := serializer.Encode(w, data); err != nil {
 		log.Printf("Failed to encode response: %v", err)
 	}
 }
 
+// respondSelected writes data trimmed to sel, or all of it when sel is nil.
+// See selection.project for under.
+func respondSelected(w http.ResponseWriter, status int, data interface{}, sel selection, under string) {
+	if sel == nil {
+		respondJSON(w, status, data)
+		return
+	}
+	projected, err := sel.project(data, under)
+	if err != nil {
+		log.Printf("Failed to select response fields: %v", err)
+		respondError(w, http.StatusInternalServerError, "failed to encode response")
+		return
+	}
+	respondJSON(w, status, projected)
+}
+
 func respondError(w http.ResponseWriter, status int, message string) {
 	respondJSON(w, status, map[string]string{
 		"error": message,
+		"code":  statusCode(status),
 	})
 }
 
-func parseIntQuery(r *http.Request, key string, defaultValue int) int {
-	value := r.URL.Query().Get(key)
-	if value == "" {
-		return defaultValue
+// statusCode is the error code for responses without a typed error.
+func statusCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return apperror.CodeBadRequest
+	case http.StatusNotFound:
+		return apperror.CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return apperror.CodeMethodNotAllowed
+	case http.StatusConflict:
+		return apperror.CodeConflict
+	case http.StatusUnsupportedMediaType:
+		return apperror.CodeUnsupportedMediaType
+	case http.StatusUnprocessableEntity:
+		return apperror.CodeValidationFailed
+	case http.StatusTooManyRequests:
+		return apperror.CodeRateLimited
+	case http.StatusServiceUnavailable:
+		return apperror.CodeUnavailable
+	default:
+		return apperror.CodeInternal
 	}
-	intValue, err := strconv.Atoi(value)
-	if err != nil {
-		return defaultValue
+}
+
+// respondServiceError maps typed service errors to their HTTP status and
+// falls back to a 500 with the given message.
+func respondServiceError(w http.ResponseWriter, err error, message string) {
+	var notFound *apperror.NotFoundError
+	if errors.As(err, &notFound) {
+		respondJSON(w, http.StatusNotFound, map[string]string{
+			"error":    notFound.Message(),
+			"code":     notFound.Code(),
+			"resource": string(notFound.Resource),
+			"id":       notFound.ID,
+		})
+		return
+	}
+
+	var conflict *apperror.ConflictError
+	if errors.As(err, &conflict) {
+		respondJSON(w, http.StatusConflict, map[string]string{
+			"error":    conflict.Message(),
+			"code":     conflict.Code(),
+			"resource": string(conflict.Resource),
+			"id":       conflict.ID,
+		})
+		return
+	}
+
+	var validation *apperror.ValidationError
+	if errors.As(err, &validation) {
+		respondJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error": validation.Message,
+			"code":  validation.Code(),
+			"field": validation.Field,
+			"limit": validation.Limit,
+		})
+		return
+	}
+
+	var unavailable *apperror.UnavailableError
+	if errors.As(err, &unavailable) {
+		if unavailable.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int((unavailable.RetryAfter+time.Second-1)/time.Second)))
+		}
+		respondError(w, http.StatusServiceUnavailable, unavailable.Message)
+		return
+	}
+
+	var invalid *apperror.InvalidParameterError
+	if errors.As(err, &invalid) {
+		respondJSON(w, http.StatusBadRequest, map[string]string{
+			"error":     invalid.Message,
+			"code":      invalid.Code(),
+			"parameter": invalid.Parameter,
+		})
+		return
+	}
+
+	respondError(w, http.StatusInternalServerError, message)
+}
+
+// wantsFresh reports whether the client asked to bypass the cache, either with
+// ?fresh=true or a Cache-Control: no-cache header.
+func wantsFresh(r *http.Request) (bool, error) {
+	fresh, err := httpparam.Bool(r.URL.Query(), "fresh", false)
+	if err != nil || fresh {
+		return fresh, err
 	}
-	return intValue
+	return strings.Contains(strings.ToLower(r.Header.Get("Cache-Control")), "no-cache"), nil
 }
@@ -0,0 +1,963 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fedorovmatvey/involta-test/internal/apperror"
+	"github.com/fedorovmatvey/involta-test/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type MockService struct {
+	docs       map[string]*model.Document
+	listParams model.PaginationParams
+	fresh      bool
+	health     *model.HealthStatus
+}
+
+func (m *MockService) Create(ctx context.Context, req model.CreateDocumentRequest) (*model.Document, error) {
+	return &model.Document{ID: "new", Title: req.Title}, nil
+}
+
+func (m *MockService) CreateWithID(ctx context.Context, id string, req model.CreateDocumentRequest) (*model.Document, error) {
+	if _, ok := m.docs[id]; ok {
+		return nil, apperror.Conflict(apperror.ResourceDocument, id)
+	}
+	return &model.Document{ID: id, Title: req.Title}, nil
+}
+
+func (m *MockService) GetByID(ctx context.Context, id string, fresh bool) (*model.Document, error) {
+	m.fresh = fresh
+	return m.find(id)
+}
+
+func (m *MockService) ListItems(ctx context.Context, id string, params model.PaginationParams) (*model.ItemList, error) {
+	m.listParams = params
+	doc, err := m.find(id)
+	if err != nil {
+		return nil, err
+	}
+	return &model.ItemList{Items: doc.Items, Total: len(doc.Items), Page: params.Page, PerPage: params.PerPage}, nil
+}
+
+func (m *MockService) find(id string) (*model.Document, error) {
+	doc, ok := m.docs[id]
+	if !ok {
+		return nil, fmt.Errorf("document not found: %w", apperror.NotFound(apperror.ResourceDocument, id))
+	}
+	return doc, nil
+}
+
+func (m *MockService) Health(ctx context.Context) *model.HealthStatus {
+	if m.health != nil {
+		return m.health
+	}
+	return &model.HealthStatus{Status: model.HealthReady, Storage: model.HealthOK, Cache: model.CacheHealth{Status: model.HealthOK}}
+}
+
+func (m *MockService) Preload(ctx context.Context, req model.PreloadRequest) (*model.PreloadResult, error) {
+	return &model.PreloadResult{Loaded: len(req.IDs)}, nil
+}
+
+func (m *MockService) ExplainList(ctx context.Context, params model.PaginationParams) (*model.QueryExplain, error) {
+	m.listParams = params
+	return &model.QueryExplain{TotalUs: 42, SortIndex: "created_at", Selectors: []model.QuerySelector{}}, nil
+}
+
+func (m *MockService) Stream(ctx context.Context, params model.PaginationParams, fn func(doc *model.Document) error) error {
+	m.listParams = params
+	for _, id := range []string{"doc-1", "doc-2"} {
+		if doc, ok := m.docs[id]; ok {
+			if err := fn(doc); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (m *MockService) Exists(ctx context.Context, id string) (*model.DocumentMeta, error) {
+	doc, err := m.find(id)
+	if err != nil {
+		return nil, err
+	}
+	return &model.DocumentMeta{ID: doc.ID, UpdatedAt: doc.UpdatedAt}, nil
+}
+
+func (m *MockService) Update(ctx context.Context, id string, req model.UpdateDocumentRequest) (*model.Document, error) {
+	return m.find(id)
+}
+
+func (m *MockService) Upsert(ctx context.Context, id string, req model.UpdateDocumentRequest) (*model.Document, bool, error) {
+	if doc, ok := m.docs[id]; ok {
+		return doc, false, nil
+	}
+	return &model.Document{ID: id}, true, nil
+}
+
+func (m *MockService) Clone(ctx context.Context, id string, req model.CloneDocumentRequest) (*model.Document, error) {
+	return m.find(id)
+}
+
+func (m *MockService) Diff(ctx context.Context, id string, req model.UpdateDocumentRequest) (*model.DocumentDiff, error) {
+	doc, err := m.find(id)
+	if err != nil {
+		return nil, err
+	}
+	diff := &model.DocumentDiff{ID: id}
+	if req.Title != nil && *req.Title != doc.Title {
+		diff.Changed = append(diff.Changed, model.FieldChange{Path: "title", Old: doc.Title, New: *req.Title})
+	}
+	return diff, nil
+}
+
+func (m *MockService) Delete(ctx context.Context, id string) error { return nil }
+
+func (m *MockService) UpdateWhere(ctx context.Context, req model.UpdateWhereRequest) (*model.UpdateWhereResult, error) {
+	return &model.UpdateWhereResult{Updated: len(req.Filter)}, nil
+}
+
+func (m *MockService) List(ctx context.Context, params model.PaginationParams) (*model.DocumentList, error) {
+	m.listParams = params
+	if params.SortBy == "internal" {
+		return nil, apperror.InvalidParameter("sort", `unsupported field "internal"`)
+	}
+	if params.Page == 7 {
+		return &model.DocumentList{Documents: []model.Document{{ID: "doc-1"}}, Total: 61, Page: 7, PerPage: 10, TotalPages: 7}, nil
+	}
+	return &model.DocumentList{Documents: []model.Document{}, Page: params.Page, PerPage: params.PerPage}, nil
+}
+
+func (m *MockService) CreateBatch(ctx context.Context, req model.BatchCreateRequest) ([]*model.Document, error) {
+	return nil, nil
+}
+
+func (m *MockService) Import(ctx context.Context, req model.ImportRequest) ([]*model.Document, error) {
+	docs := make([]*model.Document, 0, len(req.Documents))
+	for _, d := range req.Documents {
+		doc := &model.Document{ID: "imported", Title: d.Title}
+		if d.CreatedAt != nil {
+			doc.CreatedAt = *d.CreatedAt
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+func (m *MockService) UpdateBatch(ctx context.Context, req model.BatchUpdateRequest) ([]*model.Document, error) {
+	return nil, nil
+}
+
+func (m *MockService) DeleteMany(ctx context.Context, req model.BatchDeleteRequest) (*model.BatchDeleteResult, error) {
+	result := &model.BatchDeleteResult{Missing: []string{}}
+	for _, id := range req.IDs {
+		if _, ok := m.docs[id]; ok {
+			result.Deleted++
+		} else {
+			result.Missing = append(result.Missing, id)
+		}
+	}
+	return result, nil
+}
+
+var testUpdatedAt = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestRouter() http.Handler {
+	return New(&MockService{docs: map[string]*model.Document{
+		"doc-1": {ID: "doc-1", Title: "first", UpdatedAt: testUpdatedAt},
+	}}).InitRoutes()
+}
+
+func decodeResponse(t *testing.T, rec *httptest.ResponseRecorder) map[string]string {
+	t.Helper()
+	var body map[string]string
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	return body
+}
+
+func TestHandler_Ping(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	newTestRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ping", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "pong", rec.Body.String())
+	assert.Equal(t, "text/plain; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Empty(t, rec.Header().Get(defaultRequestIDHeader))
+
+	rec = httptest.NewRecorder()
+	newTestRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/ping", nil))
+	assert.NotEqual(t, http.StatusOK, rec.Code)
+}
+
+func TestHandler_GetDocument_NotFoundBody(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/documents/missing", nil)
+
+	newTestRouter().ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, map[string]string{
+		"error":    "document not found",
+		"code":     "document_not_found",
+		"resource": "document",
+		"id":       "missing",
+	}, decodeResponse(t, rec))
+}
+
+func TestRespondServiceError_ResourceBodies(t *testing.T) {
+	tests := []struct {
+		resource apperror.Resource
+		message  string
+		code     string
+	}{
+		{apperror.ResourceDocument, "document not found", "document_not_found"},
+		{apperror.ResourceItem, "item not found", "item_not_found"},
+		{apperror.ResourceSecondLevel, "second level not found", "second_level_not_found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.resource), func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			err := fmt.Errorf("wrapped: %w", apperror.NotFound(tt.resource, "id-1"))
+
+			respondServiceError(rec, err, "failed")
+
+			assert.Equal(t, http.StatusNotFound, rec.Code)
+			assert.Equal(t, map[string]string{
+				"error":    tt.message,
+				"code":     tt.code,
+				"resource": string(tt.resource),
+				"id":       "id-1",
+			}, decodeResponse(t, rec))
+		})
+	}
+}
+
+func TestRespondServiceError_FallsBackTo500(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	respondServiceError(rec, fmt.Errorf("boom"), "failed to get document")
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, map[string]string{"error": "failed to get document", "code": "internal_error"}, decodeResponse(t, rec))
+}
+
+func TestHandler_HeadDocument_Present(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodHead, "/api/v1/documents/doc-1", nil)
+
+	newTestRouter().ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Body.String())
+	assert.Equal(t, documentETag("doc-1", testUpdatedAt), rec.Header().Get("ETag"))
+	assert.Equal(t, "Wed, 01 May 2024 12:00:00 GMT", rec.Header().Get("Last-Modified"))
+}
+
+func TestHandler_HeadDocument_Absent(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodHead, "/api/v1/documents/missing", nil)
+
+	newTestRouter().ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Empty(t, rec.Body.String())
+	assert.Empty(t, rec.Header().Get("ETag"))
+}
+
+func TestHandler_Readiness_FlipsAfterWarmup(t *testing.T) {
+	h := New(&MockService{})
+	router := h.InitRoutes()
+	h.SetReady(false)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "warming_up", decodeResponse(t, rec)["status"])
+
+	h.SetReady(true)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status":"ready","storage":"ok","cache":{"status":"ok"}}`, rec.Body.String())
+}
+
+func TestHandler_Readiness_StorageDown(t *testing.T) {
+	svc := &MockService{health: &model.HealthStatus{
+		Status:  model.HealthUnavailable,
+		Storage: model.HealthDown,
+		Cache:   model.CacheHealth{Status: model.HealthOK},
+	}}
+	rec := httptest.NewRecorder()
+
+	New(svc).InitRoutes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.JSONEq(t, `{"status":"unavailable","storage":"down","cache":{"status":"ok"}}`, rec.Body.String())
+}
+
+func TestRespondServiceError_Validation(t *testing.T) {
+	rec := httptest.NewRecorder()
+	err := fmt.Errorf("wrapped: %w", apperror.Validation("title", 255, "must be at most 255 characters"))
+
+	respondServiceError(rec, err, "failed")
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	var body map[string]interface{}
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	assert.Equal(t, "title", body["field"])
+	assert.Equal(t, float64(255), body["limit"])
+	assert.Equal(t, "must be at most 255 characters", body["error"])
+	assert.Equal(t, "validation_failed", body["code"])
+}
+
+func TestHandler_ListDocuments_UpdatedSince(t *testing.T) {
+	svc := &MockService{}
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/documents?updated_since=2024-05-01T12:00:00Z", nil)
+
+	New(svc).InitRoutes().ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, testUpdatedAt.Equal(svc.listParams.UpdatedSince))
+}
+
+func TestHandler_ListDocuments_InvalidUpdatedSince(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/documents?updated_since=yesterday", nil)
+
+	newTestRouter().ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestHandler_GetDocument_Fresh(t *testing.T) {
+	tests := []struct {
+		name   string
+		url    string
+		header string
+		fresh  bool
+	}{
+		{"default", "/api/v1/documents/doc-1", "", false},
+		{"query", "/api/v1/documents/doc-1?fresh=true", "", true},
+		{"header", "/api/v1/documents/doc-1", "no-cache", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &MockService{docs: map[string]*model.Document{"doc-1": {ID: "doc-1"}}}
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			if tt.header != "" {
+				req.Header.Set("Cache-Control", tt.header)
+			}
+
+			rec := httptest.NewRecorder()
+			New(svc).InitRoutes().ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tt.fresh, svc.fresh)
+		})
+	}
+}
+
+func TestHandler_ListDocuments_Sort(t *testing.T) {
+	svc := &MockService{}
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/documents?sort=-title", nil)
+
+	New(svc).InitRoutes().ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "title", svc.listParams.SortBy)
+	assert.True(t, svc.listParams.SortDesc)
+}
+
+func TestHandler_ListDocuments_HasStatus(t *testing.T) {
+	svc := &MockService{}
+	rec := httptest.NewRecorder()
+
+	New(svc).InitRoutes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/documents?has_status=approved", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "approved", svc.listParams.HasStatus)
+}
+
+func TestHandler_ImportDocuments(t *testing.T) {
+	rec := httptest.NewRecorder()
+	body := `{"documents":[{"title":"legacy","created_at":"2019-03-01T10:00:00Z","updated_at":"2021-07-15T08:30:00Z"}]}`
+
+	newTestRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/admin/documents/import", strings.NewReader(body)))
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	var docs []model.Document
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&docs))
+	if assert.Len(t, docs, 1) {
+		assert.Equal(t, "legacy", docs[0].Title)
+		assert.Equal(t, time.Date(2019, 3, 1, 10, 0, 0, 0, time.UTC), docs[0].CreatedAt)
+	}
+}
+
+func TestHandler_ListDocuments_EmptyList(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newTestRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/documents", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"documents": [], "total": 0, "page": 1, "per_page": 10, "total_pages": 0, "has_next": false, "has_prev": false}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	newTestRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/documents?envelope=false", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[]`, rec.Body.String())
+
+	router := New(&MockService{}, WithEmptyListNoContent(true)).InitRoutes()
+	for _, url := range []string{"/api/v1/documents", "/api/v1/documents?envelope=false"} {
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		assert.Equal(t, http.StatusNoContent, rec.Code, url)
+		assert.Empty(t, rec.Body.String(), url)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/documents?page=7", nil))
+	assert.Equal(t, http.StatusOK, rec.Code, "non-empty pages are unaffected")
+}
+
+func TestHandler_ListDocuments_Tag(t *testing.T) {
+	svc := &MockService{}
+	rec := httptest.NewRecorder()
+
+	New(svc).InitRoutes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/documents?tag=+urgent+", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "urgent", svc.listParams.Tag)
+}
+
+func TestHandler_ListDocuments_StrictPagination(t *testing.T) {
+	tests := []struct {
+		query     string
+		parameter string
+	}{
+		{"per_page=0", "per_page"},
+		{"per_page=101", "per_page"},
+		{"page=-1", "page"},
+		{"page=0&per_page=10", "page"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+
+			New(&MockService{}, WithStrictPagination(true)).InitRoutes().
+				ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/documents?"+tt.query, nil))
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Equal(t, tt.parameter, decodeResponse(t, rec)["parameter"])
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	svc := &MockService{}
+	New(svc, WithStrictPagination(true)).InitRoutes().
+		ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/documents?page=2&per_page=100", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, model.PaginationParams{Page: 2, PerPage: 100}, svc.listParams)
+}
+
+func TestHandler_ListDocuments_LenientPaginationClamps(t *testing.T) {
+	svc := &MockService{}
+	rec := httptest.NewRecorder()
+
+	New(svc).InitRoutes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/documents?page=-1&per_page=0", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	params := svc.listParams
+	params.Validate()
+	assert.Equal(t, 1, params.Page)
+	assert.Equal(t, 10, params.PerPage)
+}
+
+func TestHandler_ListDocuments_UnknownSortField(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/documents?sort=internal", nil)
+	svc := &MockService{}
+
+	New(svc).InitRoutes().ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "sort", decodeResponse(t, rec)["parameter"])
+	assert.Zero(t, svc.listParams, "an unknown sort field must be rejected before the service")
+}
+
+func TestHandler_GetDocument_Depth(t *testing.T) {
+	doc := &model.Document{ID: "doc-1", Items: []model.FirstLevelItem{
+		{ID: "item-1", SecondLevel: []model.SecondLevelItem{{ID: "sub-1"}}},
+	}}
+
+	tests := []struct {
+		url         string
+		secondLevel bool
+	}{
+		{"/api/v1/documents/doc-1?depth=1", false},
+		{"/api/v1/documents/doc-1?depth=2", true},
+		{"/api/v1/documents/doc-1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			svc := &MockService{docs: map[string]*model.Document{"doc-1": doc}}
+			rec := httptest.NewRecorder()
+
+			New(svc).InitRoutes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.url, nil))
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			var got model.Document
+			assert.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+			assert.Len(t, got.Items, 1)
+			assert.Equal(t, "item-1", got.Items[0].ID)
+			if tt.secondLevel {
+				assert.Len(t, got.Items[0].SecondLevel, 1)
+			} else {
+				assert.Empty(t, got.Items[0].SecondLevel)
+			}
+		})
+	}
+
+	assert.Len(t, doc.Items[0].SecondLevel, 1)
+}
+
+func TestHandler_GetDocument_InvalidDepth(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	newTestRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/documents/doc-1?depth=3", nil))
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestHandler_Version_Defaults(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	New(&MockService{}, WithEnv("test")).InitRoutes().
+		ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/version", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, map[string]string{
+		"version":    "dev",
+		"commit":     "unknown",
+		"build_time": "unknown",
+		"env":        "test",
+	}, decodeResponse(t, rec))
+}
+
+func TestHandler_CacheControl_GetUsesMaxAge(t *testing.T) {
+	rec := httptest.NewRecorder()
+	svc := &MockService{docs: map[string]*model.Document{"doc-1": {ID: "doc-1", UpdatedAt: testUpdatedAt}}}
+
+	New(svc, WithCacheControl(15*time.Minute)).InitRoutes().
+		ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/documents/doc-1", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "max-age=900", rec.Header().Get("Cache-Control"))
+}
+
+func TestHandler_CacheControl_PostIsNoStore(t *testing.T) {
+	rec := httptest.NewRecorder()
+	body := strings.NewReader(`{"title":"new"}`)
+
+	New(&MockService{}, WithCacheControl(15*time.Minute)).InitRoutes().
+		ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/documents", body))
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+}
+
+func TestHandler_CacheControl_ErrorIsNoStore(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	New(&MockService{}, WithCacheControl(15*time.Minute)).InitRoutes().
+		ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/documents/missing", nil))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+}
+
+func TestHandler_CreateDocument_Location(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	newTestRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/documents", strings.NewReader(`{"title":"new"}`)))
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "/api/v1/documents/new", rec.Header().Get("Location"))
+}
+
+func TestHandler_CloneDocument_Location(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	newTestRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/documents/doc-1/clone", nil))
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "/api/v1/documents/doc-1", rec.Header().Get("Location"))
+}
+
+func TestHandler_DiffDocument(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	newTestRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/documents/doc-1/diff",
+		strings.NewReader(`{"title":"second"}`)))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"id":"doc-1","added":null,"removed":null,
+		"changed":[{"path":"title","old":"first","new":"second"}]}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	newTestRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/documents/missing/diff",
+		strings.NewReader(`{}`)))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestHandler_ExplainListDocuments(t *testing.T) {
+	svc := &MockService{}
+	rec := httptest.NewRecorder()
+
+	New(svc, WithEnv("development")).InitRoutes().
+		ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/admin/explain/documents?sort=-title", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"total_us":42,"preselect_us":0,"prepare_us":0,"indexes_us":0,"postprocess_us":0,
+		"loop_us":0,"general_sort_us":0,"sort_index":"created_at","selectors":[]}`, rec.Body.String())
+	assert.Equal(t, "title", svc.listParams.SortBy)
+}
+
+func TestHandler_ExplainListDocuments_HiddenInProduction(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	New(&MockService{}, WithEnv("production")).InitRoutes().
+		ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/admin/explain/documents", nil))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestHandler_StreamDocuments(t *testing.T) {
+	svc := &MockService{docs: map[string]*model.Document{
+		"doc-1": {ID: "doc-1", Title: "first"},
+		"doc-2": {ID: "doc-2", Title: "second"},
+	}}
+	rec := httptest.NewRecorder()
+
+	New(svc).InitRoutes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/documents/stream?sort=title", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, rec.Flushed)
+	var docs []model.Document
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &docs))
+	assert.Len(t, docs, 2)
+	assert.Equal(t, "doc-1", docs[0].ID)
+	assert.Equal(t, "doc-2", docs[1].ID)
+	assert.Equal(t, "title", svc.listParams.SortBy)
+}
+
+func TestHandler_StreamDocuments_Empty(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	New(&MockService{}).InitRoutes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/documents/stream", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[]`, rec.Body.String())
+}
+
+func putCreateOnly(id string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/documents/"+id, strings.NewReader(`{"title":"imported"}`))
+	req.Header.Set("If-None-Match", "*")
+	rec := httptest.NewRecorder()
+	newTestRouter().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandler_PutIfNoneMatch_Creates(t *testing.T) {
+	rec := putCreateOnly("client-1")
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "/api/v1/documents/client-1", rec.Header().Get("Location"))
+	var doc model.Document
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&doc))
+	assert.Equal(t, "client-1", doc.ID)
+	assert.Equal(t, "imported", doc.Title)
+}
+
+func TestHandler_PutIfNoneMatch_Exists(t *testing.T) {
+	rec := putCreateOnly("doc-1")
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, map[string]string{
+		"error":    "document already exists",
+		"code":     "document_already_exists",
+		"resource": "document",
+		"id":       "doc-1",
+	}, decodeResponse(t, rec))
+}
+
+func TestRespondServiceError_InvalidParameterCode(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	respondServiceError(rec, apperror.InvalidParameter("sort", "unsupported field"), "failed")
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "invalid_parameter", decodeResponse(t, rec)["code"])
+}
+
+func TestRespondServiceError_Unavailable(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	err := fmt.Errorf("failed to get document: %w", apperror.Unavailable("storage is unavailable", 1500*time.Millisecond))
+	respondServiceError(rec, err, "failed")
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("Retry-After"))
+	assert.Equal(t, map[string]string{"error": "storage is unavailable", "code": "unavailable"}, decodeResponse(t, rec))
+}
+
+func TestRespondError_StatusCodes(t *testing.T) {
+	tests := map[int]string{
+		http.StatusBadRequest:           "bad_request",
+		http.StatusUnsupportedMediaType: "unsupported_media_type",
+		http.StatusTooManyRequests:      "rate_limited",
+		http.StatusInternalServerError:  "internal_error",
+	}
+
+	for status, code := range tests {
+		rec := httptest.NewRecorder()
+
+		respondError(rec, status, "message")
+
+		assert.Equal(t, code, decodeResponse(t, rec)["code"])
+	}
+}
+
+func TestHandler_Swagger_Toggle(t *testing.T) {
+	for enabled, status := range map[bool]int{true: http.StatusOK, false: http.StatusNotFound} {
+		rec := httptest.NewRecorder()
+
+		New(&MockService{}, WithSwagger(enabled)).InitRoutes().
+			ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/swagger/doc.json", nil))
+
+		assert.Equal(t, status, rec.Code, "enabled=%v", enabled)
+	}
+}
+
+func TestHandler_GetDocument_StaleHeader(t *testing.T) {
+	svc := &MockService{docs: map[string]*model.Document{
+		"doc-1": {ID: "doc-1"},
+		"doc-2": {ID: "doc-2", Stale: true},
+	}}
+	router := New(svc).InitRoutes()
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/documents/doc-1", nil))
+	assert.Empty(t, rec.Header().Get("X-Cache"))
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/documents/doc-2", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "STALE", rec.Header().Get("X-Cache"))
+}
+
+func TestHandler_InvalidQueryParameters(t *testing.T) {
+	tests := []struct {
+		url       string
+		parameter string
+	}{
+		{"/api/v1/documents?page=two", "page"},
+		{"/api/v1/documents?per_page=many", "per_page"},
+		{"/api/v1/documents/doc-1?depth=0", "depth"},
+		{"/api/v1/documents/doc-1?fresh=maybe", "fresh"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+
+			newTestRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.url, nil))
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			body := decodeResponse(t, rec)
+			assert.Equal(t, apperror.CodeInvalidParameter, body["code"])
+			assert.Equal(t, tt.parameter, body["parameter"])
+		})
+	}
+}
+
+func TestHandler_ListDocumentItems(t *testing.T) {
+	svc := &MockService{docs: map[string]*model.Document{
+		"doc-1": {ID: "doc-1", Items: []model.FirstLevelItem{{ID: "item-1"}}},
+	}}
+	router := New(svc).InitRoutes()
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/documents/doc-1/items?page=2&per_page=5", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 2, svc.listParams.Page)
+	assert.Equal(t, 5, svc.listParams.PerPage)
+	var list model.ItemList
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&list))
+	assert.Equal(t, 1, list.Total)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/documents/missing/items", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestHandler_DeleteDocumentsBatch(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/documents/batch-delete", strings.NewReader(`{"ids":["doc-1","gone"]}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	newTestRouter().ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"deleted":1,"missing":["gone"]}`, rec.Body.String())
+}
+
+func TestHandler_ListDocuments_Envelope(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		body     string
+		paginate bool
+	}{
+		{"default envelope", "/api/v1/documents?page=7", `"documents":[`, false},
+		{"bare array", "/api/v1/documents?page=7&envelope=false", `[{"id":"doc-1"`, true},
+		{"empty bare array", "/api/v1/documents?envelope=false", `[]`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+
+			newTestRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.url, nil))
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Contains(t, rec.Body.String(), tt.body)
+			if tt.paginate {
+				assert.NotEmpty(t, rec.Header().Get("X-Total-Count"))
+			} else {
+				assert.Empty(t, rec.Header().Get("X-Total-Count"))
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	newTestRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/documents?page=7&envelope=false", nil))
+	assert.Equal(t, "61", rec.Header().Get("X-Total-Count"))
+	assert.Equal(t, "7", rec.Header().Get("X-Page"))
+	assert.Equal(t, "10", rec.Header().Get("X-Per-Page"))
+	assert.Equal(t, "7", rec.Header().Get("X-Total-Pages"))
+}
+
+func TestHandler_PutUpsert(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		status   int
+		created  string
+		location string
+	}{
+		{"updates existing", "/api/v1/documents/doc-1?upsert=true", http.StatusOK, "false", ""},
+		{"creates missing", "/api/v1/documents/doc-new?upsert=true", http.StatusCreated, "true", "/api/v1/documents/doc-new"},
+		{"plain update", "/api/v1/documents/doc-1", http.StatusOK, "false", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPut, tt.url, strings.NewReader(`{"title":"t"}`))
+			req.Header.Set("Content-Type", "application/json")
+
+			newTestRouter().ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Equal(t, tt.created, rec.Header().Get("X-Resource-Created"))
+			assert.Equal(t, tt.location, rec.Header().Get("Location"))
+		})
+	}
+}
+
+func TestHandler_InternalFieldNotExposed(t *testing.T) {
+	svc := &MockService{docs: map[string]*model.Document{
+		"doc-1": {ID: "doc-1", Title: "public", Internal: "secret"},
+		"doc-2": {ID: "doc-2", Internal: "secret"},
+	}}
+	router := New(svc).InitRoutes()
+
+	for _, url := range []string{"/api/v1/documents/doc-1", "/api/v1/documents/stream"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.NotContains(t, rec.Body.String(), "secret")
+		assert.NotContains(t, rec.Body.String(), "Internal")
+	}
+}
+
+func TestHandler_UnknownRoute_JSON(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	newTestRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/unknown", nil))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "application/json")
+	body := decodeResponse(t, rec)
+	assert.Equal(t, "route not found", body["error"])
+	assert.Equal(t, apperror.CodeNotFound, body["code"])
+}
+
+func TestHandler_MethodNotAllowed_JSON(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	newTestRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodPatch, "/api/v1/documents/doc-1", nil))
+
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "application/json")
+	assert.Equal(t, "DELETE, GET, HEAD, PUT", rec.Header().Get("Allow"))
+	body := decodeResponse(t, rec)
+	assert.Equal(t, "method PATCH is not allowed", body["error"])
+	assert.Equal(t, apperror.CodeMethodNotAllowed, body["code"])
+}
+
+func TestHandler_GetDocument_DepthWireFormat(t *testing.T) {
+	svc := &MockService{docs: map[string]*model.Document{"doc-1": {ID: "doc-1", Items: []model.FirstLevelItem{
+		{ID: "item-1", SecondLevel: []model.SecondLevelItem{{ID: "sub-1"}}},
+		{ID: "item-2"},
+	}}}}
+	router := New(svc).InitRoutes()
+
+	for query, want := range map[string]string{
+		"depth=1": `[{"id": "item-1"}, {"id": "item-2"}]`,
+		"depth=2": `[{"id": "item-1", "second_level": [{"id": "sub-1"}]}, {"id": "item-2", "second_level": null}]`,
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/documents/doc-1?fields=items(id,second_level(id))&"+query, nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code, query)
+		var body map[string]json.RawMessage
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+		assert.JSONEq(t, want, string(body["items"]), query)
+	}
+}
+
+func TestRouteMatches(t *testing.T) {
+	assert.True(t, routeMatches("/api/v1/documents/{id}/", "/api/v1/documents/doc-1"))
+	assert.True(t, routeMatches("/api/v1/documents/{id}/items", "/api/v1/documents/doc-1/items/"))
+	assert.True(t, routeMatches("/swagger/*", "/swagger/index.html"))
+	assert.False(t, routeMatches("/api/v1/documents/{id}/", "/api/v1/documents/doc-1/items"))
+	assert.False(t, routeMatches("/api/v1/documents/{id}/items", "/api/v1/documents/doc-1"))
+	assert.False(t, routeMatches("/api/v1/documents/stream", "/api/v1/documents/other"))
+}
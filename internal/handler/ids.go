@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+var ulidPattern = regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Za-hjkmnp-tv-z]{25}$`)
+
+// idValidators maps the supported ID formats to their checks. An empty format
+// accepts any ID, e.g. for documents imported from other systems.
+var idValidators = map[string]func(id string) bool{
+	"uuid": func(id string) bool {
+		_, err := uuid.Parse(id)
+		return err == nil
+	},
+	"ulid": ulidPattern.MatchString,
+}
+
+// CheckIDFormat reports a format WithIDFormat does not support, so a typo in
+// the configuration fails startup instead of turning validation off.
+func CheckIDFormat(format string) error {
+	if _, ok := idValidators[format]; format != "" && !ok {
+		return fmt.Errorf("unknown ID format %q", format)
+	}
+	return nil
+}
+
+func idValidator(format string) func(id string) bool {
+	return idValidators[format]
+}
+
+// validateID rejects malformed {id} path parameters with 400 before they
+// reach storage.
+func (h *Handler) validateID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.validID != nil && !h.validID(chi.URLParam(r, "id")) {
+			respondError(w, http.StatusBadRequest, "invalid document id format")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fedorovmatvey/involta-test/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func getDocumentWithFormat(format, id string) int {
+	svc := &MockService{docs: map[string]*model.Document{id: {ID: id, UpdatedAt: testUpdatedAt}}}
+	rec := httptest.NewRecorder()
+	New(svc, WithIDFormat(format)).InitRoutes().
+		ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/documents/"+id, nil))
+	return rec.Code
+}
+
+func TestValidateID_UUID(t *testing.T) {
+	assert.Equal(t, http.StatusOK, getDocumentWithFormat("uuid", "9b2d3f1e-4c5a-4b6d-8e7f-0a1b2c3d4e5f"))
+	assert.Equal(t, http.StatusBadRequest, getDocumentWithFormat("uuid", "not-a-uuid"))
+}
+
+func TestValidateID_ULID(t *testing.T) {
+	assert.Equal(t, http.StatusOK, getDocumentWithFormat("ulid", "01ARZ3NDEKTSV4RRFFQ69G5FAV"))
+	assert.Equal(t, http.StatusBadRequest, getDocumentWithFormat("ulid", "01ARZ3NDEKTSV4RRFFQ69G5FAU!"))
+}
+
+func TestValidateID_AnyByDefault(t *testing.T) {
+	assert.Equal(t, http.StatusOK, getDocumentWithFormat("", "legacy-42"))
+}
+
+func TestCheckIDFormat(t *testing.T) {
+	for _, format := range []string{"", "uuid", "ulid"} {
+		assert.NoError(t, CheckIDFormat(format), format)
+	}
+	assert.Error(t, CheckIDFormat("uiid"))
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// sampledLogFormatter wraps a chi log formatter and writes only one of every
+// rate successful requests. Errors and requests slower than slow are always
+// written.
+type sampledLogFormatter struct {
+	next  middleware.LogFormatter
+	rate  uint64
+	slow  time.Duration
+	count atomic.Uint64
+}
+
+func newSampledLogFormatter(next middleware.LogFormatter, rate int, slow time.Duration) *sampledLogFormatter {
+	if rate < 1 {
+		rate = 1
+	}
+	return &sampledLogFormatter{next: next, rate: uint64(rate), slow: slow}
+}
+
+func (f *sampledLogFormatter) NewLogEntry(r *http.Request) middleware.LogEntry {
+	return &sampledLogEntry{LogEntry: f.next.NewLogEntry(r), formatter: f}
+}
+
+func (f *sampledLogFormatter) keep(status int, elapsed time.Duration) bool {
+	if status >= http.StatusBadRequest {
+		return true
+	}
+	if f.slow > 0 && elapsed >= f.slow {
+		return true
+	}
+	return (f.count.Add(1)-1)%f.rate == 0
+}
+
+type sampledLogEntry struct {
+	middleware.LogEntry
+	formatter *sampledLogFormatter
+}
+
+func (e *sampledLogEntry) Write(status, bytes int, header http.Header, elapsed time.Duration, extra interface{}) {
+	if e.formatter.keep(status, elapsed) {
+		e.LogEntry.Write(status, bytes, header, elapsed, extra)
+	}
+}
+
+// requestLogger is chi's request logger, sampled when a sample rate above one
+// is configured.
+func (h *Handler) requestLogger() func(http.Handler) http.Handler {
+	if h.logSampleRate <= 1 {
+		return middleware.Logger
+	}
+	formatter := &middleware.DefaultLogFormatter{Logger: log.New(os.Stdout, "", log.LstdFlags)}
+	return middleware.RequestLogger(newSampledLogFormatter(formatter, h.logSampleRate, h.logSlowThreshold))
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func serveSampled(rate int, slow time.Duration, status int, delay time.Duration, requests int) string {
+	var buf bytes.Buffer
+	formatter := newSampledLogFormatter(&middleware.DefaultLogFormatter{Logger: log.New(&buf, "", 0), NoColor: true}, rate, slow)
+	handler := middleware.RequestLogger(formatter)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		w.WriteHeader(status)
+	}))
+
+	for i := 0; i < requests; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/documents", nil))
+	}
+	return buf.String()
+}
+
+func TestSampledLogger_SamplesSuccesses(t *testing.T) {
+	logs := serveSampled(5, 0, http.StatusOK, 0, 10)
+
+	assert.Equal(t, 2, strings.Count(logs, "\n"))
+}
+
+func TestSampledLogger_AlwaysLogsErrors(t *testing.T) {
+	logs := serveSampled(5, 0, http.StatusInternalServerError, 0, 10)
+
+	assert.Equal(t, 10, strings.Count(logs, "\n"))
+}
+
+func TestSampledLogger_AlwaysLogsSlowRequests(t *testing.T) {
+	logs := serveSampled(5, time.Millisecond, http.StatusOK, 2*time.Millisecond, 3)
+
+	assert.Equal(t, 3, strings.Count(logs, "\n"))
+}
@@ -0,0 +1,286 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"mime"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fedorovmatvey/involta-test/internal/apperror"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// defaultRequestIDHeader is used when no request ID header is configured.
+const defaultRequestIDHeader = "X-Request-Id"
+
+// requestID reuses the inbound request ID from the configured header or
+// generates one, stores it for middleware.GetReqID and echoes it back.
+func (h *Handler) requestID(next http.Handler) http.Handler {
+	header := h.requestIDHeader
+	if header == "" {
+		header = defaultRequestIDHeader
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(header)
+		if id == "" {
+			id = strconv.FormatUint(middleware.NextRequestID(), 10)
+		}
+
+		w.Header().Set(header, id)
+		ctx := context.WithValue(r.Context(), middleware.RequestIDKey, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// limitConcurrency rejects API requests with 503 while maxInFlight of them
+// are already being served. Requests are never queued; rejected clients are
+// told when to come back through Retry-After.
+func (h *Handler) limitConcurrency(next http.Handler) http.Handler {
+	if h.maxInFlight <= 0 {
+		return next
+	}
+
+	sem := make(chan struct{}, h.maxInFlight)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if unlimitedPath(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		select {
+		case sem <- struct{}{}:
+			defer func() { <-sem }()
+			next.ServeHTTP(w, r)
+		default:
+			if h.retryAfter > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(h.retryAfter, h.retryAfterJitter)))
+			}
+			respondError(w, http.StatusServiceUnavailable, "server is overloaded")
+		}
+	})
+}
+
+// unlimitedPath reports paths the concurrency limit does not apply to. Health
+// checks and metrics must keep answering under overload, or orchestrators
+// restart pods that are merely busy, and a stream would hold a slot for as
+// long as its client keeps reading.
+func unlimitedPath(path string) bool {
+	return !strings.HasPrefix(path, "/api/v1/") || path == documentsPath+"/stream"
+}
+
+// limitURLLength rejects requests whose request URI, path and query
+// included, is longer than maxURLLength with 414.
+func (h *Handler) limitURLLength(next http.Handler) http.Handler {
+	if h.maxURLLength <= 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uri := r.RequestURI
+		if uri == "" {
+			uri = r.URL.RequestURI()
+		}
+		if len(uri) > h.maxURLLength {
+			respondError(w, http.StatusRequestURITooLong, fmt.Sprintf("request URL must not exceed %d bytes", h.maxURLLength))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// retryAfterSeconds picks a Retry-After value in [base, base+jitter], rounded
+// up to whole seconds, so rejected clients do not all retry at once.
+func retryAfterSeconds(base, jitter time.Duration) int {
+	wait := base
+	if jitter > 0 {
+		wait += time.Duration(rand.Int64N(int64(jitter) + 1))
+	}
+	return int((wait + time.Second - 1) / time.Second)
+}
+
+// recoverer turns a panic into a JSON 500 and logs it with the request ID and
+// stack trace.
+func (h *Handler) recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+
+			stack := string(debug.Stack())
+			slog.Error("Panic recovered",
+				"request_id", middleware.GetReqID(r.Context()),
+				"method", r.Method,
+				"path", r.URL.Path,
+				"panic", rec,
+				"stack", stack,
+			)
+
+			body := map[string]string{"error": "internal server error", "code": apperror.CodeInternal}
+			if h.debugErrors {
+				body["panic"] = fmt.Sprint(rec)
+				body["stack"] = stack
+			}
+			respondJSON(w, http.StatusInternalServerError, body)
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// cacheControl lets intermediaries cache successful reads for cacheMaxAge and
+// forbids storing responses to writes. Error responses are marked no-store in
+// respondJSON.
+func (h *Handler) cacheControl(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+			if h.cacheMaxAge > 0 {
+				w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d", int(h.cacheMaxAge.Seconds())))
+			}
+		default:
+			w.Header().Set("Cache-Control", "no-store")
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireJSON rejects write requests whose body is not declared as
+// application/json with 415. Parameters such as charset are accepted only when
+// jsonParams is set. Bodyless requests, e.g. a clone without overrides, pass.
+func (h *Handler) requireJSON(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !h.enforceJSON || r.ContentLength == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
+			mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if err != nil || mediaType != "application/json" || (len(params) > 0 && !h.jsonParams) {
+				respondError(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys lists JSON keys, lower-cased, whose values are never logged.
+var sensitiveKeys = map[string]bool{
+	"metadata":     true,
+	"meta_data":    true,
+	"privateinfo":  true,
+	"private_info": true,
+	"internal":     true,
+}
+
+// bodyLogger logs request and response bodies at debug level. Bodies are
+// captured up to h.bodyLogLimit bytes while being passed through unchanged.
+func (h *Handler) bodyLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !slog.Default().Enabled(r.Context(), slog.LevelDebug) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		reqBody := &limitedBuffer{limit: h.bodyLogLimit}
+		if r.Body != nil {
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.TeeReader(r.Body, reqBody), r.Body}
+		}
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		respBody := &limitedBuffer{limit: h.bodyLogLimit}
+		ww.Tee(respBody)
+
+		next.ServeHTTP(ww, r)
+
+		slog.Debug("HTTP body",
+			"request_id", middleware.GetReqID(r.Context()),
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", ww.Status(),
+			"request_body", reqBody.loggable(),
+			"response_body", respBody.loggable(),
+		)
+	})
+}
+
+// limitedBuffer keeps the first limit bytes written to it and counts the rest.
+type limitedBuffer struct {
+	buf   bytes.Buffer
+	limit int
+	total int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	b.total += len(p)
+	if room := b.limit - b.buf.Len(); room > 0 {
+		if len(p) > room {
+			b.buf.Write(p[:room])
+		} else {
+			b.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+// loggable returns the captured body with sensitive fields redacted. Bodies
+// that were truncated or are not JSON cannot be redacted reliably, so only
+// their size is reported.
+func (b *limitedBuffer) loggable() string {
+	if b.total == 0 {
+		return ""
+	}
+	if b.total > b.buf.Len() {
+		return fmt.Sprintf("[%d bytes, truncated]", b.total)
+	}
+
+	var body interface{}
+	if err := json.Unmarshal(b.buf.Bytes(), &body); err != nil {
+		return fmt.Sprintf("[%d bytes, not json]", b.total)
+	}
+
+	out, err := json.Marshal(redact(body))
+	if err != nil {
+		return fmt.Sprintf("[%d bytes]", b.total)
+	}
+	return string(out)
+}
+
+func redact(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for key, item := range val {
+			if sensitiveKeys[strings.ToLower(key)] {
+				val[key] = redacted
+				continue
+			}
+			val[key] = redact(item)
+		}
+	case []interface{}:
+		for i, item := range val {
+			val[i] = redact(item)
+		}
+	}
+	return v
+}
@@ -0,0 +1,291 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPanicServer(h *Handler) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		respondJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	return httptest.NewServer(middleware.RequestID(h.recoverer(mux)))
+}
+
+func TestRecoverer_JSON500(t *testing.T) {
+	srv := newPanicServer(New(&MockService{}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/panic")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	var body map[string]string
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, map[string]string{"error": "internal server error", "code": "internal_error"}, body)
+
+	next, err := http.Get(srv.URL + "/ok")
+	assert.NoError(t, err)
+	defer next.Body.Close()
+	assert.Equal(t, http.StatusOK, next.StatusCode)
+}
+
+func TestRecoverer_DebugIncludesStack(t *testing.T) {
+	srv := newPanicServer(New(&MockService{}, WithDebugErrors(true)))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/panic")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var body map[string]string
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "boom", body["panic"])
+	assert.NotEmpty(t, body["stack"])
+}
+
+func withLogger(t *testing.T, level slog.Level) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: level})))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return &buf
+}
+
+func serveBodyLogged(t *testing.T) {
+	t.Helper()
+	h := New(&MockService{}, WithBodyLogging(4096))
+	body := `{"title":"hello","items":[{"name":"n","MetaData":"meta-secret","private_info":"pi-secret"}]}`
+
+	rec := httptest.NewRecorder()
+	h.InitRoutes().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/documents/batch",
+		strings.NewReader(`{"documents":[`+body+`]}`)))
+}
+
+func TestBodyLogger_Debug(t *testing.T) {
+	logs := withLogger(t, slog.LevelDebug)
+
+	serveBodyLogged(t)
+
+	assert.Contains(t, logs.String(), "HTTP body")
+	assert.Contains(t, logs.String(), "hello")
+	assert.NotContains(t, logs.String(), "meta-secret")
+	assert.NotContains(t, logs.String(), "pi-secret")
+	assert.Contains(t, logs.String(), redacted)
+}
+
+func TestBodyLogger_Info(t *testing.T) {
+	logs := withLogger(t, slog.LevelInfo)
+
+	serveBodyLogged(t)
+
+	assert.NotContains(t, logs.String(), "HTTP body")
+	assert.NotContains(t, logs.String(), "hello")
+}
+
+func TestLimitedBuffer_Truncated(t *testing.T) {
+	b := &limitedBuffer{limit: 4}
+	_, _ = b.Write([]byte(`{"secret":"value"}`))
+
+	assert.Equal(t, "[18 bytes, truncated]", b.loggable())
+}
+
+func serveRequestID(h *Handler, req *http.Request) (*httptest.ResponseRecorder, string) {
+	var seen string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = middleware.GetReqID(r.Context())
+	})
+	rec := httptest.NewRecorder()
+	h.requestID(next).ServeHTTP(rec, req)
+	return rec, seen
+}
+
+func TestRequestID_PreservesCustomHeader(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Amzn-Trace-Id", "Root=1-abc")
+
+	rec, seen := serveRequestID(New(&MockService{}, WithRequestIDHeader("X-Amzn-Trace-Id")), req)
+
+	assert.Equal(t, "Root=1-abc", seen)
+	assert.Equal(t, "Root=1-abc", rec.Header().Get("X-Amzn-Trace-Id"))
+}
+
+func TestRequestID_GeneratesWhenMissing(t *testing.T) {
+	rec, seen := serveRequestID(New(&MockService{}), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.NotEmpty(t, seen)
+	assert.Equal(t, seen, rec.Header().Get("X-Request-Id"))
+}
+
+func postDocument(h *Handler, contentType string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/documents", strings.NewReader(`{"title":"new"}`))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	rec := httptest.NewRecorder()
+	h.InitRoutes().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRequireJSON_Accepted(t *testing.T) {
+	assert.Equal(t, http.StatusCreated, postDocument(New(&MockService{}, WithJSONContentType(true, false)), "application/json").Code)
+	assert.Equal(t, http.StatusCreated, postDocument(New(&MockService{}, WithJSONContentType(true, true)), "application/json; charset=utf-8").Code)
+}
+
+func TestRequireJSON_Missing(t *testing.T) {
+	rec := postDocument(New(&MockService{}, WithJSONContentType(true, true)), "")
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+}
+
+func TestRequireJSON_Wrong(t *testing.T) {
+	assert.Equal(t, http.StatusUnsupportedMediaType, postDocument(New(&MockService{}, WithJSONContentType(true, true)), "text/plain").Code)
+	assert.Equal(t, http.StatusUnsupportedMediaType, postDocument(New(&MockService{}, WithJSONContentType(true, false)), "application/json; charset=utf-8").Code)
+}
+
+func TestRequireJSON_Disabled(t *testing.T) {
+	assert.Equal(t, http.StatusCreated, postDocument(New(&MockService{}), "text/plain").Code)
+}
+
+func TestLimitConcurrency_RejectsOverLimit(t *testing.T) {
+	const limit = 3
+	h := New(&MockService{}, WithMaxConcurrentRequests(limit))
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	limited := h.limitConcurrency(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+	}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < limit; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			limited.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, documentsPath, nil))
+		}()
+		<-entered
+	}
+
+	rec := httptest.NewRecorder()
+	limited.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, documentsPath, nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	close(release)
+	wg.Wait()
+
+	go func() { <-entered }()
+	rec = httptest.NewRecorder()
+	limited.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, documentsPath, nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestLimitConcurrency_ExemptsProbesAndStreams(t *testing.T) {
+	h := New(&MockService{}, WithMaxConcurrentRequests(1))
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	limited := h.limitConcurrency(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == documentsPath {
+			entered <- struct{}{}
+			<-release
+		}
+	}))
+	go limited.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, documentsPath, nil))
+	<-entered
+	defer close(release)
+
+	rec := httptest.NewRecorder()
+	limited.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, documentsPath+"/doc-1", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	for _, path := range []string{"/health", "/health/ready", "/version", "/metrics", documentsPath + "/stream"} {
+		rec := httptest.NewRecorder()
+		limited.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusOK, rec.Code, path)
+	}
+}
+
+func TestLimitConcurrency_RetryAfterJitter(t *testing.T) {
+	h := New(&MockService{}, WithMaxConcurrentRequests(1), WithRetryAfter(2*time.Second, 3*time.Second))
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	limited := h.limitConcurrency(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		limited.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, documentsPath, nil))
+	}()
+	<-entered
+
+	for i := 0; i < 50; i++ {
+		rec := httptest.NewRecorder()
+		limited.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, documentsPath, nil))
+		require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+		seconds, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, seconds, 2)
+		assert.LessOrEqual(t, seconds, 5)
+	}
+
+	close(release)
+	<-done
+}
+
+func TestLimitConcurrency_NoRetryAfterByDefault(t *testing.T) {
+	h := New(&MockService{}, WithMaxConcurrentRequests(1))
+
+	release := make(chan struct{})
+	entered := make(chan struct{})
+	limited := h.limitConcurrency(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+	}))
+	go limited.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, documentsPath, nil))
+	<-entered
+
+	rec := httptest.NewRecorder()
+	limited.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, documentsPath, nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Empty(t, rec.Header().Get("Retry-After"))
+	close(release)
+}
+
+func TestLimitURLLength(t *testing.T) {
+	router := New(&MockService{}, WithMaxURLLength(64)).InitRoutes()
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/documents?has_status="+strings.Repeat("a", 64), nil))
+	assert.Equal(t, http.StatusRequestURITooLong, rec.Code)
+	assert.Contains(t, rec.Body.String(), "must not exceed 64 bytes")
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/documents?page=2", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+)
+
+// Option configures optional Handler behavior.
+type Option func(*Handler)
+
+// WithDebugErrors includes panic details and stack traces in 500 responses.
+// It must stay off in production.
+func WithDebugErrors(enabled bool) Option {
+	return func(h *Handler) {
+		h.debugErrors = enabled
+	}
+}
+
+// WithMetricsHandler exposes metrics at /metrics.
+func WithMetricsHandler(metrics http.Handler) Option {
+	return func(h *Handler) {
+		h.metrics = metrics
+	}
+}
+
+// WithEnv sets the environment name reported by /version.
+func WithEnv(env string) Option {
+	return func(h *Handler) {
+		h.env = env
+	}
+}
+
+// WithBodyLogging logs request and response bodies, up to limit bytes each,
+// while the default logger is at debug level.
+func WithBodyLogging(limit int) Option {
+	return func(h *Handler) {
+		h.bodyLogLimit = limit
+	}
+}
+
+// WithCacheControl sets the max-age sent with successful document reads.
+// Zero sends no caching directive for reads.
+func WithCacheControl(maxAge time.Duration) Option {
+	return func(h *Handler) {
+		h.cacheMaxAge = maxAge
+	}
+}
+
+// WithRequestIDHeader sets the header the request ID is read from and
+// echoed in. Defaults to X-Request-Id.
+func WithRequestIDHeader(header string) Option {
+	return func(h *Handler) {
+		h.requestIDHeader = header
+	}
+}
+
+// WithJSONContentType rejects write requests that are not sent as
+// application/json when enabled. allowParams accepts suffixes such as
+// "; charset=utf-8".
+func WithJSONContentType(enabled, allowParams bool) Option {
+	return func(h *Handler) {
+		h.enforceJSON = enabled
+		h.jsonParams = allowParams
+	}
+}
+
+// WithIDFormat validates path IDs against format ("uuid" or "ulid"). An
+// empty format accepts any ID; check others with CheckIDFormat first.
+func WithIDFormat(format string) Option {
+	return func(h *Handler) {
+		h.validID = idValidator(format)
+	}
+}
+
+// WithLogSampling logs only one of every rate successful requests. Failed
+// requests and requests taking at least slow are always logged; a zero slow
+// disables that rule.
+func WithLogSampling(rate int, slow time.Duration) Option {
+	return func(h *Handler) {
+		h.logSampleRate = rate
+		h.logSlowThreshold = slow
+	}
+}
+
+// WithMaxConcurrentRequests answers 503 once limit requests are in flight. A
+// non-positive limit disables the check.
+func WithMaxConcurrentRequests(limit int) Option {
+	return func(h *Handler) {
+		h.maxInFlight = limit
+	}
+}
+
+// WithMaxURLLength answers 414 to requests whose URI, query string included,
+// is longer than limit bytes. A non-positive limit disables the check.
+func WithMaxURLLength(limit int) Option {
+	return func(h *Handler) {
+		h.maxURLLength = limit
+	}
+}
+
+// WithRetryAfter sets the Retry-After header on overload rejections to base
+// plus a random delay of up to jitter, spreading out client retries. A
+// non-positive base omits the header.
+func WithRetryAfter(base, jitter time.Duration) Option {
+	return func(h *Handler) {
+		h.retryAfter = base
+		h.retryAfterJitter = jitter
+	}
+}
+
+// WithEmptyListNoContent answers list requests whose page has no documents
+// with 204 No Content instead of 200 and an empty list.
+func WithEmptyListNoContent(enabled bool) Option {
+	return func(h *Handler) {
+		h.emptyListNoContent = enabled
+	}
+}
+
+// WithStrictPagination answers 400 to page values below 1 and per_page values
+// outside [1, model.MaxPerPage] instead of clamping them.
+func WithStrictPagination(enabled bool) Option {
+	return func(h *Handler) {
+		h.strictPagination = enabled
+	}
+}
+
+// WithSwagger controls whether API docs are served at /swagger/. They are
+// served by default.
+func WithSwagger(enabled bool) Option {
+	return func(h *Handler) {
+		h.swagger = enabled
+	}
+}
+
+// WithCompression compresses responses, preferring algorithm (gzip, deflate
+// or zstd) at level when the client accepts it and falling back to gzip,
+// deflate or identity. An empty algorithm disables compression.
+func WithCompression(algorithm string, level int) Option {
+	return func(h *Handler) {
+		h.compressor = newCompressor(algorithm, level)
+	}
+}
+
+// WithSerializer registers a response format that clients select with the
+// Accept header. JSON stays the default.
+func WithSerializer(serializer Serializer) Option {
+	return func(h *Handler) {
+		if h.serializers == nil {
+			h.serializers = make(map[string]Serializer)
+		}
+		h.serializers[serializer.MediaType()] = serializer
+	}
+}
@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/fedorovmatvey/involta-test/internal/apperror"
+	"github.com/fedorovmatvey/involta-test/internal/model"
+)
+
+const fieldsParam = "fields"
+
+// selection is a parsed fields parameter such as "id,items(id,sort)". A nil
+// entry selects the whole field, a non-nil one only its nested fields.
+type selection map[string]selection
+
+var documentType = reflect.TypeOf(model.Document{})
+
+// parseSelection reads the fields parameter and checks it against the
+// document fields. It returns nil when the parameter is absent.
+func parseSelection(query url.Values) (selection, error) {
+	value := query.Get(fieldsParam)
+	if value == "" {
+		return nil, nil
+	}
+
+	p := selectionParser{input: value}
+	sel, err := p.list()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.input) {
+		return nil, p.errorf("unexpected %q", p.input[p.pos])
+	}
+	if err := sel.validate(documentType, ""); err != nil {
+		return nil, err
+	}
+	return sel, nil
+}
+
+type selectionParser struct {
+	input string
+	pos   int
+}
+
+// list parses name[(list)] entries separated by commas.
+func (p *selectionParser) list() (selection, error) {
+	sel := selection{}
+	for {
+		start := p.pos
+		for p.pos < len(p.input) && isFieldNameByte(p.input[p.pos]) {
+			p.pos++
+		}
+		name := p.input[start:p.pos]
+		if name == "" {
+			return nil, p.errorf("expected a field name")
+		}
+		if _, ok := sel[name]; ok {
+			return nil, p.errorf("field %q is selected twice", name)
+		}
+
+		var nested selection
+		if p.next('(') {
+			var err error
+			if nested, err = p.list(); err != nil {
+				return nil, err
+			}
+			if !p.next(')') {
+				return nil, p.errorf("expected \")\"")
+			}
+		}
+		sel[name] = nested
+
+		if !p.next(',') {
+			return sel, nil
+		}
+	}
+}
+
+// next consumes c when it is the next input byte.
+func (p *selectionParser) next(c byte) bool {
+	if p.pos < len(p.input) && p.input[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *selectionParser) errorf(format string, args ...interface{}) error {
+	return apperror.InvalidParameter(fieldsParam,
+		fmt.Sprintf("invalid fields at position %d: %s", p.pos, fmt.Sprintf(format, args...)))
+}
+
+func isFieldNameByte(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+// validate reports selected fields that t does not have in its JSON form and
+// nested selections on fields without nested fields.
+func (sel selection) validate(t reflect.Type, path string) error {
+	fields := jsonFields(t)
+	for name, nested := range sel {
+		fieldType, ok := fields[name]
+		if !ok {
+			return apperror.InvalidParameter(fieldsParam, fmt.Sprintf("unknown field %q", path+name))
+		}
+		if nested == nil {
+			continue
+		}
+		for fieldType.Kind() == reflect.Pointer || fieldType.Kind() == reflect.Slice {
+			fieldType = fieldType.Elem()
+		}
+		if fieldType.Kind() != reflect.Struct || fieldType == reflect.TypeOf(time.Time{}) {
+			return apperror.InvalidParameter(fieldsParam, fmt.Sprintf("field %q has no nested fields", path+name))
+		}
+		if err := nested.validate(fieldType, path+name+"."); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jsonFields maps the JSON names of t's tagged fields to their types.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+// project encodes v and keeps only the selected fields. With under set the
+// selection applies to that field of v, such as the documents of a list.
+func (sel selection) project(v interface{}, under string) (interface{}, error) {
+	generic, err := toGeneric(v)
+	if err != nil {
+		return nil, err
+	}
+
+	if under == "" {
+		return sel.apply(generic), nil
+	}
+	if object, ok := generic.(map[string]interface{}); ok {
+		object[under] = sel.apply(object[under])
+	}
+	return generic, nil
+}
+
+// toGeneric round-trips v through JSON into maps and slices, keeping numbers
+// exact, so responses can be reshaped without knowing their types.
+func toGeneric(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode response: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var generic interface{}
+	if err := dec.Decode(&generic); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return generic, nil
+}
+
+// apply trims a decoded JSON value in place, selecting from every element of
+// arrays.
+func (sel selection) apply(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			nested, ok := sel[key]
+			if !ok {
+				delete(v, key)
+			} else if nested != nil {
+				v[key] = nested.apply(value)
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = sel.apply(v[i])
+		}
+	}
+	return v
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/fedorovmatvey/involta-test/internal/apperror"
+	"github.com/fedorovmatvey/involta-test/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSelection(t *testing.T) {
+	sel, err := parseSelection(url.Values{"fields": {"id,items(id,second_level(status)),title"}})
+
+	require.NoError(t, err)
+	assert.Equal(t, selection{
+		"id":    nil,
+		"title": nil,
+		"items": selection{
+			"id":           nil,
+			"second_level": selection{"status": nil},
+		},
+	}, sel)
+
+	sel, err = parseSelection(url.Values{})
+	assert.NoError(t, err)
+	assert.Nil(t, sel)
+}
+
+func TestParseSelection_Invalid(t *testing.T) {
+	for _, fields := range []string{
+		"id,",
+		"items(",
+		"items()",
+		"items(id))",
+		"id,id",
+		"id title",
+		"internal",
+		"Internal",
+		"items(metadata)",
+		"title(id)",
+		"created_at(id)",
+	} {
+		t.Run(fields, func(t *testing.T) {
+			_, err := parseSelection(url.Values{"fields": {fields}})
+
+			var invalid *apperror.InvalidParameterError
+			assert.ErrorAs(t, err, &invalid)
+		})
+	}
+}
+
+func newSelectionRouter() http.Handler {
+	return New(&MockService{docs: map[string]*model.Document{
+		"doc-1": {
+			ID:          "doc-1",
+			Title:       "first",
+			Description: "long description",
+			Items: []model.FirstLevelItem{{
+				ID:          "item-1",
+				Name:        "name",
+				Sort:        3,
+				SecondLevel: []model.SecondLevelItem{{ID: "sub-1", Status: "approved", Content: "content"}},
+			}},
+		},
+	}}).InitRoutes()
+}
+
+func TestHandler_GetDocument_NestedFields(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/documents/doc-1?fields=id,items(id,sort,second_level(status))", nil)
+
+	newSelectionRouter().ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{
+		"id": "doc-1",
+		"items": [{"id": "item-1", "sort": 3, "second_level": [{"status": "approved"}]}]
+	}`, rec.Body.String())
+}
+
+func TestHandler_ListDocuments_Fields(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	newTestRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/documents?page=7&fields=id,items(name)", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var body map[string]json.RawMessage
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	assert.JSONEq(t, "61", string(body["total"]))
+	assert.JSONEq(t, `[{"id": "doc-1", "items": null}]`, string(body["documents"]))
+}
+
+func TestHandler_GetDocument_InvalidFields(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	newSelectionRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/documents/doc-1?fields=items(unknown)", nil))
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, decodeResponse(t, rec)["error"], `unknown field "items.unknown"`)
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// Serializer encodes response bodies in one media type. JSON is built in;
+// other formats are registered with WithSerializer and chosen by Accept.
+type Serializer interface {
+	// MediaType is the Content-Type of the encoded body.
+	MediaType() string
+	Encode(w io.Writer, v interface{}) error
+}
+
+type jsonSerializer struct{}
+
+func (jsonSerializer) MediaType() string { return "application/json" }
+
+func (jsonSerializer) Encode(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+// serializerWriter carries the serializer negotiated for a request to
+// respondJSON.
+type serializerWriter struct {
+	http.ResponseWriter
+	serializer Serializer
+}
+
+func (w *serializerWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *serializerWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// serializerFor returns the serializer negotiated for w, defaulting to JSON.
+func serializerFor(w http.ResponseWriter) Serializer {
+	if sw, ok := w.(*serializerWriter); ok {
+		return sw.serializer
+	}
+	return jsonSerializer{}
+}
+
+// negotiate picks the response serializer from the Accept header. Media
+// ranges are tried in the order the client lists them; quality values are
+// not weighed. Requests that accept nothing registered get JSON. Responses
+// vary by Accept, so shared caches do not serve one format to another client.
+func (h *Handler) negotiate(next http.Handler) http.Handler {
+	if len(h.serializers) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept")
+		if serializer := h.acceptedSerializer(r.Header.Get("Accept")); serializer != nil {
+			w = &serializerWriter{ResponseWriter: w, serializer: serializer}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *Handler) acceptedSerializer(accept string) Serializer {
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+		if err != nil {
+			continue
+		}
+		if mediaType == "application/json" || mediaType == "*/*" {
+			return nil
+		}
+		if serializer, ok := h.serializers[mediaType]; ok {
+			return serializer
+		}
+	}
+	return nil
+}
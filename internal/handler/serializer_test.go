@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fedorovmatvey/involta-test/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// textSerializer is a stand-in for a binary format such as MessagePack.
+type textSerializer struct{}
+
+func (textSerializer) MediaType() string { return "text/plain" }
+
+func (textSerializer) Encode(w io.Writer, v interface{}) error {
+	if doc, ok := v.(*model.Document); ok {
+		_, err := fmt.Fprintf(w, "document %s", doc.ID)
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%v", v)
+	return err
+}
+
+func TestHandler_Serializer_Negotiation(t *testing.T) {
+	tests := []struct {
+		accept      string
+		contentType string
+		body        string
+	}{
+		{"", "application/json", `"id":"doc-1"`},
+		{"text/plain", "text/plain", "document doc-1"},
+		{"application/xml, text/plain;q=0.5", "text/plain", "document doc-1"},
+		{"application/json, text/plain", "application/json", `"id":"doc-1"`},
+		{"application/xml", "application/json", `"id":"doc-1"`},
+	}
+
+	router := New(&MockService{docs: map[string]*model.Document{"doc-1": {ID: "doc-1"}}},
+		WithSerializer(textSerializer{})).InitRoutes()
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/documents/doc-1", nil)
+			req.Header.Set("Accept", tt.accept)
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tt.contentType, rec.Header().Get("Content-Type"))
+			assert.Contains(t, rec.Header().Values("Vary"), "Accept")
+			assert.Contains(t, rec.Body.String(), tt.body)
+		})
+	}
+}
@@ -0,0 +1,80 @@
+// Package httpparam reads typed query string parameters. Every getter returns
+// its default when the parameter is absent and an
+// *apperror.InvalidParameterError when it is present but malformed, so
+// handlers can answer with a uniform 400.
+package httpparam
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fedorovmatvey/involta-test/internal/apperror"
+)
+
+// Int parses key as a base-10 integer.
+func Int(query url.Values, key string, defaultValue int) (int, error) {
+	value := query.Get(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	intValue, err := strconv.Atoi(value)
+	if err != nil {
+		return defaultValue, apperror.InvalidParameter(key, key+" must be an integer")
+	}
+	return intValue, nil
+}
+
+// IntRange parses key as an integer in [minValue, maxValue].
+func IntRange(query url.Values, key string, defaultValue, minValue, maxValue int) (int, error) {
+	intValue, err := Int(query, key, defaultValue)
+	if err != nil {
+		return defaultValue, err
+	}
+	if intValue < minValue || intValue > maxValue {
+		return defaultValue, apperror.InvalidParameter(key, fmt.Sprintf("%s must be between %d and %d", key, minValue, maxValue))
+	}
+	return intValue, nil
+}
+
+// Bool parses key with strconv.ParseBool.
+func Bool(query url.Values, key string, defaultValue bool) (bool, error) {
+	value := query.Get(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	boolValue, err := strconv.ParseBool(value)
+	if err != nil {
+		return defaultValue, apperror.InvalidParameter(key, key+" must be a boolean")
+	}
+	return boolValue, nil
+}
+
+// Time parses key as an RFC3339 timestamp. An absent parameter yields the
+// zero time.
+func Time(query url.Values, key string) (time.Time, error) {
+	value := query.Get(key)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	timeValue, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, apperror.InvalidParameter(key, key+" must be an RFC3339 timestamp")
+	}
+	return timeValue, nil
+}
+
+// Enum returns key if it is one of allowed.
+func Enum(query url.Values, key, defaultValue string, allowed ...string) (string, error) {
+	value := query.Get(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	if !slices.Contains(allowed, value) {
+		return defaultValue, apperror.InvalidParameter(key, fmt.Sprintf("%s must be one of %s", key, strings.Join(allowed, ", ")))
+	}
+	return value, nil
+}
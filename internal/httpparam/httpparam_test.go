@@ -0,0 +1,99 @@
+package httpparam
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/fedorovmatvey/involta-test/internal/apperror"
+	"github.com/stretchr/testify/assert"
+)
+
+func assertInvalid(t *testing.T, err error, key string) {
+	t.Helper()
+	var invalid *apperror.InvalidParameterError
+	if assert.ErrorAs(t, err, &invalid) {
+		assert.Equal(t, key, invalid.Parameter)
+	}
+}
+
+func TestInt(t *testing.T) {
+	got, err := Int(url.Values{}, "page", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, got)
+
+	got, err = Int(url.Values{"page": {"-3"}}, "page", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, -3, got)
+
+	got, err = Int(url.Values{"page": {"two"}}, "page", 1)
+	assertInvalid(t, err, "page")
+	assert.Equal(t, 1, got)
+}
+
+func TestIntRange(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int
+		valid bool
+	}{
+		{"", 2, true},
+		{"1", 1, true},
+		{"2", 2, true},
+		{"0", 2, false},
+		{"3", 2, false},
+		{"1.5", 2, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := IntRange(url.Values{"depth": {tt.value}}, "depth", 2, 1, 2)
+
+			assert.Equal(t, tt.want, got)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assertInvalid(t, err, "depth")
+			}
+		})
+	}
+}
+
+func TestBool(t *testing.T) {
+	got, err := Bool(url.Values{}, "fresh", false)
+	assert.NoError(t, err)
+	assert.False(t, got)
+
+	got, err = Bool(url.Values{"fresh": {"1"}}, "fresh", false)
+	assert.NoError(t, err)
+	assert.True(t, got)
+
+	_, err = Bool(url.Values{"fresh": {"yes"}}, "fresh", false)
+	assertInvalid(t, err, "fresh")
+}
+
+func TestTime(t *testing.T) {
+	got, err := Time(url.Values{}, "updated_since")
+	assert.NoError(t, err)
+	assert.True(t, got.IsZero())
+
+	got, err = Time(url.Values{"updated_since": {"2024-05-01T12:00:00Z"}}, "updated_since")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), got)
+
+	_, err = Time(url.Values{"updated_since": {"2024-05-01"}}, "updated_since")
+	assertInvalid(t, err, "updated_since")
+}
+
+func TestEnum(t *testing.T) {
+	got, err := Enum(url.Values{}, "format", "json", "json", "ndjson")
+	assert.NoError(t, err)
+	assert.Equal(t, "json", got)
+
+	got, err = Enum(url.Values{"format": {"ndjson"}}, "format", "json", "json", "ndjson")
+	assert.NoError(t, err)
+	assert.Equal(t, "ndjson", got)
+
+	_, err = Enum(url.Values{"format": {"xml"}}, "format", "json", "json", "ndjson")
+	assertInvalid(t, err, "format")
+}
@@ -1,15 +1,54 @@
 package model
 
-import "time"
+import (
+	"encoding/json"
+	"math"
+	"time"
+)
 
 type Document struct {
 	ID          string           `json:"id" reindex:"id,,pk"`
-	Title       string           `json:"title" reindex:"title"`
+	Title       string           `json:"title" reindex:"title,tree,collate_utf8"`
 	Description string           `json:"description" reindex:"description"`
 	CreatedAt   time.Time        `json:"created_at" reindex:"created_at"`
 	UpdatedAt   time.Time        `json:"updated_at" reindex:"updated_at"`
 	Items       []FirstLevelItem `json:"items" reindex:"items"`
-	Internal    string           `reindex:"internal"`
+	// UpdatedAtNano mirrors UpdatedAt as Unix nanoseconds for updated_since
+	// filtering. Reindexer stores times as RFC3339Nano strings, which do not
+	// compare across offsets or when trailing zeros are trimmed. Storage sets
+	// it on every write and MarshalJSON drops it.
+	UpdatedAtNano int64 `reindex:"updated_at_nano,tree"`
+	// Tags are trimmed and deduplicated on write.
+	Tags []string `json:"tags,omitempty" reindex:"tags"`
+	// Internal is private data. Reindexer rejects json:"-" on indexed
+	// fields, so MarshalJSON drops it from API responses instead.
+	Internal string `reindex:"internal"`
+	// ItemCount is computed when the document is served and never set on
+	// documents being written, so omitempty keeps it out of storage.
+	ItemCount int `json:"item_count,omitempty"`
+	// Stale marks a document served from an expired cache entry because
+	// storage failed. It is set on served copies only and never stored.
+	Stale bool `json:"-"`
+	// Meta tells clients how the document was served. Like Stale it is set
+	// on served copies only.
+	Meta *ServeMeta `json:"meta,omitempty"`
+}
+
+// ServeMeta describes how a document was served. Stale mirrors the
+// X-Cache: STALE header for clients that do not read headers.
+type ServeMeta struct {
+	Stale bool `json:"stale"`
+}
+
+// MarshalJSON encodes the document without Internal and UpdatedAtNano.
+func (d Document) MarshalJSON() ([]byte, error) {
+	type document Document
+	return json.Marshal(struct {
+		document
+		// These shadow the embedded fields; nil is always omitted.
+		Internal      *struct{} `json:"Internal,omitempty"`
+		UpdatedAtNano *struct{} `json:"UpdatedAtNano,omitempty"`
+	}{document: document(d)})
 }
 
 type FirstLevelItem struct {
@@ -19,6 +58,9 @@ type FirstLevelItem struct {
 	Value       string            `json:"value"`
 	SecondLevel []SecondLevelItem `json:"second_level"`
 	MetaData    string            `json:"-"`
+	// ValueTruncated marks a served Value cut to the configured limit. It
+	// is set on served copies only and cleared on write.
+	ValueTruncated bool `json:"value_truncated,omitempty"`
 }
 
 type SecondLevelItem struct {
@@ -27,6 +69,24 @@ type SecondLevelItem struct {
 	Content     string `json:"content"`
 	Status      string `json:"status"`
 	PrivateInfo string `json:"-"`
+	// ContentTruncated marks a served Content cut to the configured limit.
+	ContentTruncated bool `json:"content_truncated,omitempty"`
+}
+
+// ItemList is a page of one document's first-level items.
+type ItemList struct {
+	Items      []FirstLevelItem `json:"items"`
+	Total      int              `json:"total"`
+	Page       int              `json:"page"`
+	PerPage    int              `json:"per_page"`
+	TotalPages int              `json:"total_pages"`
+}
+
+// DocumentMeta is the subset of a document needed for existence checks and
+// conditional request headers.
+type DocumentMeta struct {
+	ID        string
+	UpdatedAt time.Time
 }
 
 type DocumentList struct {
@@ -35,25 +95,182 @@ type DocumentList struct {
 	Page       int        `json:"page"`
 	PerPage    int        `json:"per_page"`
 	TotalPages int        `json:"total_pages"`
+	HasNext    bool       `json:"has_next"`
+	HasPrev    bool       `json:"has_prev"`
+	// Timings is only reported when the service is configured to expose it.
+	Timings *ListTimings `json:"timings,omitempty"`
+}
+
+// ListTimings splits the time spent building a DocumentList between the
+// storage query and document processing, in milliseconds.
+type ListTimings struct {
+	StorageMs float64 `json:"storage_ms"`
+	ProcessMs float64 `json:"process_ms"`
+}
+
+const (
+	HealthOK          = "ok"
+	HealthDown        = "down"
+	HealthReady       = "ready"
+	HealthUnavailable = "unavailable"
+)
+
+// HealthStatus is the readiness report: an overall status plus the status of
+// each dependency. Only storage is critical.
+type HealthStatus struct {
+	Status  string      `json:"status"`
+	Storage string      `json:"storage"`
+	Cache   CacheHealth `json:"cache"`
+}
+
+type CacheHealth struct {
+	Status string `json:"status"`
+	// Size is reported by caches that can count their entries.
+	Size *int `json:"size,omitempty"`
 }
 
 type CreateDocumentRequest struct {
 	Title       string           `json:"title"`
 	Description string           `json:"description"`
 	Items       []FirstLevelItem `json:"items"`
+	Tags        []string         `json:"tags"`
 }
 
 type UpdateDocumentRequest struct {
 	Title       *string           `json:"title,omitempty"`
 	Description *string           `json:"description,omitempty"`
 	Items       *[]FirstLevelItem `json:"items,omitempty"`
+	Tags        *[]string         `json:"tags,omitempty"`
+}
+
+type CloneDocumentRequest struct {
+	Title *string `json:"title,omitempty"`
+}
+
+type BatchCreateRequest struct {
+	Documents []CreateDocumentRequest `json:"documents"`
+	Atomic    bool                    `json:"atomic"`
+}
+
+// ImportDocument is a document migrated from another system. Timestamps that
+// are set are kept instead of being replaced with the time of the import.
+type ImportDocument struct {
+	CreateDocumentRequest
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+type ImportRequest struct {
+	Documents []ImportDocument `json:"documents"`
+	Atomic    bool             `json:"atomic"`
+}
+
+type BatchUpdateItem struct {
+	ID string `json:"id"`
+	UpdateDocumentRequest
+}
+
+type BatchUpdateRequest struct {
+	Documents []BatchUpdateItem `json:"documents"`
+	Atomic    bool              `json:"atomic"`
+}
+
+// UpdateWhereRequest sets fields on every document whose fields equal the
+// values in Filter.
+type UpdateWhereRequest struct {
+	Filter map[string]string `json:"filter"`
+	Set    map[string]string `json:"set"`
+}
+
+type UpdateWhereResult struct {
+	Updated int `json:"updated"`
+}
+
+type BatchDeleteRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// BatchDeleteResult counts the deleted documents and lists the requested IDs
+// that did not exist.
+type BatchDeleteResult struct {
+	Deleted int      `json:"deleted"`
+	Missing []string `json:"missing"`
+}
+
+// DocumentDiff lists the fields an update would add, remove or change.
+// Paths address items by ID, e.g. items[a1].second_level[b2].status.
+type DocumentDiff struct {
+	ID      string        `json:"id"`
+	Added   []FieldChange `json:"added"`
+	Removed []FieldChange `json:"removed"`
+	Changed []FieldChange `json:"changed"`
+}
+
+// FieldChange is one difference. Old is unset for added fields and New for
+// removed ones.
+type FieldChange struct {
+	Path string      `json:"path"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
 }
 
+type PreloadRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// PreloadResult counts the requested documents that were cached and the ones
+// that do not exist.
+type PreloadResult struct {
+	Loaded  int `json:"loaded"`
+	Missing int `json:"missing"`
+}
+
+// QueryExplain is a storage query plan with timings in microseconds.
+type QueryExplain struct {
+	TotalUs       int             `json:"total_us"`
+	PreselectUs   int             `json:"preselect_us"`
+	PrepareUs     int             `json:"prepare_us"`
+	IndexesUs     int             `json:"indexes_us"`
+	PostprocessUs int             `json:"postprocess_us"`
+	LoopUs        int             `json:"loop_us"`
+	GeneralSortUs int             `json:"general_sort_us"`
+	SortIndex     string          `json:"sort_index"`
+	Selectors     []QuerySelector `json:"selectors"`
+}
+
+// QuerySelector describes how one query condition was evaluated.
+type QuerySelector struct {
+	Field     string  `json:"field"`
+	Method    string  `json:"method"`
+	Condition string  `json:"condition"`
+	Keys      int     `json:"keys"`
+	Matched   int     `json:"matched"`
+	Items     int     `json:"items"`
+	Cost      float64 `json:"cost"`
+}
+
+// SortFields are the API field names a list can be sorted by. The handler
+// builds the sort parameter's values from it and storage accepts only these.
+var SortFields = []string{"created_at", "updated_at", "title"}
+
 type PaginationParams struct {
 	Page    int `json:"page"`
 	PerPage int `json:"per_page"`
+	// UpdatedSince, when set, limits the list to documents changed after it.
+	UpdatedSince time.Time `json:"updated_since"`
+	// SortBy is an API field name; empty means the default order.
+	SortBy   string `json:"sort_by"`
+	SortDesc bool   `json:"sort_desc"`
+	// HasStatus, when set, limits the list to documents with at least one
+	// second-level item in that status.
+	HasStatus string `json:"has_status"`
+	// Tag, when set, limits the list to documents with that tag.
+	Tag string `json:"tag"`
 }
 
+// MaxPerPage is the largest page size a list request can get.
+const MaxPerPage = 100
+
 func (p *PaginationParams) Validate() {
 	if p.Page < 1 {
 		p.Page = 1
@@ -61,11 +278,21 @@ func (p *PaginationParams) Validate() {
 	if p.PerPage < 1 {
 		p.PerPage = 10
 	}
-	if p.PerPage > 100 {
-		p.PerPage = 100
+	if p.PerPage > MaxPerPage {
+		p.PerPage = MaxPerPage
 	}
 }
 
+// GetOffset returns the number of documents to skip, clamped to the int32
+// range so that huge page numbers cannot overflow the query offset.
 func (p *PaginationParams) GetOffset() int {
-	return (p.Page - 1) * p.PerPage
+	if p.Page < 1 || p.PerPage < 1 {
+		return 0
+	}
+
+	offset := int64(p.Page-1) * int64(p.PerPage)
+	if offset > math.MaxInt32 || offset < 0 {
+		return math.MaxInt32
+	}
+	return int(offset)
 }
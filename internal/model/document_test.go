@@ -0,0 +1,30 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocument_MarshalJSON_OmitsPrivateFields(t *testing.T) {
+	doc := Document{
+		ID:       "doc-1",
+		Title:    "public",
+		Internal: "secret-internal",
+		Items: []FirstLevelItem{{
+			ID:          "item-1",
+			MetaData:    "secret-meta",
+			SecondLevel: []SecondLevelItem{{ID: "sub-1", PrivateInfo: "secret-private"}},
+		}},
+	}
+
+	for _, value := range []interface{}{doc, &doc, DocumentList{Documents: []Document{doc}}} {
+		data, err := json.Marshal(value)
+
+		assert.NoError(t, err)
+		assert.Contains(t, string(data), `"title":"public"`)
+		assert.NotContains(t, string(data), "Internal")
+		assert.NotContains(t, string(data), "secret")
+	}
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/fedorovmatvey/involta-test/internal/model"
+	"github.com/fedorovmatvey/involta-test/internal/storage"
+)
+
+// Diff reports what applying req to the stored document would change,
+// without writing anything.
+func (s *Service) Diff(ctx context.Context, id string, req model.UpdateDocumentRequest) (*model.DocumentDiff, error) {
+	if err := s.fields.applyUpdate(&req); err != nil {
+		return nil, err
+	}
+
+	// Read the primary, which is what Update would merge into.
+	current, err := s.storage.GetByID(storage.ReadPrimary(ctx), id)
+	if err != nil {
+		return nil, fmt.Errorf("document not found: %w", err)
+	}
+
+	proposed := *current
+	applyUpdate(&proposed, req)
+	if err := s.validateDocument(&proposed); err != nil {
+		return nil, err
+	}
+
+	return diffDocuments(current, &proposed), nil
+}
+
+func diffDocuments(from, to *model.Document) *model.DocumentDiff {
+	d := &model.DocumentDiff{
+		ID:      from.ID,
+		Added:   []model.FieldChange{},
+		Removed: []model.FieldChange{},
+		Changed: []model.FieldChange{},
+	}
+
+	compare(d, "title", from.Title, to.Title)
+	compare(d, "description", from.Description, to.Description)
+	if !slices.Equal(from.Tags, to.Tags) {
+		d.Changed = append(d.Changed, model.FieldChange{Path: "tags", Old: from.Tags, New: to.Tags})
+	}
+	diffItems(d, from.Items, to.Items)
+	return d
+}
+
+func diffItems(d *model.DocumentDiff, from, to []model.FirstLevelItem) {
+	old := make(map[string]model.FirstLevelItem, len(from))
+	for i, item := range from {
+		old[itemKey(item.ID, i)] = item
+	}
+
+	seen := make(map[string]bool, len(to))
+	for i, item := range to {
+		key := itemKey(item.ID, i)
+		path := "items[" + key + "]"
+		seen[key] = true
+
+		prev, ok := old[key]
+		if !ok {
+			d.Added = append(d.Added, model.FieldChange{Path: path, New: item})
+			continue
+		}
+
+		compare(d, path+".name", prev.Name, item.Name)
+		compare(d, path+".sort", prev.Sort, item.Sort)
+		compare(d, path+".value", prev.Value, item.Value)
+		diffSecondLevel(d, path+".second_level", prev.SecondLevel, item.SecondLevel)
+	}
+
+	for i, item := range from {
+		if key := itemKey(item.ID, i); !seen[key] {
+			d.Removed = append(d.Removed, model.FieldChange{Path: "items[" + key + "]", Old: item})
+		}
+	}
+}
+
+func diffSecondLevel(d *model.DocumentDiff, prefix string, from, to []model.SecondLevelItem) {
+	old := make(map[string]model.SecondLevelItem, len(from))
+	for i, item := range from {
+		old[itemKey(item.ID, i)] = item
+	}
+
+	seen := make(map[string]bool, len(to))
+	for i, item := range to {
+		key := itemKey(item.ID, i)
+		path := prefix + "[" + key + "]"
+		seen[key] = true
+
+		prev, ok := old[key]
+		if !ok {
+			d.Added = append(d.Added, model.FieldChange{Path: path, New: item})
+			continue
+		}
+
+		compare(d, path+".type", prev.Type, item.Type)
+		compare(d, path+".content", prev.Content, item.Content)
+		compare(d, path+".status", prev.Status, item.Status)
+	}
+
+	for i, item := range from {
+		if key := itemKey(item.ID, i); !seen[key] {
+			d.Removed = append(d.Removed, model.FieldChange{Path: prefix + "[" + key + "]", Old: item})
+		}
+	}
+}
+
+// itemKey matches items by ID, falling back to their position when the ID is
+// empty.
+func itemKey(id string, index int) string {
+	if id != "" {
+		return id
+	}
+	return "#" + strconv.Itoa(index)
+}
+
+// compare records a change when the two values differ.
+func compare[T comparable](d *model.DocumentDiff, path string, from, to T) {
+	if from != to {
+		d.Changed = append(d.Changed, model.FieldChange{Path: path, Old: from, New: to})
+	}
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fedorovmatvey/involta-test/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func newDiffService() (*Service, *MemoryStorage) {
+	store := NewMemoryStorage("")
+	store.docs["doc-1"] = &model.Document{
+		ID:    "doc-1",
+		Title: "old",
+		Items: []model.FirstLevelItem{
+			{ID: "item-1", Name: "first", SecondLevel: []model.SecondLevelItem{{ID: "sub-1", Status: "draft"}}},
+		},
+	}
+	return New(store, &MockCache{}), store
+}
+
+func TestService_Diff_ChangedTitle(t *testing.T) {
+	srv, store := newDiffService()
+
+	title := "new"
+	diff, err := srv.Diff(context.Background(), "doc-1", model.UpdateDocumentRequest{Title: &title})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []model.FieldChange{{Path: "title", Old: "old", New: "new"}}, diff.Changed)
+	assert.Empty(t, diff.Added)
+	assert.Empty(t, diff.Removed)
+	assert.Equal(t, "old", store.docs["doc-1"].Title)
+}
+
+func TestService_Diff_AddedItem(t *testing.T) {
+	srv, _ := newDiffService()
+
+	added := model.FirstLevelItem{ID: "item-2", Name: "second"}
+	items := []model.FirstLevelItem{
+		{ID: "item-1", Name: "first", SecondLevel: []model.SecondLevelItem{{ID: "sub-1", Status: "done"}}},
+		added,
+	}
+	diff, err := srv.Diff(context.Background(), "doc-1", model.UpdateDocumentRequest{Items: &items})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []model.FieldChange{{Path: "items[item-2]", New: added}}, diff.Added)
+	assert.Equal(t, []model.FieldChange{
+		{Path: "items[item-1].second_level[sub-1].status", Old: "draft", New: "done"},
+	}, diff.Changed)
+	assert.Empty(t, diff.Removed)
+}
+
+func TestService_Diff_RemovedItem(t *testing.T) {
+	srv, _ := newDiffService()
+
+	items := []model.FirstLevelItem{}
+	diff, err := srv.Diff(context.Background(), "doc-1", model.UpdateDocumentRequest{Items: &items})
+
+	assert.NoError(t, err)
+	assert.Len(t, diff.Removed, 1)
+	assert.Equal(t, "items[item-1]", diff.Removed[0].Path)
+	assert.Empty(t, diff.Added)
+	assert.Empty(t, diff.Changed)
+}
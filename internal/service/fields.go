@@ -0,0 +1,125 @@
+package service
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/fedorovmatvey/involta-test/internal/apperror"
+	"github.com/fedorovmatvey/involta-test/internal/model"
+)
+
+// Document fields a FieldPolicy can restrict.
+const (
+	FieldTitle       = "title"
+	FieldDescription = "description"
+	FieldItems       = "items"
+	FieldTags        = "tags"
+)
+
+var policyFields = []string{FieldTitle, FieldDescription, FieldItems, FieldTags}
+
+// FieldPolicy restricts which document fields clients may set on create and
+// update. A field is disallowed when Allowed is non-empty and does not list
+// it, or when Denied lists it. The zero value allows every field.
+type FieldPolicy struct {
+	Allowed []string
+	Denied  []string
+	// Reject fails requests that set a disallowed field with a validation
+	// error instead of silently dropping the field.
+	Reject bool
+}
+
+// Validate reports field names the policy does not know.
+func (p FieldPolicy) Validate() error {
+	for _, field := range slices.Concat(p.Allowed, p.Denied) {
+		if !slices.Contains(policyFields, field) {
+			return fmt.Errorf("unknown document field %q in field policy", field)
+		}
+	}
+	return nil
+}
+
+func (p FieldPolicy) allows(field string) bool {
+	if len(p.Allowed) > 0 && !slices.Contains(p.Allowed, field) {
+		return false
+	}
+	return !slices.Contains(p.Denied, field)
+}
+
+// check returns the validation error for a disallowed field that is set, or
+// reports whether the field should be stripped.
+func (p FieldPolicy) check(field string, set bool) (strip bool, err error) {
+	if !set || p.allows(field) {
+		return false, nil
+	}
+	if p.Reject {
+		return false, apperror.Validation(field, 0, "must not be set")
+	}
+	return true, nil
+}
+
+// applyCreate strips or rejects disallowed fields of a create request.
+func (p FieldPolicy) applyCreate(req *model.CreateDocumentRequest) error {
+	if strip, err := p.check(FieldTitle, req.Title != ""); err != nil {
+		return err
+	} else if strip {
+		req.Title = ""
+	}
+	if strip, err := p.check(FieldDescription, req.Description != ""); err != nil {
+		return err
+	} else if strip {
+		req.Description = ""
+	}
+	if strip, err := p.check(FieldItems, len(req.Items) > 0); err != nil {
+		return err
+	} else if strip {
+		req.Items = nil
+	}
+	if strip, err := p.check(FieldTags, len(req.Tags) > 0); err != nil {
+		return err
+	} else if strip {
+		req.Tags = nil
+	}
+	return nil
+}
+
+// applyUpdate strips or rejects disallowed fields of an update request.
+func (p FieldPolicy) applyUpdate(req *model.UpdateDocumentRequest) error {
+	if strip, err := p.check(FieldTitle, req.Title != nil); err != nil {
+		return err
+	} else if strip {
+		req.Title = nil
+	}
+	if strip, err := p.check(FieldDescription, req.Description != nil); err != nil {
+		return err
+	} else if strip {
+		req.Description = nil
+	}
+	if strip, err := p.check(FieldItems, req.Items != nil); err != nil {
+		return err
+	} else if strip {
+		req.Items = nil
+	}
+	if strip, err := p.check(FieldTags, req.Tags != nil); err != nil {
+		return err
+	} else if strip {
+		req.Tags = nil
+	}
+	return nil
+}
+
+// applySet strips or rejects disallowed fields of a bulk update's changes. It
+// returns a copy rather than editing set, which belongs to the caller.
+func (p FieldPolicy) applySet(set map[string]string) (map[string]string, error) {
+	allowed := make(map[string]string, len(set))
+	for _, field := range slices.Sorted(maps.Keys(set)) {
+		if strip, err := p.check(field, true); err != nil {
+			return nil, err
+		} else if strip {
+			continue
+		}
+		allowed[field] = set[field]
+	}
+	return allowed, nil
+}
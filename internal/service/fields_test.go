@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fedorovmatvey/involta-test/internal/apperror"
+	"github.com/fedorovmatvey/involta-test/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestService_Create_FieldPolicyStrips(t *testing.T) {
+	store := NewMemoryStorage("broken")
+	srv := New(store, &MockCache{}, WithFieldPolicy(FieldPolicy{Denied: []string{FieldDescription}}))
+
+	doc, err := srv.Create(context.Background(), model.CreateDocumentRequest{
+		Title:       "doc",
+		Description: "forbidden",
+		Items:       []model.FirstLevelItem{{ID: "item-1"}},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "doc", doc.Title)
+	assert.Empty(t, doc.Description)
+	assert.Len(t, doc.Items, 1)
+	assert.Empty(t, store.docs[doc.ID].Description)
+}
+
+func TestService_FieldPolicyRejects(t *testing.T) {
+	store := NewMemoryStorage("broken")
+	store.docs["doc-1"] = &model.Document{ID: "doc-1", Title: "doc"}
+	srv := New(store, &MockCache{}, WithFieldPolicy(FieldPolicy{
+		Allowed: []string{FieldTitle, FieldDescription},
+		Reject:  true,
+	}))
+
+	_, err := srv.Create(context.Background(), model.CreateDocumentRequest{
+		Title: "doc",
+		Items: []model.FirstLevelItem{{ID: "item-1"}},
+	})
+	var validation *apperror.ValidationError
+	assert.ErrorAs(t, err, &validation)
+	assert.Equal(t, FieldItems, validation.Field)
+	assert.Len(t, store.docs, 1)
+
+	items := []model.FirstLevelItem{}
+	_, err = srv.Update(context.Background(), "doc-1", model.UpdateDocumentRequest{Items: &items})
+	assert.ErrorAs(t, err, &validation)
+
+	title := "renamed"
+	doc, err := srv.Update(context.Background(), "doc-1", model.UpdateDocumentRequest{Title: &title})
+	assert.NoError(t, err)
+	assert.Equal(t, "renamed", doc.Title)
+}
+
+func TestService_UpdateWhere_FieldPolicy(t *testing.T) {
+	store := NewMemoryStorage("")
+	store.docs["doc-1"] = &model.Document{ID: "doc-1", Title: "draft"}
+	req := model.UpdateWhereRequest{
+		Filter: map[string]string{"title": "draft"},
+		Set:    map[string]string{"title": "final", "description": "forbidden"},
+	}
+
+	rejecting := New(store, &MockCache{}, WithFieldPolicy(FieldPolicy{Denied: []string{FieldDescription}, Reject: true}))
+	_, err := rejecting.UpdateWhere(context.Background(), req)
+	var validation *apperror.ValidationError
+	if assert.ErrorAs(t, err, &validation) {
+		assert.Equal(t, FieldDescription, validation.Field)
+	}
+	assert.Equal(t, "draft", store.docs["doc-1"].Title)
+
+	stripping := New(store, &MockCache{}, WithFieldPolicy(FieldPolicy{Denied: []string{FieldDescription}}))
+	result, err := stripping.UpdateWhere(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Updated)
+	assert.Equal(t, "final", store.docs["doc-1"].Title)
+	assert.Empty(t, store.docs["doc-1"].Description)
+	assert.Len(t, req.Set, 2, "the caller's set must not be modified")
+}
+
+func TestService_Clone_FieldPolicy(t *testing.T) {
+	store := NewMemoryStorage("")
+	store.docs["doc-1"] = &model.Document{ID: "doc-1", Title: "source"}
+	title := "renamed"
+	req := model.CloneDocumentRequest{Title: &title}
+
+	rejecting := New(store, &MockCache{}, WithFieldPolicy(FieldPolicy{Denied: []string{FieldTitle}, Reject: true}))
+	_, err := rejecting.Clone(context.Background(), "doc-1", req)
+	var validation *apperror.ValidationError
+	if assert.ErrorAs(t, err, &validation) {
+		assert.Equal(t, FieldTitle, validation.Field)
+	}
+
+	stripping := New(store, &MockCache{}, WithFieldPolicy(FieldPolicy{Denied: []string{FieldTitle}}))
+	clone, err := stripping.Clone(context.Background(), "doc-1", req)
+	assert.NoError(t, err)
+	assert.Equal(t, "source", clone.Title)
+}
+
+func TestFieldPolicy_Validate(t *testing.T) {
+	assert.NoError(t, FieldPolicy{Allowed: []string{FieldTitle}, Denied: []string{FieldItems}}.Validate())
+	assert.Error(t, FieldPolicy{Denied: []string{"created_at"}}.Validate())
+}
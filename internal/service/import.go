@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fedorovmatvey/involta-test/internal/apperror"
+	"github.com/fedorovmatvey/involta-test/internal/model"
+	"github.com/fedorovmatvey/involta-test/internal/storage"
+)
+
+// Import creates documents migrated from another system. Unlike CreateBatch
+// it keeps the created_at and updated_at the client sends; a document without
+// updated_at gets its created_at, and one without either is stamped with the
+// import time. With req.Atomic set the writes run in a single transaction.
+func (s *Service) Import(ctx context.Context, req model.ImportRequest) ([]*model.Document, error) {
+	if err := checkCount("documents", len(req.Documents), s.limits.MaxBatchSize); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	docs := make([]*model.Document, 0, len(req.Documents))
+	for i, r := range req.Documents {
+		if err := s.fields.applyCreate(&r.CreateDocumentRequest); err != nil {
+			return nil, err
+		}
+		doc := newDocument(r.CreateDocumentRequest)
+		if err := importTimestamps(doc, r, now, fmt.Sprintf("documents[%d]", i)); err != nil {
+			return nil, err
+		}
+		if err := s.validateDocument(doc); err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+
+	write := func(store storage.TxStore, doc *model.Document) error {
+		if err := store.Create(ctx, doc); err != nil {
+			return fmt.Errorf("failed to import document: %w", err)
+		}
+		return nil
+	}
+
+	if err := s.runBatch(ctx, req.Atomic, docs, write); err != nil {
+		return nil, err
+	}
+
+	return docs, nil
+}
+
+// importTimestamps applies the client timestamps of r to doc. They must not
+// be zero or in the future, and updated_at must not precede created_at. A
+// missing one defaults to the other, so a document with only updated_at is
+// taken to have been created then.
+func importTimestamps(doc *model.Document, r model.ImportDocument, now time.Time, field string) error {
+	if r.CreatedAt != nil {
+		if err := checkImportTime(field+".created_at", *r.CreatedAt, now); err != nil {
+			return err
+		}
+		doc.CreatedAt = *r.CreatedAt
+		doc.UpdatedAt = *r.CreatedAt
+	}
+	if r.UpdatedAt != nil {
+		if err := checkImportTime(field+".updated_at", *r.UpdatedAt, now); err != nil {
+			return err
+		}
+		doc.UpdatedAt = *r.UpdatedAt
+		if r.CreatedAt == nil {
+			doc.CreatedAt = *r.UpdatedAt
+		}
+	}
+
+	if doc.UpdatedAt.Before(doc.CreatedAt) {
+		return apperror.Validation(field+".updated_at", 0, "must not be before created_at")
+	}
+	return nil
+}
+
+func checkImportTime(field string, t, now time.Time) error {
+	if t.IsZero() {
+		return apperror.Validation(field, 0, "must be set")
+	}
+	if t.After(now) {
+		return apperror.Validation(field, 0, "must not be in the future")
+	}
+	return nil
+}
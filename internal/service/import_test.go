@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fedorovmatvey/involta-test/internal/apperror"
+	"github.com/fedorovmatvey/involta-test/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_Import_KeepsTimestamps(t *testing.T) {
+	store := NewMemoryStorage("broken")
+	srv := New(store, &MockCache{})
+	created := time.Date(2019, 3, 1, 10, 0, 0, 0, time.UTC)
+	updated := time.Date(2021, 7, 15, 8, 30, 0, 0, time.UTC)
+
+	before := time.Now()
+	docs, err := srv.Import(context.Background(), model.ImportRequest{Documents: []model.ImportDocument{
+		{CreateDocumentRequest: model.CreateDocumentRequest{Title: "both"}, CreatedAt: &created, UpdatedAt: &updated},
+		{CreateDocumentRequest: model.CreateDocumentRequest{Title: "created only"}, CreatedAt: &created},
+		{CreateDocumentRequest: model.CreateDocumentRequest{Title: "none"}},
+		{CreateDocumentRequest: model.CreateDocumentRequest{Title: "updated only"}, UpdatedAt: &updated},
+	}})
+	require.NoError(t, err)
+	require.Len(t, docs, 4)
+
+	assert.Equal(t, created, store.docs[docs[0].ID].CreatedAt)
+	assert.Equal(t, updated, store.docs[docs[0].ID].UpdatedAt)
+	assert.Equal(t, created, docs[1].CreatedAt)
+	assert.Equal(t, created, docs[1].UpdatedAt)
+	assert.False(t, docs[2].CreatedAt.Before(before))
+	assert.Equal(t, updated, docs[3].CreatedAt)
+	assert.Equal(t, updated, docs[3].UpdatedAt)
+}
+
+func TestService_Import_InvalidTimestamps(t *testing.T) {
+	past := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	earlier := past.Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	zero := time.Time{}
+
+	tests := []struct {
+		name  string
+		doc   model.ImportDocument
+		field string
+	}{
+		{"future created_at", model.ImportDocument{CreatedAt: &future}, "documents[0].created_at"},
+		{"zero updated_at", model.ImportDocument{UpdatedAt: &zero}, "documents[0].updated_at"},
+		{"updated before created", model.ImportDocument{CreatedAt: &past, UpdatedAt: &earlier}, "documents[0].updated_at"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStorage("broken")
+			tt.doc.Title = "doc"
+
+			_, err := New(store, &MockCache{}).Import(context.Background(), model.ImportRequest{Documents: []model.ImportDocument{tt.doc}})
+
+			var validation *apperror.ValidationError
+			require.ErrorAs(t, err, &validation)
+			assert.Equal(t, tt.field, validation.Field)
+			assert.Empty(t, store.docs)
+		})
+	}
+}
+
+func TestService_Create_SetsTimestamps(t *testing.T) {
+	before := time.Now()
+
+	doc, err := New(NewMemoryStorage("broken"), &MockCache{}).Create(context.Background(), model.CreateDocumentRequest{Title: "doc"})
+
+	require.NoError(t, err)
+	assert.False(t, doc.CreatedAt.Before(before))
+	assert.Equal(t, doc.CreatedAt, doc.UpdatedAt)
+}
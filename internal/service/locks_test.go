@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fedorovmatvey/involta-test/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// CopyingStorage hands out copies like a real database and pauses between
+// read and write so unsynchronized updates would overwrite each other.
+type CopyingStorage struct {
+	MockStorage
+	mu  sync.Mutex
+	doc model.Document
+}
+
+func (m *CopyingStorage) GetByID(ctx context.Context, id string) (*model.Document, error) {
+	m.mu.Lock()
+	doc := m.doc
+	m.mu.Unlock()
+	time.Sleep(10 * time.Millisecond)
+	return &doc, nil
+}
+
+func (m *CopyingStorage) Update(ctx context.Context, doc *model.Document) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.doc = *doc
+	return nil
+}
+
+func TestService_Update_SerializesSameID(t *testing.T) {
+	store := &CopyingStorage{doc: model.Document{ID: "doc-1", Title: "old", Description: "old"}}
+	srv := New(store, &MockCache{})
+
+	title, description := "new title", "new description"
+	var wg sync.WaitGroup
+	for _, req := range []model.UpdateDocumentRequest{{Title: &title}, {Description: &description}} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := srv.Update(context.Background(), "doc-1", req)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, "new title", store.doc.Title)
+	assert.Equal(t, "new description", store.doc.Description)
+	assert.Zero(t, srv.updateLocks.len())
+}
+
+func TestService_UpdateBatch_SerializesWithUpdate(t *testing.T) {
+	store := &CopyingStorage{doc: model.Document{ID: "doc-1", Title: "old", Description: "old"}}
+	srv := New(store, &MockCache{})
+
+	title, description := "new title", "new description"
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, err := srv.Update(context.Background(), "doc-1", model.UpdateDocumentRequest{Title: &title})
+		assert.NoError(t, err)
+	}()
+	go func() {
+		defer wg.Done()
+		_, err := srv.UpdateBatch(context.Background(), model.BatchUpdateRequest{Documents: []model.BatchUpdateItem{
+			{ID: "doc-1", UpdateDocumentRequest: model.UpdateDocumentRequest{Description: &description}},
+		}})
+		assert.NoError(t, err)
+	}()
+	wg.Wait()
+
+	assert.Equal(t, "new title", store.doc.Title)
+	assert.Equal(t, "new description", store.doc.Description)
+	assert.Zero(t, srv.updateLocks.len())
+}
+
+func TestKeyedMutex_LockAll(t *testing.T) {
+	k := newKeyedMutex()
+
+	unlock := k.LockAll([]string{"b", "a", "b"})
+	assert.Equal(t, 2, k.len())
+
+	locked := make(chan struct{})
+	go func() {
+		defer k.LockAll([]string{"a", "c"})()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+		t.Fatal("overlapping LockAll must wait")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	unlock()
+	<-locked
+}
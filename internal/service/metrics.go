@@ -0,0 +1,39 @@
+package service
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Sources a GetByID response can be served from.
+const (
+	sourceCache   = "cache"
+	sourceStorage = "storage"
+)
+
+type processingMetrics struct {
+	activeWorkers prometheus.Gauge
+	duration      prometheus.Histogram
+	served        *prometheus.CounterVec
+}
+
+func newProcessingMetrics() *processingMetrics {
+	return &processingMetrics{
+		activeWorkers: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "documents_processing_active_goroutines",
+			Help: "Number of goroutines currently processing documents for List.",
+		}),
+		duration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "documents_processing_duration_seconds",
+			Help:    "Time spent processing a single document for List.",
+			Buckets: []float64{.00001, .00005, .0001, .0005, .001, .005, .01, .05},
+		}),
+		served: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "documents_served_total",
+			Help: "Documents returned by GetByID, by the source they were read from.",
+		}, []string{"source"}),
+	}
+}
+
+func (m *processingMetrics) register(reg prometheus.Registerer) {
+	reg.MustRegister(m.activeWorkers, m.duration, m.served)
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Option configures optional Service behavior.
+type Option func(*Service)
+
+// Limits caps the length of user-supplied strings, counted in characters,
+// and the range of item sort values. A zero value disables the corresponding
+// check.
+type Limits struct {
+	MaxTitleLength       int
+	MaxDescriptionLength int
+	MaxItemNameLength    int
+	MaxItemValueLength   int
+	// MaxItemContentLength caps second-level item content.
+	MaxItemContentLength int
+	// TruncateItems accepts item values and contents over their limits and
+	// truncates them when documents are served instead of rejecting writes.
+	TruncateItems bool
+	// MaxItemSort restricts item sort values to [0, MaxItemSort].
+	MaxItemSort int
+	// MaxBatchSize caps the number of documents or IDs in one batch request.
+	MaxBatchSize int
+	// MaxTags caps the number of tags on a document after deduplication.
+	MaxTags      int
+	MaxTagLength int
+}
+
+func WithLimits(limits Limits) Option {
+	return func(s *Service) {
+		s.limits = limits
+	}
+}
+
+// WithFieldPolicy restricts the document fields clients may set on create
+// and update. The policy should be checked with FieldPolicy.Validate first.
+func WithFieldPolicy(policy FieldPolicy) Option {
+	return func(s *Service) {
+		s.fields = policy
+	}
+}
+
+// WithMetrics registers the service collectors on reg.
+func WithMetrics(reg prometheus.Registerer) Option {
+	return func(s *Service) {
+		s.metrics.register(reg)
+	}
+}
+
+// WithRevalidation makes GetByID check cache entries older than after against
+// the document's updated_at in storage before serving them.
+func WithRevalidation(after time.Duration) Option {
+	return func(s *Service) {
+		s.revalidateAfter = after
+	}
+}
+
+// WithProcessTimeout limits how long processing one listed document may take.
+func WithProcessTimeout(timeout time.Duration) Option {
+	return func(s *Service) {
+		s.processTimeout = timeout
+	}
+}
+
+// WithBatchConcurrency lets non-atomic batch creates and updates write up to n
+// documents in parallel. Atomic batches always write sequentially.
+func WithBatchConcurrency(n int) Option {
+	return func(s *Service) {
+		s.batchConcurrency = n
+	}
+}
+
+// WithListTimings reports in every List result how long the storage query and
+// document processing took. It is meant for debugging, not production.
+func WithListTimings(enabled bool) Option {
+	return func(s *Service) {
+		s.listTimings = enabled
+	}
+}
+
+// WithWarmup loads up to concurrency pages at once during Warmup and stops it
+// after timeout. A zero timeout lets warmup run until it is done.
+func WithWarmup(concurrency int, timeout time.Duration) Option {
+	return func(s *Service) {
+		s.warmupConcurrency = concurrency
+		s.warmupTimeout = timeout
+	}
+}
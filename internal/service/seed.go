@@ -0,0 +1,112 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/fedorovmatvey/involta-test/internal/model"
+	"github.com/fedorovmatvey/involta-test/internal/storage"
+)
+
+// Seed fills an empty namespace with the documents in the file at path,
+// given either as a JSON array or as newline-delimited JSON objects. It
+// returns the number of documents created, which is zero when the namespace
+// already has documents. The documents are created in one transaction: a
+// partly seeded namespace would not be empty, so a failed seed could never be
+// retried.
+func (s *Service) Seed(ctx context.Context, path string) (int, error) {
+	_, total, err := s.storage.List(ctx, model.PaginationParams{Page: 1, PerPage: 1})
+	if err != nil {
+		return 0, fmt.Errorf("failed to check namespace before seeding: %w", err)
+	}
+	if total > 0 {
+		slog.Info("Namespace is not empty, skipping seed", "path", path, "documents", total)
+		return 0, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open seed file: %w", err)
+	}
+	defer f.Close()
+
+	reqs, err := readSeed(f)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read seed file %s: %w", path, err)
+	}
+
+	docs := make([]*model.Document, 0, len(reqs))
+	for i, req := range reqs {
+		doc := newDocument(req)
+		if err := s.validateDocument(doc); err != nil {
+			return 0, fmt.Errorf("invalid seed document %d: %w", i, err)
+		}
+		docs = append(docs, doc)
+	}
+
+	create := func(store storage.TxStore, doc *model.Document) error {
+		if err := store.Create(ctx, doc); err != nil {
+			return fmt.Errorf("failed to create document: %w", err)
+		}
+		return nil
+	}
+	if err := s.runBatch(ctx, true, docs, create); err != nil {
+		return 0, fmt.Errorf("failed to seed documents: %w", err)
+	}
+
+	slog.Info("Seeded namespace", "path", path, "documents", len(docs))
+	return len(docs), nil
+}
+
+// readSeed decodes a JSON array of documents or a stream of JSON objects.
+func readSeed(r io.Reader) ([]model.CreateDocumentRequest, error) {
+	br := bufio.NewReader(r)
+	first, err := firstNonSpace(br)
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(br)
+	if first == '[' {
+		var reqs []model.CreateDocumentRequest
+		if err := dec.Decode(&reqs); err != nil {
+			return nil, err
+		}
+		return reqs, nil
+	}
+
+	var reqs []model.CreateDocumentRequest
+	for {
+		var req model.CreateDocumentRequest
+		if err := dec.Decode(&req); errors.Is(err, io.EOF) {
+			return reqs, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("document %d: %w", len(reqs), err)
+		}
+		reqs = append(reqs, req)
+	}
+}
+
+// firstNonSpace peeks at the first byte that is not JSON whitespace.
+func firstNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return b, br.UnreadByte()
+	}
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/fedorovmatvey/involta-test/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// SeedStorage is a MemoryStorage whose List reports the stored count.
+type SeedStorage struct {
+	*MemoryStorage
+}
+
+func (m *SeedStorage) List(ctx context.Context, params model.PaginationParams) ([]model.Document, int, error) {
+	return nil, len(m.docs), nil
+}
+
+func writeSeedFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "seed.json")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func storedTitles(store *SeedStorage) []string {
+	var titles []string
+	for _, doc := range store.docs {
+		titles = append(titles, doc.Title)
+	}
+	sort.Strings(titles)
+	return titles
+}
+
+func TestService_Seed(t *testing.T) {
+	tests := []struct {
+		name, content string
+	}{
+		{"array", `[{"title":"a","items":[{"name":"x"}]}, {"title":"b"}]`},
+		{"ndjson", "{\"title\":\"a\",\"items\":[{\"name\":\"x\"}]}\n{\"title\":\"b\"}\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &SeedStorage{NewMemoryStorage("broken")}
+			srv := New(store, &MockCache{})
+
+			seeded, err := srv.Seed(context.Background(), writeSeedFile(t, tt.content))
+
+			assert.NoError(t, err)
+			assert.Equal(t, 2, seeded)
+			assert.Equal(t, []string{"a", "b"}, storedTitles(store))
+		})
+	}
+}
+
+func TestService_Seed_SkipsNonEmptyNamespace(t *testing.T) {
+	store := &SeedStorage{NewMemoryStorage("broken")}
+	store.docs["doc-1"] = &model.Document{ID: "doc-1", Title: "existing"}
+	srv := New(store, &MockCache{})
+
+	seeded, err := srv.Seed(context.Background(), writeSeedFile(t, `[{"title":"a"}]`))
+
+	assert.NoError(t, err)
+	assert.Zero(t, seeded)
+	assert.Equal(t, []string{"existing"}, storedTitles(store))
+}
+
+func TestService_Seed_AllOrNothing(t *testing.T) {
+	store := &SeedStorage{NewMemoryStorage("broken")}
+	srv := New(store, &MockCache{})
+
+	_, err := srv.Seed(context.Background(), writeSeedFile(t, `[{"title":"a"}, {"title":"broken"}]`))
+
+	assert.Error(t, err)
+	assert.Empty(t, storedTitles(store), "a failed seed must leave the namespace empty so it is retried")
+}
+
+func TestService_Seed_Errors(t *testing.T) {
+	srv := New(&SeedStorage{NewMemoryStorage("broken")}, &MockCache{}, WithLimits(Limits{MaxTitleLength: 3}))
+
+	for name, path := range map[string]string{
+		"missing file": filepath.Join(t.TempDir(), "absent.json"),
+		"malformed":    writeSeedFile(t, "{\"title\":\"a\"}\n{\"title\":"),
+		"invalid":      writeSeedFile(t, `[{"title":"too long"}]`),
+	} {
+		_, err := srv.Seed(context.Background(), path)
+		assert.Error(t, err, name)
+	}
+}
@@ -2,51 +2,103 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"math"
 	"runtime"
+	"runtime/debug"
+	"slices"
 	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/fedorovmatvey/involta-test/internal/apperror"
+	"github.com/fedorovmatvey/involta-test/internal/features"
 	"github.com/fedorovmatvey/involta-test/internal/model"
+	"github.com/fedorovmatvey/involta-test/internal/storage"
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sync/singleflight"
 )
 
-type documentStorage interface {
-	Create(ctx context.Context, doc *model.Document) error
-	GetByID(ctx context.Context, id string) (*model.Document, error)
-	Update(ctx context.Context, doc *model.Document) error
-	Delete(ctx context.Context, id string) error
-	List(ctx context.Context, params model.PaginationParams) ([]model.Document, int, error)
-	CheckConnection(ctx context.Context) error
-}
-
 type documentCache interface {
 	Get(id string) (*model.Document, bool)
 	Set(id string, doc *model.Document)
 	Delete(id string)
 }
+
+// cacheSizer and cachePinger are optional cache capabilities reported by
+// Health.
+type cacheSizer interface {
+	Size() int
+}
+
+type cachePinger interface {
+	Ping(ctx context.Context) error
+}
+
+// staleCache is implemented by caches that can return expired entries.
+type staleCache interface {
+	GetStale(id string) (*model.Document, bool)
+}
+
+// agedCache is implemented by caches that report how old an entry is, which
+// GetByID needs to decide when to revalidate it.
+type agedCache interface {
+	GetWithAge(id string) (*model.Document, time.Duration, bool)
+}
+
 type Service struct {
-	storage documentStorage
-	cache   documentCache
+	storage     storage.DocumentStore
+	cache       documentCache
+	listGroup   singleflight.Group
+	updateLocks *keyedMutex
+	limits      Limits
+	fields      FieldPolicy
+	metrics     *processingMetrics
+	// processTimeout bounds processing of a single document in
+	// processDocumentsParallel. Zero means no limit.
+	processTimeout time.Duration
+	// revalidateAfter is the cache entry age after which GetByID checks
+	// storage for a newer version. Zero disables the check.
+	revalidateAfter time.Duration
+	// listTimings adds storage and processing durations to List results.
+	listTimings bool
+	// batchConcurrency is the number of parallel writes in a non-atomic
+	// batch. Values below two write sequentially.
+	batchConcurrency int
+	// warmupConcurrency is the number of pages Warmup loads at once.
+	warmupConcurrency int
+	// warmupTimeout bounds Warmup. Zero means no limit.
+	warmupTimeout time.Duration
+	// process is the per-document step of processDocumentsParallel.
+	process func(ctx context.Context, doc *model.Document) *model.Document
 }
 
-func New(storage documentStorage, cache documentCache) *Service {
-	return &Service{
-		storage: storage,
-		cache:   cache,
+func New(store storage.DocumentStore, cache documentCache, opts ...Option) *Service {
+	s := &Service{
+		storage:     store,
+		cache:       cache,
+		updateLocks: newKeyedMutex(),
+		metrics:     newProcessingMetrics(),
+	}
+	s.process = s.processDocument
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
 func (s *Service) Create(ctx context.Context, req model.CreateDocumentRequest) (*model.Document, error) {
-	doc := &model.Document{
-		ID:          generateID(),
-		Title:       req.Title,
-		Description: req.Description,
-		Items:       req.Items,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+	if err := s.fields.applyCreate(&req); err != nil {
+		return nil, err
+	}
+	doc := newDocument(req)
+	if err := s.validateDocument(doc); err != nil {
+		return nil, err
 	}
 
 	if err := s.storage.Create(ctx, doc); err != nil {
@@ -56,39 +108,276 @@ func (s *Service) Create(ctx context.Context, req model.CreateDocumentRequest) (
 	return doc, nil
 }
 
-func (s *Service) GetByID(ctx context.Context, id string) (*model.Document, error) {
-	if cachedDoc, found := s.cache.Get(id); found {
-		processedDoc := s.processDocument(cachedDoc)
-		return processedDoc, nil
+// CreateWithID creates a document under a client-supplied ID, failing with a
+// conflict error when the ID is taken. Creations of the same ID are
+// serialized with updates so the existence check cannot race.
+func (s *Service) CreateWithID(ctx context.Context, id string, req model.CreateDocumentRequest) (*model.Document, error) {
+	if err := s.fields.applyCreate(&req); err != nil {
+		return nil, err
 	}
+	doc := newDocument(req)
+	doc.ID = id
+	if err := s.validateDocument(doc); err != nil {
+		return nil, err
+	}
+
+	unlock := s.updateLocks.Lock(id)
+	defer unlock()
 
-	doc, err := s.storage.GetByID(ctx, id)
+	if _, err := s.storage.GetMeta(ctx, id); err == nil {
+		return nil, apperror.Conflict(apperror.ResourceDocument, id)
+	} else if !errors.As(err, new(*apperror.NotFoundError)) {
+		return nil, fmt.Errorf("failed to check document: %w", err)
+	}
+
+	if err := s.storage.Create(ctx, doc); err != nil {
+		return nil, fmt.Errorf("failed to create document: %w", err)
+	}
+
+	return doc, nil
+}
+
+// GetByID returns a document, serving it from the cache when possible. With
+// fresh set the cache is bypassed and refreshed from storage.
+func (s *Service) GetByID(ctx context.Context, id string, fresh bool) (*model.Document, error) {
+	if !fresh {
+		if cachedDoc, found := s.cachedDocument(ctx, id); found {
+			s.metrics.served.WithLabelValues(sourceCache).Inc()
+			processedDoc := s.processDocument(ctx, cachedDoc)
+			return processedDoc, nil
+		}
+	}
+
+	// Read from the primary because the result is cached: a lagging
+	// replica's copy would be served for the whole TTL.
+	doc, err := s.storage.GetByID(storage.ReadPrimary(ctx), id)
 	if err != nil {
+		if stale, ok := s.staleFallback(id, err); ok {
+			return s.processDocument(ctx, stale), nil
+		}
 		return nil, fmt.Errorf("document not found: %w", err)
 	}
 
+	s.metrics.served.WithLabelValues(sourceStorage).Inc()
 	s.cache.Set(id, doc)
 
-	processedDoc := s.processDocument(doc)
+	processedDoc := s.processDocument(ctx, doc)
 	return processedDoc, nil
 }
 
-func (s *Service) Update(ctx context.Context, id string, req model.UpdateDocumentRequest) (*model.Document, error) {
-	doc, err := s.storage.GetByID(ctx, id)
+// ListItems returns a page of the document's first-level items in the same
+// order GetByID serves them. A page past the end is empty.
+func (s *Service) ListItems(ctx context.Context, id string, params model.PaginationParams) (*model.ItemList, error) {
+	params.Validate()
+
+	doc, err := s.GetByID(ctx, id, false)
+	if err != nil {
+		return nil, err
+	}
+
+	total := len(doc.Items)
+	start := min(params.GetOffset(), total)
+	end := min(start+params.PerPage, total)
+
+	return &model.ItemList{
+		Items:      doc.Items[start:end],
+		Total:      total,
+		Page:       params.Page,
+		PerPage:    params.PerPage,
+		TotalPages: totalPages(total, params.PerPage),
+	}, nil
+}
+
+// cachedDocument returns the cached copy of id. An entry older than
+// revalidateAfter is compared with the updated_at watermark in storage and
+// treated as a miss if storage has a newer version, so changes made through
+// other instances are picked up without invalidation messages. An entry that
+// is still current starts a new TTL.
+func (s *Service) cachedDocument(ctx context.Context, id string) (*model.Document, bool) {
+	aged, ok := s.cache.(agedCache)
+	if s.revalidateAfter <= 0 || !ok {
+		return s.cache.Get(id)
+	}
+
+	doc, age, found := aged.GetWithAge(id)
+	if !found || age < s.revalidateAfter {
+		return doc, found
+	}
+
+	meta, err := s.storage.GetMeta(ctx, id)
+	if err != nil {
+		if errors.As(err, new(*apperror.NotFoundError)) {
+			s.cache.Delete(id)
+			return nil, false
+		}
+		slog.Warn("Failed to revalidate cached document", "id", id, "error", err)
+		return doc, true
+	}
+	if meta.UpdatedAt.After(doc.UpdatedAt) {
+		return nil, false
+	}
+
+	s.cache.Set(id, doc)
+	return doc, true
+}
+
+// staleFallback returns a copy of the expired cache entry for id, marked
+// stale, when storage failed for a reason other than the document being gone.
+func (s *Service) staleFallback(id string, err error) (*model.Document, bool) {
+	if errors.As(err, new(*apperror.NotFoundError)) {
+		return nil, false
+	}
+	cache, ok := s.cache.(staleCache)
+	if !ok {
+		return nil, false
+	}
+	doc, found := cache.GetStale(id)
+	if !found {
+		return nil, false
+	}
+
+	slog.Warn("Serving stale document after storage error", "id", id, "error", err)
+	stale := *doc
+	stale.Stale = true
+	stale.Meta = &model.ServeMeta{Stale: true}
+	return &stale, true
+}
+
+// Warmup loads up to size of the most recent documents into the cache and
+// returns how many it loaded. Documents are fetched in pages, up to
+// warmupConcurrency pages at once. When warmupTimeout runs out, warmup stops
+// and keeps what it has loaded; this is not an error.
+func (s *Service) Warmup(ctx context.Context, size int) (int, error) {
+	if s.warmupTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.warmupTimeout)
+		defer cancel()
+	}
+
+	perPage := min(size, model.MaxPerPage)
+	pages := totalPages(size, perPage)
+	sem := make(chan struct{}, max(s.warmupConcurrency, 1))
+	errs := make([]error, pages)
+	var loaded atomic.Int64
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+
+dispatch:
+	for page := 1; page <= pages && !failed.Load(); page++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			break dispatch
+		}
+
+		wg.Add(1)
+		go func(page int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			documents, _, err := s.storage.List(storage.ReadPrimary(ctx), model.PaginationParams{Page: page, PerPage: perPage})
+			if err != nil {
+				errs[page-1] = err
+				failed.Store(true)
+				return
+			}
+			if remaining := size - (page-1)*perPage; len(documents) > remaining {
+				documents = documents[:remaining]
+			}
+			for i := range documents {
+				doc := documents[i]
+				s.cache.Set(doc.ID, &doc)
+			}
+			loaded.Add(int64(len(documents)))
+		}(page)
+	}
+
+	wg.Wait()
+
+	n := int(loaded.Load())
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		slog.Warn("Cache warmup timed out", "timeout", s.warmupTimeout, "loaded", n, "size", size)
+		return n, nil
+	}
+	for _, err := range errs {
+		if err != nil {
+			return n, fmt.Errorf("failed to load documents for warmup: %w", err)
+		}
+	}
+	return n, ctx.Err()
+}
+
+// Preload loads the given documents from storage in one query and caches
+// them. Duplicate IDs are loaded once.
+func (s *Service) Preload(ctx context.Context, req model.PreloadRequest) (*model.PreloadResult, error) {
+	if len(req.IDs) == 0 {
+		return nil, apperror.InvalidParameter("ids", "ids must not be empty")
+	}
+	if err := checkCount("ids", len(req.IDs), s.limits.MaxBatchSize); err != nil {
+		return nil, err
+	}
+
+	ids := uniqueIDs(req.IDs)
+	documents, err := s.storage.GetByIDs(storage.ReadPrimary(ctx), ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load documents for preload: %w", err)
+	}
+
+	for i := range documents {
+		doc := documents[i]
+		s.cache.Set(doc.ID, &doc)
+	}
+
+	return &model.PreloadResult{
+		Loaded:  len(documents),
+		Missing: len(ids) - len(documents),
+	}, nil
+}
+
+func uniqueIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	unique := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// Exists reports the document's metadata without materializing the full
+// document, or a not-found error if it does not exist.
+func (s *Service) Exists(ctx context.Context, id string) (*model.DocumentMeta, error) {
+	if cachedDoc, found := s.cache.Get(id); found {
+		return &model.DocumentMeta{ID: cachedDoc.ID, UpdatedAt: cachedDoc.UpdatedAt}, nil
+	}
+
+	meta, err := s.storage.GetMeta(ctx, id)
 	if err != nil {
 		return nil, fmt.Errorf("document not found: %w", err)
 	}
 
-	if req.Title != nil {
-		doc.Title = *req.Title
+	return meta, nil
+}
+
+func (s *Service) Update(ctx context.Context, id string, req model.UpdateDocumentRequest) (*model.Document, error) {
+	if err := s.fields.applyUpdate(&req); err != nil {
+		return nil, err
 	}
-	if req.Description != nil {
-		doc.Description = *req.Description
+
+	unlock := s.updateLocks.Lock(id)
+	defer unlock()
+
+	doc, err := s.storage.GetByID(storage.ReadPrimary(ctx), id)
+	if err != nil {
+		return nil, fmt.Errorf("document not found: %w", err)
 	}
-	if req.Items != nil {
-		doc.Items = *req.Items
+
+	applyUpdate(doc, req)
+	if err := s.validateDocument(doc); err != nil {
+		return nil, err
 	}
-	doc.UpdatedAt = time.Now()
 
 	if err := s.storage.Update(ctx, doc); err != nil {
 		return nil, fmt.Errorf("failed to update document: %w", err)
@@ -99,6 +388,288 @@ func (s *Service) Update(ctx context.Context, id string, req model.UpdateDocumen
 	return doc, nil
 }
 
+// Upsert updates the document or, when the ID is free, creates it from the
+// fields set in req. created reports which of the two happened.
+func (s *Service) Upsert(ctx context.Context, id string, req model.UpdateDocumentRequest) (doc *model.Document, created bool, err error) {
+	if err := s.fields.applyUpdate(&req); err != nil {
+		return nil, false, err
+	}
+
+	unlock := s.updateLocks.Lock(id)
+	defer unlock()
+
+	doc, err = s.storage.GetByID(storage.ReadPrimary(ctx), id)
+	switch {
+	case err == nil:
+	case errors.As(err, new(*apperror.NotFoundError)):
+		doc = newDocument(model.CreateDocumentRequest{})
+		doc.ID = id
+		created = true
+	default:
+		return nil, false, fmt.Errorf("failed to load document: %w", err)
+	}
+
+	applyUpdate(doc, req)
+	if err := s.validateDocument(doc); err != nil {
+		return nil, false, err
+	}
+
+	if created {
+		err = s.storage.Create(ctx, doc)
+	} else {
+		err = s.storage.Update(ctx, doc)
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to upsert document: %w", err)
+	}
+
+	s.cache.Delete(id)
+
+	return doc, created, nil
+}
+
+// Health checks storage and cache. The service is unavailable when storage is
+// down; a failing cache only degrades performance.
+func (s *Service) Health(ctx context.Context) *model.HealthStatus {
+	health := &model.HealthStatus{
+		Status:  model.HealthReady,
+		Storage: model.HealthOK,
+		Cache:   model.CacheHealth{Status: model.HealthOK},
+	}
+
+	if err := s.storage.CheckConnection(ctx); err != nil {
+		slog.Error("Storage health check failed", "error", err)
+		health.Storage = model.HealthDown
+		health.Status = model.HealthUnavailable
+	}
+
+	if sizer, ok := s.cache.(cacheSizer); ok {
+		size := sizer.Size()
+		health.Cache.Size = &size
+	}
+	if pinger, ok := s.cache.(cachePinger); ok {
+		if err := pinger.Ping(ctx); err != nil {
+			slog.Warn("Cache health check failed", "error", err)
+			health.Cache.Status = model.HealthDown
+		}
+	}
+
+	return health
+}
+
+// Clone copies an existing document under a new ID. Nested items get new IDs
+// as well, timestamps are reset and the title can be overridden.
+func (s *Service) Clone(ctx context.Context, id string, req model.CloneDocumentRequest) (*model.Document, error) {
+	// The source may have just been created, so the replica may not have it.
+	source, err := s.storage.GetByID(storage.ReadPrimary(ctx), id)
+	if err != nil {
+		return nil, fmt.Errorf("document not found: %w", err)
+	}
+
+	now := time.Now()
+	doc := &model.Document{
+		ID:          generateID(),
+		Title:       source.Title,
+		Description: source.Description,
+		Items:       cloneItems(source.Items),
+		Tags:        slices.Clone(source.Tags),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Internal:    source.Internal,
+	}
+	if strip, err := s.fields.check(FieldTitle, req.Title != nil); err != nil {
+		return nil, err
+	} else if req.Title != nil && !strip {
+		doc.Title = *req.Title
+	}
+	if err := s.validateDocument(doc); err != nil {
+		return nil, err
+	}
+
+	if err := s.storage.Create(ctx, doc); err != nil {
+		return nil, fmt.Errorf("failed to clone document: %w", err)
+	}
+
+	return doc, nil
+}
+
+// CreateBatch creates all requested documents. With req.Atomic set the
+// inserts run in a single transaction, so either every document is stored or
+// none is.
+func (s *Service) CreateBatch(ctx context.Context, req model.BatchCreateRequest) ([]*model.Document, error) {
+	if err := checkCount("documents", len(req.Documents), s.limits.MaxBatchSize); err != nil {
+		return nil, err
+	}
+
+	docs := make([]*model.Document, 0, len(req.Documents))
+	for _, r := range req.Documents {
+		if err := s.fields.applyCreate(&r); err != nil {
+			return nil, err
+		}
+		doc := newDocument(r)
+		if err := s.validateDocument(doc); err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+
+	write := func(store storage.TxStore, doc *model.Document) error {
+		if err := store.Create(ctx, doc); err != nil {
+			return fmt.Errorf("failed to create document: %w", err)
+		}
+		return nil
+	}
+
+	if err := s.runBatch(ctx, req.Atomic, docs, write); err != nil {
+		return nil, err
+	}
+
+	return docs, nil
+}
+
+// UpdateBatch applies every update in req. With req.Atomic set the writes run
+// in a single transaction.
+func (s *Service) UpdateBatch(ctx context.Context, req model.BatchUpdateRequest) ([]*model.Document, error) {
+	if err := checkCount("documents", len(req.Documents), s.limits.MaxBatchSize); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(req.Documents))
+	for _, item := range req.Documents {
+		ids = append(ids, item.ID)
+	}
+	// Held until the writes finish, like Update, so concurrent updates of
+	// the same documents are not lost.
+	unlock := s.updateLocks.LockAll(ids)
+	defer unlock()
+
+	docs := make([]*model.Document, 0, len(req.Documents))
+	for _, item := range req.Documents {
+		if err := s.fields.applyUpdate(&item.UpdateDocumentRequest); err != nil {
+			return nil, err
+		}
+		doc, err := s.storage.GetByID(storage.ReadPrimary(ctx), item.ID)
+		if err != nil {
+			return nil, fmt.Errorf("document not found: %w", err)
+		}
+		applyUpdate(doc, item.UpdateDocumentRequest)
+		if err := s.validateDocument(doc); err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+
+	write := func(store storage.TxStore, doc *model.Document) error {
+		if err := store.Update(ctx, doc); err != nil {
+			return fmt.Errorf("failed to update document: %w", err)
+		}
+		return nil
+	}
+
+	err := s.runBatch(ctx, req.Atomic, docs, write)
+	// Evicted on failure too: a non-atomic batch may have written some of
+	// the documents before the error.
+	for _, doc := range docs {
+		s.cache.Delete(doc.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return docs, nil
+}
+
+// runBatch writes every document. Atomic batches run sequentially in one
+// transaction; other batches write up to batchConcurrency documents at once.
+func (s *Service) runBatch(ctx context.Context, atomic bool, docs []*model.Document, write func(store storage.TxStore, doc *model.Document) error) error {
+	if atomic {
+		return s.storage.WithTransaction(ctx, func(store storage.TxStore) error {
+			for _, doc := range docs {
+				if err := write(store, doc); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+
+	if s.batchConcurrency <= 1 {
+		for _, doc := range docs {
+			if err := write(s.storage, doc); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return s.writeParallel(ctx, docs, write)
+}
+
+// writeParallel writes docs with at most batchConcurrency writes in flight.
+// After the first failure no new writes start, and the error of the earliest
+// failed document is returned.
+func (s *Service) writeParallel(ctx context.Context, docs []*model.Document, write func(store storage.TxStore, doc *model.Document) error) error {
+	sem := make(chan struct{}, s.batchConcurrency)
+	errs := make([]error, len(docs))
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+
+dispatch:
+	for i, doc := range docs {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			break dispatch
+		}
+		if failed.Load() {
+			<-sem
+			break
+		}
+
+		wg.Add(1)
+		go func(idx int, d *model.Document) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := write(s.storage, d); err != nil {
+				errs[idx] = err
+				failed.Store(true)
+			}
+		}(i, doc)
+	}
+
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+// UpdateWhere applies a bulk update in storage and evicts every updated
+// document from the cache. The field policy applies to the changes as it does
+// to single updates.
+func (s *Service) UpdateWhere(ctx context.Context, req model.UpdateWhereRequest) (*model.UpdateWhereResult, error) {
+	set, err := s.fields.applySet(req.Set)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateSet(set); err != nil {
+		return nil, err
+	}
+	ids, err := s.storage.UpdateWhere(ctx, req.Filter, set)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update documents: %w", err)
+	}
+
+	for _, id := range ids {
+		s.cache.Delete(id)
+	}
+
+	return &model.UpdateWhereResult{Updated: len(ids)}, nil
+}
+
 func (s *Service) Delete(ctx context.Context, id string) error {
 	if err := s.storage.Delete(ctx, id); err != nil {
 		return fmt.Errorf("failed to delete document: %w", err)
@@ -109,44 +680,194 @@ func (s *Service) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+// DeleteMany deletes the documents with the given IDs and evicts them from
+// the cache.
+func (s *Service) DeleteMany(ctx context.Context, req model.BatchDeleteRequest) (*model.BatchDeleteResult, error) {
+	if len(req.IDs) == 0 {
+		return nil, apperror.InvalidParameter("ids", "ids must not be empty")
+	}
+	if err := checkCount("ids", len(req.IDs), s.limits.MaxBatchSize); err != nil {
+		return nil, err
+	}
+
+	ids := uniqueIDs(req.IDs)
+	deleted, err := s.storage.DeleteByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete documents: %w", err)
+	}
+
+	existed := make(map[string]bool, len(deleted))
+	for _, id := range deleted {
+		existed[id] = true
+	}
+	missing := make([]string, 0, len(ids)-len(deleted))
+	for _, id := range ids {
+		s.cache.Delete(id)
+		if !existed[id] {
+			missing = append(missing, id)
+		}
+	}
+
+	return &model.BatchDeleteResult{Deleted: len(deleted), Missing: missing}, nil
+}
+
+// List returns a page of documents. Identical concurrent requests share a
+// single storage query; a caller that gives up does not cancel it for the rest.
 func (s *Service) List(ctx context.Context, params model.PaginationParams) (*model.DocumentList, error) {
 	params.Validate()
 
+	key := fmt.Sprintf("%d:%d:%d:%s:%t:%q:%q:%s",
+		params.Page, params.PerPage, params.UpdatedSince.UnixNano(), params.SortBy, params.SortDesc, params.HasStatus, params.Tag, features.FromContext(ctx))
+	ch := s.listGroup.DoChan(key, func() (interface{}, error) {
+		return s.list(context.WithoutCancel(ctx), params)
+	})
+
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.(*model.DocumentList), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Stream calls fn with every processed document matching params, in List
+// order. Storages without streaming support fall back to a single List page.
+func (s *Service) Stream(ctx context.Context, params model.PaginationParams, fn func(doc *model.Document) error) error {
+	emit := func(doc *model.Document) error {
+		return fn(s.processDocument(ctx, doc))
+	}
+
+	if streamer, ok := s.storage.(storage.Streamer); ok {
+		if err := streamer.Stream(ctx, params, emit); err != nil {
+			return fmt.Errorf("failed to stream documents: %w", err)
+		}
+		return nil
+	}
+
+	documents, _, err := s.storage.List(ctx, params)
+	if err != nil {
+		return fmt.Errorf("failed to list documents: %w", err)
+	}
+	for i := range documents {
+		if err := emit(&documents[i]); err != nil {
+			return fmt.Errorf("failed to stream documents: %w", err)
+		}
+	}
+	return nil
+}
+
+// ExplainList returns the storage query plan for a List call.
+func (s *Service) ExplainList(ctx context.Context, params model.PaginationParams) (*model.QueryExplain, error) {
+	explainer, ok := s.storage.(storage.Explainer)
+	if !ok {
+		return nil, errors.New("storage does not support query explain")
+	}
+
+	explain, err := explainer.ExplainList(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to explain list query: %w", err)
+	}
+	return explain, nil
+}
+
+func (s *Service) list(ctx context.Context, params model.PaginationParams) (*model.DocumentList, error) {
+	start := time.Now()
 	documents, total, err := s.storage.List(ctx, params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list documents: %w", err)
 	}
+	fetched := time.Now()
+	if documents == nil {
+		// An empty page is listed as [] rather than null.
+		documents = []model.Document{}
+	}
 
 	processedDocs, err := s.processDocumentsParallel(ctx, documents)
 	if err != nil {
 		return nil, fmt.Errorf("failed to process documents: %w", err)
 	}
 
-	totalPages := int(math.Ceil(float64(total) / float64(params.PerPage)))
-
-	return &model.DocumentList{
+	pages := totalPages(total, params.PerPage)
+	list := &model.DocumentList{
 		Documents:  processedDocs,
 		Total:      total,
 		Page:       params.Page,
 		PerPage:    params.PerPage,
-		TotalPages: totalPages,
-	}, nil
+		TotalPages: pages,
+		HasNext:    params.Page < pages,
+		HasPrev:    params.Page > 1,
+	}
+	if s.listTimings {
+		list.Timings = &model.ListTimings{
+			StorageMs: milliseconds(fetched.Sub(start)),
+			ProcessMs: milliseconds(time.Since(fetched)),
+		}
+	}
+	return list, nil
 }
 
-func (s *Service) processDocument(doc *model.Document) *model.Document {
-	processed := *doc
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
 
-	if doc.Items != nil {
-		processed.Items = make([]model.FirstLevelItem, len(doc.Items))
-		copy(processed.Items, doc.Items)
+// totalPages returns how many pages of perPage documents cover total. A
+// non-positive perPage yields zero instead of dividing by zero.
+func totalPages(total, perPage int) int {
+	if perPage <= 0 || total <= 0 {
+		return 0
 	}
+	return int(math.Ceil(float64(total) / float64(perPage)))
+}
+
+func (s *Service) processDocument(ctx context.Context, doc *model.Document) *model.Document {
+	processed := *doc
+
+	processed.Items = make([]model.FirstLevelItem, len(doc.Items))
+	copy(processed.Items, doc.Items)
 
 	sort.Slice(processed.Items, func(i, j int) bool {
 		return processed.Items[i].Sort > processed.Items[j].Sort
 	})
 
+	if features.FromContext(ctx).Enabled(features.SortSecondLevel) {
+		for i := range processed.Items {
+			processed.Items[i].SecondLevel = sortedSecondLevel(processed.Items[i].SecondLevel)
+		}
+	}
+
+	if s.limits.TruncateItems {
+		s.truncateItems(processed.Items)
+	}
+
+	processed.ItemCount = countItems(processed.Items)
+
 	return &processed
 }
+
+// countItems returns the number of first- and second-level items.
+func countItems(items []model.FirstLevelItem) int {
+	count := len(items)
+	for _, item := range items {
+		count += len(item.SecondLevel)
+	}
+	return count
+}
+
+func sortedSecondLevel(items []model.SecondLevelItem) []model.SecondLevelItem {
+	if items == nil {
+		return nil
+	}
+
+	sorted := make([]model.SecondLevelItem, len(items))
+	copy(sorted, items)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].ID < sorted[j].ID
+	})
+	return sorted
+}
 func (s *Service) processDocumentsParallel(ctx context.Context, documents []model.Document) ([]model.Document, error) {
 	if len(documents) == 0 {
 		return documents, nil
@@ -157,21 +878,18 @@ func (s *Service) processDocumentsParallel(ctx context.Context, documents []mode
 	type result struct {
 		index int
 		doc   *model.Document
+		err   error
 	}
 
 	results := make(chan result, len(documents))
 	var wg sync.WaitGroup
 
+dispatch:
 	for i, doc := range documents {
-		if ctx.Err() != nil {
-			break
-		}
-
 		select {
 		case sem <- struct{}{}:
-
 		case <-ctx.Done():
-			break
+			break dispatch
 		}
 
 		wg.Add(1)
@@ -180,12 +898,17 @@ func (s *Service) processDocumentsParallel(ctx context.Context, documents []mode
 
 			defer func() { <-sem }()
 
+			s.metrics.activeWorkers.Inc()
+			defer s.metrics.activeWorkers.Dec()
+
 			select {
 			case <-ctx.Done():
 				return
 			default:
-				processed := s.processDocument(&d)
-				results <- result{index: idx, doc: processed}
+				timer := prometheus.NewTimer(s.metrics.duration)
+				processed, err := s.processBounded(ctx, &d)
+				timer.ObserveDuration()
+				results <- result{index: idx, doc: processed, err: err}
 			}
 		}(i, doc)
 	}
@@ -199,6 +922,9 @@ func (s *Service) processDocumentsParallel(ctx context.Context, documents []mode
 
 	processedMap := make(map[int]*model.Document)
 	for r := range results {
+		if r.err != nil {
+			return nil, r.err
+		}
 		processedMap[r.index] = r.doc
 	}
 
@@ -214,6 +940,137 @@ func (s *Service) processDocumentsParallel(ctx context.Context, documents []mode
 	return processed, nil
 }
 
+// processBounded runs processRecovered with a context limited to
+// processTimeout. A processor that overruns it is abandoned and the document
+// fails with the context error; processors doing I/O should honour ctx so
+// they stop as well.
+func (s *Service) processBounded(ctx context.Context, doc *model.Document) (*model.Document, error) {
+	if s.processTimeout <= 0 {
+		return s.processRecovered(ctx, doc)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.processTimeout)
+	defer cancel()
+
+	type outcome struct {
+		doc *model.Document
+		err error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		processed, err := s.processRecovered(ctx, doc)
+		done <- outcome{doc: processed, err: err}
+	}()
+
+	select {
+	case out := <-done:
+		return out.doc, out.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("processing document %q: %w", doc.ID, ctx.Err())
+	}
+}
+
+// processRecovered runs s.process and turns a panic into an error. Worker
+// goroutines are outside the HTTP recoverer, so an unrecovered panic there
+// would take down the whole process.
+func (s *Service) processRecovered(ctx context.Context, doc *model.Document) (processed *model.Document, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			slog.Error("Panic while processing document",
+				"id", doc.ID,
+				"panic", rec,
+				"stack", string(debug.Stack()),
+			)
+			err = fmt.Errorf("panic while processing document %q: %v", doc.ID, rec)
+		}
+	}()
+
+	return s.process(ctx, doc), nil
+}
+
+func newDocument(req model.CreateDocumentRequest) *model.Document {
+	now := time.Now()
+	return &model.Document{
+		ID:          generateID(),
+		Title:       req.Title,
+		Description: req.Description,
+		Items:       normalizeItems(req.Items),
+		Tags:        normalizeTags(req.Tags),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+}
+
+func applyUpdate(doc *model.Document, req model.UpdateDocumentRequest) {
+	if req.Title != nil {
+		doc.Title = *req.Title
+	}
+	if req.Description != nil {
+		doc.Description = *req.Description
+	}
+	if req.Items != nil {
+		doc.Items = *req.Items
+	}
+	if req.Tags != nil {
+		doc.Tags = normalizeTags(*req.Tags)
+	}
+	doc.Items = normalizeItems(doc.Items)
+	doc.UpdatedAt = time.Now()
+}
+
+// normalizeItems replaces a nil slice with an empty one so documents always
+// serialize items as [] rather than null, and clears the truncation flags
+// that only served copies may carry.
+func normalizeItems(items []model.FirstLevelItem) []model.FirstLevelItem {
+	if items == nil {
+		return []model.FirstLevelItem{}
+	}
+	for i := range items {
+		items[i].ValueTruncated = false
+		for j := range items[i].SecondLevel {
+			items[i].SecondLevel[j].ContentTruncated = false
+		}
+	}
+	return items
+}
+
+// normalizeTags trims tags and drops empty and repeated ones, keeping the
+// first occurrence of each.
+func normalizeTags(tags []string) []string {
+	var normalized []string
+	seen := make(map[string]struct{}, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if _, ok := seen[tag]; ok || tag == "" {
+			continue
+		}
+		seen[tag] = struct{}{}
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+func cloneItems(items []model.FirstLevelItem) []model.FirstLevelItem {
+	if items == nil {
+		return []model.FirstLevelItem{}
+	}
+
+	cloned := make([]model.FirstLevelItem, len(items))
+	for i, item := range items {
+		cloned[i] = item
+		cloned[i].ID = generateID()
+
+		if item.SecondLevel != nil {
+			cloned[i].SecondLevel = make([]model.SecondLevelItem, len(item.SecondLevel))
+			for j, sub := range item.SecondLevel {
+				cloned[i].SecondLevel[j] = sub
+				cloned[i].SecondLevel[j].ID = generateID()
+			}
+		}
+	}
+	return cloned
+}
+
 func generateID() string {
 	return uuid.NewString()
 }
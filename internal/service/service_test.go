@@ -2,10 +2,25 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/fedorovmatvey/involta-test/internal/apperror"
+	"github.com/fedorovmatvey/involta-test/internal/features"
 	"github.com/fedorovmatvey/involta-test/internal/model"
+	"github.com/fedorovmatvey/involta-test/internal/storage"
+	"github.com/fedorovmatvey/involta-test/internal/testutil"
+	"github.com/prometheus/client_golang/prometheus"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type MockStorage struct{}
@@ -14,9 +29,24 @@ func (m *MockStorage) Create(ctx context.Context, doc *model.Document) error { r
 func (m *MockStorage) GetByID(ctx context.Context, id string) (*model.Document, error) {
 	return nil, nil
 }
+func (m *MockStorage) GetByIDs(ctx context.Context, ids []string) ([]model.Document, error) {
+	return nil, nil
+}
+func (m *MockStorage) GetMeta(ctx context.Context, id string) (*model.DocumentMeta, error) {
+	return nil, nil
+}
 func (m *MockStorage) Update(ctx context.Context, doc *model.Document) error { return nil }
 func (m *MockStorage) Delete(ctx context.Context, id string) error           { return nil }
 func (m *MockStorage) CheckConnection(ctx context.Context) error             { return nil }
+func (m *MockStorage) DeleteByIDs(ctx context.Context, ids []string) ([]string, error) {
+	return nil, nil
+}
+func (m *MockStorage) UpdateWhere(ctx context.Context, filter, set map[string]string) ([]string, error) {
+	return nil, nil
+}
+func (m *MockStorage) WithTransaction(ctx context.Context, fn func(tx storage.TxStore) error) error {
+	return fn(m)
+}
 
 func (m *MockStorage) List(ctx context.Context, params model.PaginationParams) ([]model.Document, int, error) {
 	docs := []model.Document{
@@ -65,3 +95,1320 @@ func TestService_List_ConcurrencyAndSort(t *testing.T) {
 	assert.Equal(t, 99, list.Documents[1].Items[0].Sort)
 	assert.Equal(t, 1, list.Documents[1].Items[1].Sort)
 }
+
+// MemoryStorage keeps documents in memory and stages transactional writes so
+// that a failed transaction leaves the stored set untouched.
+type MemoryStorage struct {
+	MockStorage
+	docs   map[string]*model.Document
+	failOn string
+}
+
+var _ storage.DocumentStore = (*MemoryStorage)(nil)
+
+func NewMemoryStorage(failOn string) *MemoryStorage {
+	return &MemoryStorage{docs: make(map[string]*model.Document), failOn: failOn}
+}
+
+func (m *MemoryStorage) Create(ctx context.Context, doc *model.Document) error {
+	if doc.Title == m.failOn {
+		return errors.New("insert failed")
+	}
+	m.docs[doc.ID] = doc
+	return nil
+}
+
+func (m *MemoryStorage) Update(ctx context.Context, doc *model.Document) error {
+	if doc.Title == m.failOn {
+		return errors.New("update failed")
+	}
+	m.docs[doc.ID] = doc
+	return nil
+}
+
+func (m *MemoryStorage) GetByID(ctx context.Context, id string) (*model.Document, error) {
+	doc, ok := m.docs[id]
+	if !ok {
+		return nil, apperror.NotFound(apperror.ResourceDocument, id)
+	}
+	return doc, nil
+}
+
+func (m *MemoryStorage) GetMeta(ctx context.Context, id string) (*model.DocumentMeta, error) {
+	doc, ok := m.docs[id]
+	if !ok {
+		return nil, apperror.NotFound(apperror.ResourceDocument, id)
+	}
+	return &model.DocumentMeta{ID: doc.ID, UpdatedAt: doc.UpdatedAt}, nil
+}
+
+func (m *MemoryStorage) DeleteByIDs(ctx context.Context, ids []string) ([]string, error) {
+	var deleted []string
+	for _, id := range ids {
+		if _, ok := m.docs[id]; ok {
+			delete(m.docs, id)
+			deleted = append(deleted, id)
+		}
+	}
+	return deleted, nil
+}
+
+func (m *MemoryStorage) GetByIDs(ctx context.Context, ids []string) ([]model.Document, error) {
+	var docs []model.Document
+	for _, id := range ids {
+		if doc, ok := m.docs[id]; ok {
+			docs = append(docs, *doc)
+		}
+	}
+	return docs, nil
+}
+
+// UpdateWhere supports title equality filters and title/description changes.
+func (m *MemoryStorage) UpdateWhere(ctx context.Context, filter, set map[string]string) ([]string, error) {
+	var ids []string
+	for id, doc := range m.docs {
+		if doc.Title != filter["title"] {
+			continue
+		}
+		if v, ok := set["title"]; ok {
+			doc.Title = v
+		}
+		if v, ok := set["description"]; ok {
+			doc.Description = v
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (m *MemoryStorage) WithTransaction(ctx context.Context, fn func(tx storage.TxStore) error) error {
+	staged := NewMemoryStorage(m.failOn)
+	if err := fn(staged); err != nil {
+		return err
+	}
+	for id, doc := range staged.docs {
+		m.docs[id] = doc
+	}
+	return nil
+}
+
+func TestService_CreateBatch_AtomicRollback(t *testing.T) {
+	store := NewMemoryStorage("broken")
+	srv := New(store, &MockCache{})
+
+	_, err := srv.CreateBatch(context.Background(), model.BatchCreateRequest{
+		Documents: []model.CreateDocumentRequest{{Title: "ok"}, {Title: "broken"}},
+		Atomic:    true,
+	})
+
+	assert.Error(t, err)
+	assert.Empty(t, store.docs)
+}
+
+func TestService_CreateBatch_NonAtomicPartial(t *testing.T) {
+	store := NewMemoryStorage("broken")
+	srv := New(store, &MockCache{})
+
+	_, err := srv.CreateBatch(context.Background(), model.BatchCreateRequest{
+		Documents: []model.CreateDocumentRequest{{Title: "ok"}, {Title: "broken"}},
+	})
+
+	assert.Error(t, err)
+	assert.Len(t, store.docs, 1)
+}
+
+func TestService_UpdateBatch_NonAtomicPartialEvicts(t *testing.T) {
+	store := NewMemoryStorage("broken")
+	store.docs["a"] = &model.Document{ID: "a", Title: "a"}
+	store.docs["b"] = &model.Document{ID: "b", Title: "b"}
+	cache := NewRecordingCache()
+	cache.Set("a", &model.Document{ID: "a", Title: "a"})
+	cache.Set("b", &model.Document{ID: "b", Title: "b"})
+	srv := New(store, cache)
+
+	renamed, broken := "renamed", "broken"
+	_, err := srv.UpdateBatch(context.Background(), model.BatchUpdateRequest{Documents: []model.BatchUpdateItem{
+		{ID: "a", UpdateDocumentRequest: model.UpdateDocumentRequest{Title: &renamed}},
+		{ID: "b", UpdateDocumentRequest: model.UpdateDocumentRequest{Title: &broken}},
+	}})
+
+	assert.Error(t, err)
+	assert.Equal(t, "renamed", store.docs["a"].Title)
+	assert.NotContains(t, cache.docs, "a", "a written document must not stay cached with its old content")
+	assert.NotContains(t, cache.docs, "b")
+}
+
+func TestService_CreateBatch_AtomicCommit(t *testing.T) {
+	store := NewMemoryStorage("")
+	srv := New(store, &MockCache{})
+
+	docs, err := srv.CreateBatch(context.Background(), model.BatchCreateRequest{
+		Documents: []model.CreateDocumentRequest{{Title: "a"}, {Title: "b"}},
+		Atomic:    true,
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, docs, 2)
+	assert.Len(t, store.docs, 2)
+}
+
+// ConcurrentStorage is a MemoryStorage safe for parallel Create calls that
+// records how many ran at once.
+type ConcurrentStorage struct {
+	*MemoryStorage
+	mu       sync.Mutex
+	inFlight int
+	peak     int
+}
+
+func (m *ConcurrentStorage) Create(ctx context.Context, doc *model.Document) error {
+	m.mu.Lock()
+	m.inFlight++
+	m.peak = max(m.peak, m.inFlight)
+	m.mu.Unlock()
+
+	time.Sleep(time.Millisecond)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inFlight--
+	return m.MemoryStorage.Create(ctx, doc)
+}
+
+func TestService_CreateBatch_Concurrent(t *testing.T) {
+	const n, limit = 200, 8
+	store := &ConcurrentStorage{MemoryStorage: NewMemoryStorage("broken")}
+	srv := New(store, &MockCache{}, WithBatchConcurrency(limit))
+
+	req := model.BatchCreateRequest{}
+	for i := 0; i < n; i++ {
+		req.Documents = append(req.Documents, model.CreateDocumentRequest{Title: strconv.Itoa(i)})
+	}
+	docs, err := srv.CreateBatch(context.Background(), req)
+
+	assert.NoError(t, err)
+	assert.Len(t, store.docs, n)
+	assert.Len(t, docs, n)
+	for i, doc := range docs {
+		assert.Equal(t, strconv.Itoa(i), doc.Title)
+	}
+	assert.Greater(t, store.peak, 1)
+	assert.LessOrEqual(t, store.peak, limit)
+}
+
+func TestService_CreateBatch_ConcurrentFailure(t *testing.T) {
+	store := &ConcurrentStorage{MemoryStorage: NewMemoryStorage("broken")}
+	srv := New(store, &MockCache{}, WithBatchConcurrency(4))
+
+	_, err := srv.CreateBatch(context.Background(), model.BatchCreateRequest{
+		Documents: []model.CreateDocumentRequest{{Title: "a"}, {Title: "broken"}, {Title: "c"}},
+	})
+
+	assert.ErrorContains(t, err, "insert failed")
+}
+
+// BlockingStorage holds every List call until release is closed.
+type BlockingStorage struct {
+	MockStorage
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (m *BlockingStorage) List(ctx context.Context, params model.PaginationParams) ([]model.Document, int, error) {
+	m.calls.Add(1)
+	<-m.release
+	return m.MockStorage.List(ctx, params)
+}
+
+func TestService_List_DeduplicatesInFlight(t *testing.T) {
+	store := &BlockingStorage{release: make(chan struct{})}
+	srv := New(store, &MockCache{})
+
+	const callers = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := srv.List(context.Background(), model.PaginationParams{Page: 1, PerPage: 10})
+			errs <- err
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(store.release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), store.calls.Load())
+}
+
+func TestService_List_CallerCancelDoesNotAbortShared(t *testing.T) {
+	store := &BlockingStorage{release: make(chan struct{})}
+	srv := New(store, &MockCache{})
+	params := model.PaginationParams{Page: 1, PerPage: 10}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelledErr := make(chan error, 1)
+	go func() {
+		_, err := srv.List(ctx, params)
+		cancelledErr <- err
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+
+	result := make(chan *model.DocumentList, 1)
+	go func() {
+		list, _ := srv.List(context.Background(), params)
+		result <- list
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	assert.ErrorIs(t, <-cancelledErr, context.Canceled)
+
+	close(store.release)
+	list := <-result
+	assert.NotNil(t, list)
+	assert.Len(t, list.Documents, 2)
+	assert.Equal(t, int32(1), store.calls.Load())
+}
+
+func TestService_Clone(t *testing.T) {
+	store := NewMemoryStorage("")
+	source := &model.Document{
+		ID:    "src",
+		Title: "original",
+		Items: []model.FirstLevelItem{
+			{ID: "item-1", Name: "first", Sort: 2, SecondLevel: []model.SecondLevelItem{
+				{ID: "sub-1", Content: "a"},
+				{ID: "sub-2", Content: "b"},
+			}},
+			{ID: "item-2", Name: "second", Sort: 1},
+		},
+	}
+	store.docs[source.ID] = source
+	srv := New(store, &MockCache{})
+
+	title := "copy"
+	clone, err := srv.Clone(context.Background(), "src", model.CloneDocumentRequest{Title: &title})
+
+	assert.NoError(t, err)
+	assert.NotEqual(t, source.ID, clone.ID)
+	assert.Equal(t, "copy", clone.Title)
+	assert.Contains(t, store.docs, clone.ID)
+
+	assert.Len(t, clone.Items, 2)
+	for i, item := range clone.Items {
+		assert.NotEqual(t, source.Items[i].ID, item.ID)
+		assert.Equal(t, source.Items[i].Name, item.Name)
+		assert.Equal(t, source.Items[i].Sort, item.Sort)
+		assert.Len(t, item.SecondLevel, len(source.Items[i].SecondLevel))
+		for j, sub := range item.SecondLevel {
+			assert.NotEqual(t, source.Items[i].SecondLevel[j].ID, sub.ID)
+			assert.Equal(t, source.Items[i].SecondLevel[j].Content, sub.Content)
+		}
+	}
+
+	assert.Equal(t, "item-1", source.Items[0].ID)
+	assert.Equal(t, "sub-1", source.Items[0].SecondLevel[0].ID)
+}
+
+func TestService_GetByID_SecondLevelSortFlag(t *testing.T) {
+	store := NewMemoryStorage("")
+	store.docs["doc-1"] = &model.Document{
+		ID: "doc-1",
+		Items: []model.FirstLevelItem{{ID: "item-1", SecondLevel: []model.SecondLevelItem{
+			{ID: "c"}, {ID: "a"}, {ID: "b"},
+		}}},
+	}
+	srv := New(store, &MockCache{})
+
+	plain, err := srv.GetByID(context.Background(), "doc-1", false)
+	assert.NoError(t, err)
+	assert.Equal(t, "c", plain.Items[0].SecondLevel[0].ID)
+
+	ctx := features.WithContext(context.Background(), features.Parse(features.SortSecondLevel))
+	flagged, err := srv.GetByID(ctx, "doc-1", false)
+	assert.NoError(t, err)
+	assert.Equal(t, "a", flagged.Items[0].SecondLevel[0].ID)
+	assert.Equal(t, "b", flagged.Items[0].SecondLevel[1].ID)
+	assert.Equal(t, "c", flagged.Items[0].SecondLevel[2].ID)
+
+	assert.Equal(t, "c", store.docs["doc-1"].Items[0].SecondLevel[0].ID)
+}
+
+// RecordingCache stores documents in a map so tests can inspect what was cached.
+type RecordingCache struct {
+	docs map[string]*model.Document
+}
+
+func NewRecordingCache() *RecordingCache {
+	return &RecordingCache{docs: make(map[string]*model.Document)}
+}
+
+func (m *RecordingCache) Get(id string) (*model.Document, bool) {
+	doc, ok := m.docs[id]
+	return doc, ok
+}
+func (m *RecordingCache) Set(id string, doc *model.Document) { m.docs[id] = doc }
+func (m *RecordingCache) Delete(id string)                   { delete(m.docs, id) }
+func (m *RecordingCache) Size() int                          { return len(m.docs) }
+
+func TestService_Warmup_FillsCache(t *testing.T) {
+	cache := NewRecordingCache()
+	srv := New(&MockStorage{}, cache)
+
+	loaded, err := srv.Warmup(context.Background(), 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, loaded)
+
+	assert.Len(t, cache.docs, 2)
+	assert.Equal(t, "doc-1", cache.docs["doc-1"].ID)
+	assert.Equal(t, "doc-2", cache.docs["doc-2"].ID)
+}
+
+// PagingStorage serves full pages of generated documents after delay,
+// tracking how many List calls run at once.
+type PagingStorage struct {
+	MockStorage
+	delay    time.Duration
+	mu       sync.Mutex
+	inFlight int
+	peak     int
+	calls    int
+}
+
+func (m *PagingStorage) List(ctx context.Context, params model.PaginationParams) ([]model.Document, int, error) {
+	m.mu.Lock()
+	m.inFlight++
+	m.calls++
+	m.peak = max(m.peak, m.inFlight)
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		m.inFlight--
+		m.mu.Unlock()
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil, 0, ctx.Err()
+	case <-time.After(m.delay):
+	}
+
+	docs := make([]model.Document, params.PerPage)
+	for i := range docs {
+		docs[i].ID = strconv.Itoa(params.Page) + "-" + strconv.Itoa(i)
+	}
+	return docs, 0, nil
+}
+
+func TestService_Warmup_ConcurrencyLimit(t *testing.T) {
+	store := &PagingStorage{delay: 5 * time.Millisecond}
+	srv := New(store, &MockCache{}, WithWarmup(3, time.Minute))
+
+	loaded, err := srv.Warmup(context.Background(), 950)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 950, loaded)
+	assert.Equal(t, 10, store.calls)
+	assert.LessOrEqual(t, store.peak, 3)
+}
+
+func TestService_Warmup_Timeout(t *testing.T) {
+	store := &PagingStorage{delay: 10 * time.Millisecond}
+	srv := New(store, &MockCache{}, WithWarmup(1, 35*time.Millisecond))
+
+	start := time.Now()
+	loaded, err := srv.Warmup(context.Background(), 100*model.MaxPerPage)
+
+	assert.NoError(t, err, "a timeout keeps what was loaded")
+	assert.Less(t, loaded, 100*model.MaxPerPage)
+	assert.Zero(t, loaded%model.MaxPerPage)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestService_Create_NilItemsSerializeAsEmptyArray(t *testing.T) {
+	srv := New(&MockStorage{}, &MockCache{})
+
+	doc, err := srv.Create(context.Background(), model.CreateDocumentRequest{Title: "no items"})
+	assert.NoError(t, err)
+
+	body, err := json.Marshal(doc)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `"items":[]`)
+
+	processed := srv.processDocument(context.Background(), doc)
+	assert.NotNil(t, processed.Items)
+	assert.Empty(t, processed.Items)
+}
+
+func TestService_Update_NilItemsNormalized(t *testing.T) {
+	store := NewMemoryStorage("")
+	store.docs["doc-1"] = &model.Document{ID: "doc-1"}
+	srv := New(store, &MockCache{})
+
+	title := "renamed"
+	doc, err := srv.Update(context.Background(), "doc-1", model.UpdateDocumentRequest{Title: &title})
+
+	assert.NoError(t, err)
+	assert.NotNil(t, doc.Items)
+	assert.Empty(t, doc.Items)
+}
+
+// FailingUpdateStorage fails every Update with err.
+type FailingUpdateStorage struct {
+	*MemoryStorage
+	err error
+}
+
+func (m *FailingUpdateStorage) Update(ctx context.Context, doc *model.Document) error { return m.err }
+
+func TestService_Update_PropagatesStorageError(t *testing.T) {
+	failure := errors.New("update failed")
+	store := &FailingUpdateStorage{MemoryStorage: NewMemoryStorage(""), err: failure}
+	store.docs["doc-1"] = &model.Document{ID: "doc-1"}
+	srv := New(store, &MockCache{})
+
+	title := "renamed"
+	doc, err := srv.Update(context.Background(), "doc-1", model.UpdateDocumentRequest{Title: &title})
+
+	assert.ErrorIs(t, err, failure)
+	assert.Nil(t, doc)
+}
+
+// SyncStorage filters documents by UpdatedSince, HasStatus and Tag the way
+// the Reindexer query does.
+type SyncStorage struct {
+	MockStorage
+	docs []model.Document
+}
+
+func (m *SyncStorage) List(ctx context.Context, params model.PaginationParams) ([]model.Document, int, error) {
+	var result []model.Document
+	for _, doc := range m.docs {
+		if !params.UpdatedSince.IsZero() && !doc.UpdatedAt.After(params.UpdatedSince) {
+			continue
+		}
+		if params.HasStatus != "" && !hasSecondLevelStatus(doc, params.HasStatus) {
+			continue
+		}
+		if params.Tag != "" && !slices.Contains(doc.Tags, params.Tag) {
+			continue
+		}
+		result = append(result, doc)
+	}
+	return result, len(result), nil
+}
+
+func hasSecondLevelStatus(doc model.Document, status string) bool {
+	for _, item := range doc.Items {
+		for _, sub := range item.SecondLevel {
+			if sub.Status == status {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func TestService_List_UpdatedSince(t *testing.T) {
+	cutoff := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	store := &SyncStorage{docs: []model.Document{
+		{ID: "before", UpdatedAt: cutoff.Add(-time.Hour)},
+		{ID: "after", UpdatedAt: cutoff.Add(time.Hour)},
+	}}
+	srv := New(store, &MockCache{})
+
+	list, err := srv.List(context.Background(), model.PaginationParams{Page: 1, PerPage: 10, UpdatedSince: cutoff})
+	assert.NoError(t, err)
+	assert.Len(t, list.Documents, 1)
+	assert.Equal(t, "after", list.Documents[0].ID)
+
+	all, err := srv.List(context.Background(), model.PaginationParams{Page: 1, PerPage: 10})
+	assert.NoError(t, err)
+	assert.Len(t, all.Documents, 2)
+}
+
+func TestService_Create_NormalizesTags(t *testing.T) {
+	store := NewMemoryStorage("broken")
+	srv := New(store, &MockCache{})
+
+	doc, err := srv.Create(context.Background(), model.CreateDocumentRequest{
+		Title: "tagged",
+		Tags:  []string{" urgent", "finance ", "", "urgent", "  "},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"urgent", "finance"}, doc.Tags)
+	assert.Equal(t, []string{"urgent", "finance"}, store.docs[doc.ID].Tags)
+
+	tags := []string{"archived", "archived "}
+	updated, err := srv.Update(context.Background(), doc.ID, model.UpdateDocumentRequest{Tags: &tags})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"archived"}, updated.Tags)
+
+	title := "renamed"
+	updated, err = srv.Update(context.Background(), doc.ID, model.UpdateDocumentRequest{Title: &title})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"archived"}, updated.Tags, "tags are kept when not set")
+}
+
+func TestService_List_Tag(t *testing.T) {
+	srv := New(&SyncStorage{docs: []model.Document{
+		{ID: "urgent", Tags: []string{"finance", "urgent"}},
+		{ID: "finance", Tags: []string{"finance"}},
+		{ID: "untagged"},
+	}}, &MockCache{})
+
+	list, err := srv.List(context.Background(), model.PaginationParams{Page: 1, PerPage: 10, Tag: "urgent"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, list.Total)
+	assert.Equal(t, "urgent", list.Documents[0].ID)
+
+	list, err = srv.List(context.Background(), model.PaginationParams{Page: 1, PerPage: 10, Tag: "finance"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, list.Total)
+}
+
+func TestService_List_EmptyPage(t *testing.T) {
+	srv := New(&SyncStorage{}, &MockCache{})
+
+	list, err := srv.List(context.Background(), model.PaginationParams{Page: 1, PerPage: 10})
+
+	require.NoError(t, err)
+	assert.NotNil(t, list.Documents)
+	assert.Empty(t, list.Documents)
+}
+
+func TestService_List_HasStatus(t *testing.T) {
+	withStatus := func(id string, statuses ...string) model.Document {
+		item := model.FirstLevelItem{ID: id + "-item"}
+		for _, status := range statuses {
+			item.SecondLevel = append(item.SecondLevel, model.SecondLevelItem{Status: status})
+		}
+		return model.Document{ID: id, Items: []model.FirstLevelItem{{ID: "empty"}, item}}
+	}
+	srv := New(&SyncStorage{docs: []model.Document{
+		withStatus("approved", "draft", "approved"),
+		withStatus("draft", "draft"),
+		withStatus("bare"),
+	}}, &MockCache{})
+
+	list, err := srv.List(context.Background(), model.PaginationParams{Page: 1, PerPage: 10, HasStatus: "approved"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, list.Total)
+	assert.Equal(t, "approved", list.Documents[0].ID)
+
+	list, err = srv.List(context.Background(), model.PaginationParams{Page: 1, PerPage: 10, HasStatus: "rejected"})
+	assert.NoError(t, err)
+	assert.Zero(t, list.Total)
+
+	list, err = srv.List(context.Background(), model.PaginationParams{Page: 1, PerPage: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, list.Total)
+}
+
+func TestService_List_ObservesProcessingMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	srv := New(&MockStorage{}, &MockCache{}, WithMetrics(registry))
+
+	_, err := srv.List(context.Background(), model.PaginationParams{Page: 1, PerPage: 10})
+	assert.NoError(t, err)
+
+	families, err := registry.Gather()
+	assert.NoError(t, err)
+
+	var samples uint64
+	var active float64 = -1
+	for _, family := range families {
+		switch family.GetName() {
+		case "documents_processing_duration_seconds":
+			samples = family.GetMetric()[0].GetHistogram().GetSampleCount()
+		case "documents_processing_active_goroutines":
+			active = family.GetMetric()[0].GetGauge().GetValue()
+		}
+	}
+	assert.GreaterOrEqual(t, samples, uint64(1))
+	assert.Equal(t, float64(0), active)
+}
+
+// CountingStorage counts GetByID calls.
+type CountingStorage struct {
+	MemoryStorage
+	gets int
+}
+
+func (m *CountingStorage) GetByID(ctx context.Context, id string) (*model.Document, error) {
+	m.gets++
+	return m.MemoryStorage.GetByID(ctx, id)
+}
+
+func TestService_GetByID_FreshBypassesCache(t *testing.T) {
+	store := &CountingStorage{MemoryStorage: *NewMemoryStorage("")}
+	store.docs["doc-1"] = &model.Document{ID: "doc-1", Title: "stored"}
+	cache := NewRecordingCache()
+	cache.Set("doc-1", &model.Document{ID: "doc-1", Title: "cached"})
+	srv := New(store, cache)
+
+	doc, err := srv.GetByID(context.Background(), "doc-1", false)
+	assert.NoError(t, err)
+	assert.Equal(t, "cached", doc.Title)
+	assert.Equal(t, 0, store.gets)
+
+	doc, err = srv.GetByID(context.Background(), "doc-1", true)
+	assert.NoError(t, err)
+	assert.Equal(t, "stored", doc.Title)
+	assert.Equal(t, 1, store.gets)
+	assert.Equal(t, "stored", cache.docs["doc-1"].Title)
+}
+
+func TestService_ItemCount_SingleAndParallel(t *testing.T) {
+	doc := &model.Document{
+		ID: "doc-1",
+		Items: []model.FirstLevelItem{
+			{ID: "item-1", SecondLevel: []model.SecondLevelItem{{ID: "a"}, {ID: "b"}}},
+			{ID: "item-2", SecondLevel: []model.SecondLevelItem{{ID: "c"}}},
+			{ID: "item-3"},
+		},
+	}
+	store := NewMemoryStorage("")
+	store.docs[doc.ID] = doc
+	srv := New(store, &MockCache{})
+
+	single, err := srv.GetByID(context.Background(), "doc-1", false)
+	assert.NoError(t, err)
+	assert.Equal(t, 6, single.ItemCount)
+
+	parallel, err := srv.processDocumentsParallel(context.Background(), []model.Document{*doc})
+	assert.NoError(t, err)
+	assert.Equal(t, 6, parallel[0].ItemCount)
+
+	assert.Equal(t, 0, doc.ItemCount)
+}
+
+func TestService_UpdateWhere(t *testing.T) {
+	store := NewMemoryStorage("")
+	store.docs["a"] = &model.Document{ID: "a", Title: "draft"}
+	store.docs["b"] = &model.Document{ID: "b", Title: "draft"}
+	store.docs["c"] = &model.Document{ID: "c", Title: "final"}
+	cache := NewRecordingCache()
+	for id, doc := range store.docs {
+		cache.Set(id, doc)
+	}
+	srv := New(store, cache)
+
+	result, err := srv.UpdateWhere(context.Background(), model.UpdateWhereRequest{
+		Filter: map[string]string{"title": "draft"},
+		Set:    map[string]string{"description": "reviewed"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.Updated)
+	assert.Equal(t, "reviewed", store.docs["a"].Description)
+	assert.Equal(t, "reviewed", store.docs["b"].Description)
+	assert.Empty(t, store.docs["c"].Description)
+
+	assert.NotContains(t, cache.docs, "a")
+	assert.NotContains(t, cache.docs, "b")
+	assert.Contains(t, cache.docs, "c")
+}
+
+func TestService_List_EdgePagination(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  model.PaginationParams
+		page    int
+		perPage int
+	}{
+		{"zero", model.PaginationParams{Page: 0, PerPage: 0}, 1, 10},
+		{"negative", model.PaginationParams{Page: -3, PerPage: -50}, 1, 10},
+		{"min int", model.PaginationParams{Page: math.MinInt, PerPage: math.MinInt}, 1, 10},
+		{"very large", model.PaginationParams{Page: math.MaxInt, PerPage: math.MaxInt}, math.MaxInt, 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := New(&MockStorage{}, &MockCache{})
+
+			list, err := srv.List(context.Background(), tt.params)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.page, list.Page)
+			assert.Equal(t, tt.perPage, list.PerPage)
+			assert.Equal(t, 1, list.TotalPages)
+		})
+	}
+}
+
+// TotalStorage lists no documents but reports total matches.
+type TotalStorage struct {
+	MockStorage
+	total int
+}
+
+func (m *TotalStorage) List(ctx context.Context, params model.PaginationParams) ([]model.Document, int, error) {
+	return nil, m.total, nil
+}
+
+func TestService_List_Timings(t *testing.T) {
+	list, err := New(&MockStorage{}, &MockCache{}).List(context.Background(), model.PaginationParams{})
+	assert.NoError(t, err)
+	assert.Nil(t, list.Timings)
+
+	list, err = New(&MockStorage{}, &MockCache{}, WithListTimings(true)).List(context.Background(), model.PaginationParams{})
+	assert.NoError(t, err)
+	if assert.NotNil(t, list.Timings) {
+		assert.Positive(t, list.Timings.StorageMs)
+		assert.Positive(t, list.Timings.ProcessMs)
+	}
+}
+
+func TestService_List_NavigationFlags(t *testing.T) {
+	tests := []struct {
+		page             int
+		hasNext, hasPrev bool
+	}{
+		{1, true, false},
+		{2, true, true},
+		{3, false, true},
+	}
+
+	srv := New(&TotalStorage{total: 25}, &MockCache{})
+	for _, tt := range tests {
+		list, err := srv.List(context.Background(), model.PaginationParams{Page: tt.page, PerPage: 10})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3, list.TotalPages)
+		assert.Equal(t, tt.hasNext, list.HasNext, "page %d", tt.page)
+		assert.Equal(t, tt.hasPrev, list.HasPrev, "page %d", tt.page)
+	}
+
+	list, err := srv.List(context.Background(), model.PaginationParams{Page: 1, PerPage: 100})
+	assert.NoError(t, err)
+	assert.False(t, list.HasNext)
+	assert.False(t, list.HasPrev)
+}
+
+func TestTotalPages(t *testing.T) {
+	assert.Equal(t, 0, totalPages(10, 0))
+	assert.Equal(t, 0, totalPages(10, -1))
+	assert.Equal(t, 0, totalPages(0, 10))
+	assert.Equal(t, 1, totalPages(10, 10))
+	assert.Equal(t, 2, totalPages(11, 10))
+}
+
+func TestPaginationParams_GetOffset_Clamped(t *testing.T) {
+	p := model.PaginationParams{Page: math.MaxInt, PerPage: 100}
+	assert.Equal(t, math.MaxInt32, p.GetOffset())
+
+	p = model.PaginationParams{Page: 3, PerPage: 10}
+	assert.Equal(t, 20, p.GetOffset())
+}
+
+func TestService_List_RecoversProcessingPanic(t *testing.T) {
+	store := &SyncStorage{docs: []model.Document{{ID: "ok"}, {ID: "bad"}}}
+	srv := New(store, &MockCache{})
+	srv.process = func(ctx context.Context, doc *model.Document) *model.Document {
+		if doc.ID == "bad" {
+			panic("corrupted document")
+		}
+		return srv.processDocument(ctx, doc)
+	}
+
+	list, err := srv.List(context.Background(), model.PaginationParams{Page: 1, PerPage: 10})
+
+	assert.Nil(t, list)
+	assert.ErrorContains(t, err, `panic while processing document "bad"`)
+}
+
+func TestService_New_AcceptsDocumentStore(t *testing.T) {
+	var store storage.DocumentStore = NewMemoryStorage("")
+	srv := New(store, &MockCache{})
+
+	created, err := srv.Create(context.Background(), model.CreateDocumentRequest{Title: "external"})
+	assert.NoError(t, err)
+
+	got, err := srv.GetByID(context.Background(), created.ID, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "external", got.Title)
+}
+
+type DownStorage struct {
+	MockStorage
+}
+
+func (m *DownStorage) CheckConnection(ctx context.Context) error {
+	return errors.New("connection refused")
+}
+
+func TestService_Health_AllHealthy(t *testing.T) {
+	cache := NewRecordingCache()
+	cache.Set("doc-1", &model.Document{ID: "doc-1"})
+	srv := New(&MockStorage{}, cache)
+
+	health := srv.Health(context.Background())
+
+	assert.Equal(t, model.HealthReady, health.Status)
+	assert.Equal(t, model.HealthOK, health.Storage)
+	assert.Equal(t, model.HealthOK, health.Cache.Status)
+	assert.Equal(t, 1, *health.Cache.Size)
+}
+
+func TestService_Health_StorageDown(t *testing.T) {
+	srv := New(&DownStorage{}, &MockCache{})
+
+	health := srv.Health(context.Background())
+
+	assert.Equal(t, model.HealthUnavailable, health.Status)
+	assert.Equal(t, model.HealthDown, health.Storage)
+	assert.Nil(t, health.Cache.Size)
+}
+
+func TestService_Preload_FillsCache(t *testing.T) {
+	store := NewMemoryStorage("")
+	store.docs["a"] = &model.Document{ID: "a"}
+	store.docs["b"] = &model.Document{ID: "b"}
+	cache := NewRecordingCache()
+	srv := New(store, cache)
+
+	result, err := srv.Preload(context.Background(), model.PreloadRequest{IDs: []string{"a", "b", "a", "missing"}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &model.PreloadResult{Loaded: 2, Missing: 1}, result)
+	assert.Len(t, cache.docs, 2)
+	assert.Equal(t, "a", cache.docs["a"].ID)
+	assert.Equal(t, "b", cache.docs["b"].ID)
+}
+
+func TestService_Preload_RequiresIDs(t *testing.T) {
+	srv := New(NewMemoryStorage(""), NewRecordingCache())
+
+	_, err := srv.Preload(context.Background(), model.PreloadRequest{})
+
+	var invalid *apperror.InvalidParameterError
+	assert.ErrorAs(t, err, &invalid)
+}
+
+func TestService_Stream_FallsBackToList(t *testing.T) {
+	srv := New(&MockStorage{}, &MockCache{})
+
+	var ids []string
+	err := srv.Stream(context.Background(), model.PaginationParams{Page: 1, PerPage: 10}, func(doc *model.Document) error {
+		ids = append(ids, doc.ID)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"doc-1", "doc-2"}, ids)
+}
+
+func TestService_CreateWithID_New(t *testing.T) {
+	store := NewMemoryStorage("")
+	srv := New(store, &MockCache{})
+
+	doc, err := srv.CreateWithID(context.Background(), "client-1", model.CreateDocumentRequest{Title: "imported"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "client-1", doc.ID)
+	assert.Equal(t, "imported", store.docs["client-1"].Title)
+}
+
+func TestService_CreateWithID_Exists(t *testing.T) {
+	store := NewMemoryStorage("")
+	store.docs["client-1"] = &model.Document{ID: "client-1", Title: "original"}
+	srv := New(store, &MockCache{})
+
+	_, err := srv.CreateWithID(context.Background(), "client-1", model.CreateDocumentRequest{Title: "imported"})
+
+	var conflict *apperror.ConflictError
+	assert.ErrorAs(t, err, &conflict)
+	assert.Equal(t, "original", store.docs["client-1"].Title)
+}
+
+func TestService_GetByID_CountsServedSource(t *testing.T) {
+	store := NewMemoryStorage("")
+	store.docs["doc-1"] = &model.Document{ID: "doc-1"}
+	srv := New(store, NewRecordingCache())
+
+	_, err := srv.GetByID(context.Background(), "doc-1", false)
+	assert.NoError(t, err)
+	_, err = srv.GetByID(context.Background(), "doc-1", false)
+	assert.NoError(t, err)
+	_, err = srv.GetByID(context.Background(), "doc-1", false)
+	assert.NoError(t, err)
+
+	assert.Equal(t, float64(2), promtestutil.ToFloat64(srv.metrics.served.WithLabelValues(sourceCache)))
+	assert.Equal(t, float64(1), promtestutil.ToFloat64(srv.metrics.served.WithLabelValues(sourceStorage)))
+}
+
+type FailingStorage struct {
+	MockStorage
+	err error
+}
+
+func (m *FailingStorage) GetByID(ctx context.Context, id string) (*model.Document, error) {
+	return nil, m.err
+}
+
+// StaleCache misses on Get but still holds expired entries.
+type StaleCache struct {
+	MockCache
+	docs map[string]*model.Document
+}
+
+func (m *StaleCache) GetStale(id string) (*model.Document, bool) {
+	doc, ok := m.docs[id]
+	return doc, ok
+}
+
+func TestService_GetByID_ServesStaleOnStorageError(t *testing.T) {
+	cache := &StaleCache{docs: map[string]*model.Document{"doc-1": {ID: "doc-1", Title: "cached"}}}
+	srv := New(&FailingStorage{err: errors.New("connection refused")}, cache)
+
+	doc, err := srv.GetByID(context.Background(), "doc-1", false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "cached", doc.Title)
+	assert.True(t, doc.Stale)
+	assert.False(t, cache.docs["doc-1"].Stale)
+}
+
+func TestService_GetByID_StaleMetaInBody(t *testing.T) {
+	cache := &StaleCache{docs: map[string]*model.Document{"doc-1": {ID: "doc-1"}}}
+
+	doc, err := New(&FailingStorage{err: errors.New("connection refused")}, cache).GetByID(context.Background(), "doc-1", false)
+	assert.NoError(t, err)
+	body, err := json.Marshal(doc)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `"meta":{"stale":true}`)
+	assert.Nil(t, cache.docs["doc-1"].Meta)
+
+	store := NewMemoryStorage("broken")
+	store.docs["doc-1"] = &model.Document{ID: "doc-1"}
+	doc, err = New(store, cache).GetByID(context.Background(), "doc-1", false)
+	assert.NoError(t, err)
+	body, err = json.Marshal(doc)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(body), `"meta"`)
+}
+
+func TestService_GetByID_NoStaleFallback(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		id   string
+	}{
+		{"not found", apperror.NotFound(apperror.ResourceDocument, "doc-1"), "doc-1"},
+		{"not cached", errors.New("connection refused"), "doc-2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := &StaleCache{docs: map[string]*model.Document{"doc-1": {ID: "doc-1"}}}
+			srv := New(&FailingStorage{err: tt.err}, cache)
+
+			_, err := srv.GetByID(context.Background(), tt.id, false)
+
+			assert.Error(t, err)
+		})
+	}
+}
+
+// AgedCache reports every entry as being age old.
+type AgedCache struct {
+	*RecordingCache
+	age time.Duration
+}
+
+func (m *AgedCache) GetWithAge(id string) (*model.Document, time.Duration, bool) {
+	doc, ok := m.Get(id)
+	return doc, m.age, ok
+}
+
+func TestService_GetByID_RevalidatesOldEntries(t *testing.T) {
+	cachedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		age       time.Duration
+		updatedAt time.Time
+		title     string
+	}{
+		{"young entry is not checked", time.Second, cachedAt.Add(time.Hour), "cached"},
+		{"old entry still current", time.Hour, cachedAt, "cached"},
+		{"old entry updated in storage", time.Hour, cachedAt.Add(time.Hour), "updated"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStorage("")
+			store.docs["doc-1"] = &model.Document{ID: "doc-1", Title: "updated", UpdatedAt: tt.updatedAt}
+			cache := &AgedCache{RecordingCache: NewRecordingCache(), age: tt.age}
+			cache.Set("doc-1", &model.Document{ID: "doc-1", Title: "cached", UpdatedAt: cachedAt})
+			srv := New(store, cache, WithRevalidation(time.Minute))
+
+			doc, err := srv.GetByID(context.Background(), "doc-1", false)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.title, doc.Title)
+			assert.Equal(t, tt.title, cache.docs["doc-1"].Title)
+		})
+	}
+}
+
+func TestService_GetByID_RevalidationDropsDeleted(t *testing.T) {
+	cache := &AgedCache{RecordingCache: NewRecordingCache(), age: time.Hour}
+	cache.Set("doc-1", &model.Document{ID: "doc-1"})
+	srv := New(NewMemoryStorage(""), cache, WithRevalidation(time.Minute))
+
+	_, err := srv.GetByID(context.Background(), "doc-1", false)
+
+	assert.Error(t, err)
+	assert.NotContains(t, cache.docs, "doc-1")
+}
+
+func TestService_ListItems_Pagination(t *testing.T) {
+	store := NewMemoryStorage("")
+	store.docs["doc-1"] = &model.Document{ID: "doc-1", Items: []model.FirstLevelItem{
+		{ID: "a", Sort: 1}, {ID: "b", Sort: 5}, {ID: "c", Sort: 3}, {ID: "d", Sort: 4}, {ID: "e", Sort: 2},
+	}}
+	srv := New(store, &MockCache{})
+
+	tests := []struct {
+		name    string
+		page    int
+		perPage int
+		ids     []string
+	}{
+		{"first page sorted", 1, 2, []string{"b", "d"}},
+		{"partial last page", 3, 2, []string{"a"}},
+		{"past the end", 4, 2, []string{}},
+		{"defaults", 0, 0, []string{"b", "d", "c", "e", "a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := srv.ListItems(context.Background(), "doc-1", model.PaginationParams{Page: tt.page, PerPage: tt.perPage})
+
+			assert.NoError(t, err)
+			ids := []string{}
+			for _, item := range list.Items {
+				ids = append(ids, item.ID)
+			}
+			assert.Equal(t, tt.ids, ids)
+			assert.Equal(t, 5, list.Total)
+		})
+	}
+
+	list, err := srv.ListItems(context.Background(), "doc-1", model.PaginationParams{Page: 1, PerPage: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, list.TotalPages)
+}
+
+func TestService_ListItems_NotFound(t *testing.T) {
+	srv := New(NewMemoryStorage(""), &MockCache{})
+
+	_, err := srv.ListItems(context.Background(), "missing", model.PaginationParams{})
+
+	assert.Error(t, err)
+}
+
+func TestService_DeleteMany(t *testing.T) {
+	store := NewMemoryStorage("")
+	store.docs["a"] = &model.Document{ID: "a"}
+	store.docs["b"] = &model.Document{ID: "b"}
+	store.docs["c"] = &model.Document{ID: "c"}
+	cache := NewRecordingCache()
+	cache.Set("a", store.docs["a"])
+	cache.Set("c", store.docs["c"])
+	srv := New(store, cache)
+
+	result, err := srv.DeleteMany(context.Background(), model.BatchDeleteRequest{IDs: []string{"a", "missing", "b", "a"}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &model.BatchDeleteResult{Deleted: 2, Missing: []string{"missing"}}, result)
+	assert.Len(t, store.docs, 1)
+	assert.Contains(t, store.docs, "c")
+	assert.NotContains(t, cache.docs, "a")
+	assert.Contains(t, cache.docs, "c")
+}
+
+func TestService_DeleteMany_RequiresIDs(t *testing.T) {
+	srv := New(NewMemoryStorage(""), NewRecordingCache())
+
+	_, err := srv.DeleteMany(context.Background(), model.BatchDeleteRequest{})
+
+	var invalid *apperror.InvalidParameterError
+	assert.ErrorAs(t, err, &invalid)
+}
+
+func TestService_List_RecordsStorageQuery(t *testing.T) {
+	store := testutil.NewRecordingStore(&MockStorage{})
+	srv := New(store, &MockCache{})
+	since := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err := srv.List(context.Background(), model.PaginationParams{Page: 3, PerPage: 500, UpdatedSince: since, SortBy: "title", SortDesc: true})
+	assert.NoError(t, err)
+
+	calls := store.CallsTo("List")
+	if assert.Len(t, calls, 1) {
+		assert.Equal(t, model.PaginationParams{Page: 3, PerPage: 100, UpdatedSince: since, SortBy: "title", SortDesc: true}, calls[0].Args[0])
+	}
+}
+
+func TestService_List_ProcessTimeout(t *testing.T) {
+	store := &SyncStorage{docs: []model.Document{{ID: "fast"}, {ID: "slow"}}}
+	release := make(chan struct{})
+	defer close(release)
+	srv := New(store, &MockCache{}, WithProcessTimeout(20*time.Millisecond))
+	srv.process = func(ctx context.Context, doc *model.Document) *model.Document {
+		if doc.ID == "slow" {
+			select {
+			case <-release:
+			case <-ctx.Done():
+			}
+		}
+		return srv.processDocument(ctx, doc)
+	}
+
+	start := time.Now()
+	list, err := srv.List(context.Background(), model.PaginationParams{Page: 1, PerPage: 10})
+
+	assert.Nil(t, list)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, err, `"slow"`)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestService_ProcessBounded_WithinTimeout(t *testing.T) {
+	srv := New(&MockStorage{}, &MockCache{}, WithProcessTimeout(time.Second))
+
+	doc, err := srv.processBounded(context.Background(), &model.Document{ID: "doc-1"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "doc-1", doc.ID)
+}
+
+func TestService_Upsert(t *testing.T) {
+	store := NewMemoryStorage("")
+	store.docs["doc-1"] = &model.Document{ID: "doc-1", Title: "old", Description: "kept"}
+	srv := New(store, &MockCache{})
+	title := "new"
+
+	doc, created, err := srv.Upsert(context.Background(), "doc-1", model.UpdateDocumentRequest{Title: &title})
+	assert.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, "new", doc.Title)
+	assert.Equal(t, "kept", doc.Description)
+
+	doc, created, err = srv.Upsert(context.Background(), "doc-2", model.UpdateDocumentRequest{Title: &title})
+	assert.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, "doc-2", doc.ID)
+	assert.Equal(t, "new", store.docs["doc-2"].Title)
+	assert.NotNil(t, store.docs["doc-2"].Items)
+}
+
+func TestService_Batch_MaxSize(t *testing.T) {
+	srv := New(NewMemoryStorage(""), NewRecordingCache(), WithLimits(Limits{MaxBatchSize: 2}))
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		field string
+		call  func(n int) error
+	}{
+		{"create", "documents", func(n int) error {
+			_, err := srv.CreateBatch(ctx, model.BatchCreateRequest{Documents: make([]model.CreateDocumentRequest, n)})
+			return err
+		}},
+		{"update", "documents", func(n int) error {
+			items := make([]model.BatchUpdateItem, n)
+			_, err := srv.UpdateBatch(ctx, model.BatchUpdateRequest{Documents: items})
+			return err
+		}},
+		{"delete", "ids", func(n int) error {
+			_, err := srv.DeleteMany(ctx, model.BatchDeleteRequest{IDs: make([]string, n)})
+			return err
+		}},
+		{"preload", "ids", func(n int) error {
+			_, err := srv.Preload(ctx, model.PreloadRequest{IDs: make([]string, n)})
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var validation *apperror.ValidationError
+			if err := tt.call(2); err != nil {
+				assert.False(t, errors.As(err, &validation), "batch at the limit must pass the size check")
+			}
+
+			err := tt.call(3)
+			if assert.ErrorAs(t, err, &validation) {
+				assert.Equal(t, tt.field, validation.Field)
+				assert.Equal(t, 2, validation.Limit)
+			}
+		})
+	}
+}
+
+func TestService_ReadModifyWrite_ReadsPrimary(t *testing.T) {
+	primary, replica := NewMemoryStorage(""), NewMemoryStorage("")
+	primary.docs["doc-1"] = &model.Document{ID: "doc-1", Title: "new", Description: "fresh"}
+	primary.docs["doc-2"] = &model.Document{ID: "doc-2", Title: "created"}
+	replica.docs["doc-1"] = &model.Document{ID: "doc-1", Title: "old", Description: "stale"}
+	srv := New(storage.NewReadSplit(primary, replica), &MockCache{})
+	ctx := context.Background()
+	description := "changed"
+
+	doc, err := srv.Update(ctx, "doc-1", model.UpdateDocumentRequest{Description: &description})
+	require.NoError(t, err)
+	assert.Equal(t, "new", doc.Title)
+
+	_, created, err := srv.Upsert(ctx, "doc-2", model.UpdateDocumentRequest{Description: &description})
+	require.NoError(t, err)
+	assert.False(t, created, "doc-2 exists on the primary")
+
+	docs, err := srv.UpdateBatch(ctx, model.BatchUpdateRequest{Documents: []model.BatchUpdateItem{
+		{ID: "doc-1", UpdateDocumentRequest: model.UpdateDocumentRequest{Description: &description}},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, "new", docs[0].Title)
+
+	title := "new"
+	diff, err := srv.Diff(ctx, "doc-1", model.UpdateDocumentRequest{Title: &title})
+	require.NoError(t, err)
+	assert.Empty(t, diff.Changed, "the primary already has the title")
+}
+
+func TestService_CachedReads_ReadPrimary(t *testing.T) {
+	primary, replica := NewMemoryStorage(""), NewMemoryStorage("")
+	primary.docs["doc-1"] = &model.Document{ID: "doc-1", Title: "new"}
+	replica.docs["doc-1"] = &model.Document{ID: "doc-1", Title: "old"}
+	primary.docs["doc-2"] = &model.Document{ID: "doc-2", Title: "just created"}
+	cache := NewRecordingCache()
+	srv := New(storage.NewReadSplit(primary, replica), cache)
+	ctx := context.Background()
+
+	for _, fresh := range []bool{false, true} {
+		cache.Delete("doc-1")
+		served, err := srv.GetByID(ctx, "doc-1", fresh)
+		require.NoError(t, err)
+		assert.Equal(t, "new", served.Title, "fresh=%v", fresh)
+		assert.Equal(t, "new", cache.docs["doc-1"].Title, "fresh=%v", fresh)
+	}
+
+	result, err := srv.Preload(ctx, model.PreloadRequest{IDs: []string{"doc-2"}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Loaded)
+
+	clone, err := srv.Clone(ctx, "doc-2", model.CloneDocumentRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "just created", clone.Title)
+}
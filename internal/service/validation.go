@@ -0,0 +1,120 @@
+package service
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/fedorovmatvey/involta-test/internal/apperror"
+	"github.com/fedorovmatvey/involta-test/internal/model"
+)
+
+// validateDocument checks doc against the configured limits before it is
+// written to storage.
+func (s *Service) validateDocument(doc *model.Document) error {
+	if err := checkLength("title", doc.Title, s.limits.MaxTitleLength); err != nil {
+		return err
+	}
+	if err := checkLength("description", doc.Description, s.limits.MaxDescriptionLength); err != nil {
+		return err
+	}
+	if err := checkCount("tags", len(doc.Tags), s.limits.MaxTags); err != nil {
+		return err
+	}
+	for i, tag := range doc.Tags {
+		if err := checkLength(fmt.Sprintf("tags[%d]", i), tag, s.limits.MaxTagLength); err != nil {
+			return err
+		}
+	}
+
+	for i, item := range doc.Items {
+		if err := checkLength(fmt.Sprintf("items[%d].name", i), item.Name, s.limits.MaxItemNameLength); err != nil {
+			return err
+		}
+		if err := checkRange(fmt.Sprintf("items[%d].sort", i), item.Sort, s.limits.MaxItemSort); err != nil {
+			return err
+		}
+		if s.limits.TruncateItems {
+			continue
+		}
+		if err := checkLength(fmt.Sprintf("items[%d].value", i), item.Value, s.limits.MaxItemValueLength); err != nil {
+			return err
+		}
+		for j, sub := range item.SecondLevel {
+			field := fmt.Sprintf("items[%d].second_level[%d].content", i, j)
+			if err := checkLength(field, sub.Content, s.limits.MaxItemContentLength); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateSet checks the changes of a bulk update against the same limits as
+// validateDocument.
+func (s *Service) validateSet(set map[string]string) error {
+	if err := checkLength(FieldTitle, set[FieldTitle], s.limits.MaxTitleLength); err != nil {
+		return err
+	}
+	return checkLength(FieldDescription, set[FieldDescription], s.limits.MaxDescriptionLength)
+}
+
+// truncateItems cuts served item values and contents to their limits and
+// flags the ones it shortened. Second-level items are copied before they are
+// changed because they may be shared with a cached document.
+func (s *Service) truncateItems(items []model.FirstLevelItem) {
+	for i := range items {
+		if v, ok := truncate(items[i].Value, s.limits.MaxItemValueLength); ok {
+			items[i].Value, items[i].ValueTruncated = v, true
+		}
+
+		if s.limits.MaxItemContentLength <= 0 || len(items[i].SecondLevel) == 0 {
+			continue
+		}
+		subs := append([]model.SecondLevelItem(nil), items[i].SecondLevel...)
+		for j := range subs {
+			if c, ok := truncate(subs[j].Content, s.limits.MaxItemContentLength); ok {
+				subs[j].Content, subs[j].ContentTruncated = c, true
+			}
+		}
+		items[i].SecondLevel = subs
+	}
+}
+
+// truncate returns the first limit characters of value and whether it was
+// longer than that.
+func truncate(value string, limit int) (string, bool) {
+	if limit <= 0 || utf8.RuneCountInString(value) <= limit {
+		return value, false
+	}
+	runes := 0
+	for i := range value {
+		if runes == limit {
+			return value[:i], true
+		}
+		runes++
+	}
+	return value, false
+}
+
+// checkCount rejects batches with more than limit entries before any of them
+// is processed.
+func checkCount(field string, count, limit int) error {
+	if limit > 0 && count > limit {
+		return apperror.Validation(field, limit, fmt.Sprintf("must contain at most %d entries", limit))
+	}
+	return nil
+}
+
+func checkRange(field string, value, limit int) error {
+	if limit > 0 && (value < 0 || value > limit) {
+		return apperror.Validation(field, limit, fmt.Sprintf("must be between 0 and %d", limit))
+	}
+	return nil
+}
+
+func checkLength(field, value string, limit int) error {
+	if limit > 0 && utf8.RuneCountInString(value) > limit {
+		return apperror.Validation(field, limit, fmt.Sprintf("must be at most %d characters", limit))
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/fedorovmatvey/involta-test/internal/apperror"
+	"github.com/fedorovmatvey/involta-test/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestService_Create_LengthLimits(t *testing.T) {
+	limits := Limits{
+		MaxTitleLength:       5,
+		MaxDescriptionLength: 6,
+		MaxItemNameLength:    3,
+		MaxItemValueLength:   4,
+		MaxItemContentLength: 2,
+		MaxTags:              2,
+		MaxTagLength:         3,
+	}
+
+	tests := []struct {
+		name  string
+		field string
+		limit int
+		ok    model.CreateDocumentRequest
+		bad   model.CreateDocumentRequest
+	}{
+		{
+			name: "title", field: "title", limit: 5,
+			ok:  model.CreateDocumentRequest{Title: strings.Repeat("я", 5)},
+			bad: model.CreateDocumentRequest{Title: strings.Repeat("я", 6)},
+		},
+		{
+			name: "description", field: "description", limit: 6,
+			ok:  model.CreateDocumentRequest{Description: strings.Repeat("d", 6)},
+			bad: model.CreateDocumentRequest{Description: strings.Repeat("d", 7)},
+		},
+		{
+			name: "item name", field: "items[0].name", limit: 3,
+			ok:  model.CreateDocumentRequest{Items: []model.FirstLevelItem{{Name: "abc"}}},
+			bad: model.CreateDocumentRequest{Items: []model.FirstLevelItem{{Name: "abcd"}}},
+		},
+		{
+			name: "item value", field: "items[0].value", limit: 4,
+			ok:  model.CreateDocumentRequest{Items: []model.FirstLevelItem{{Value: "abcd"}}},
+			bad: model.CreateDocumentRequest{Items: []model.FirstLevelItem{{Value: "abcde"}}},
+		},
+		{
+			name: "second level content", field: "items[0].second_level[1].content", limit: 2,
+			ok: model.CreateDocumentRequest{Items: []model.FirstLevelItem{
+				{SecondLevel: []model.SecondLevelItem{{Content: "ok"}, {Content: "жж"}}},
+			}},
+			bad: model.CreateDocumentRequest{Items: []model.FirstLevelItem{
+				{SecondLevel: []model.SecondLevelItem{{Content: "ok"}, {Content: "жжж"}}},
+			}},
+		},
+		{
+			name: "tag count", field: "tags", limit: 2,
+			ok:  model.CreateDocumentRequest{Tags: []string{"a", "b", "a", " b "}},
+			bad: model.CreateDocumentRequest{Tags: []string{"a", "b", "c"}},
+		},
+		{
+			name: "tag length", field: "tags[1]", limit: 3,
+			ok:  model.CreateDocumentRequest{Tags: []string{"abc", "жжж"}},
+			bad: model.CreateDocumentRequest{Tags: []string{"abc", "abcd"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := New(&MockStorage{}, &MockCache{}, WithLimits(limits))
+
+			_, err := srv.Create(context.Background(), tt.ok)
+			assert.NoError(t, err)
+
+			_, err = srv.Create(context.Background(), tt.bad)
+			var validation *apperror.ValidationError
+			assert.ErrorAs(t, err, &validation)
+			assert.Equal(t, tt.field, validation.Field)
+			assert.Equal(t, tt.limit, validation.Limit)
+		})
+	}
+}
+
+func TestService_TruncateItems(t *testing.T) {
+	store := NewMemoryStorage("broken")
+	srv := New(store, &MockCache{}, WithLimits(Limits{
+		MaxItemValueLength:   4,
+		MaxItemContentLength: 2,
+		TruncateItems:        true,
+	}))
+
+	created, err := srv.Create(context.Background(), model.CreateDocumentRequest{Items: []model.FirstLevelItem{
+		{Sort: 2, Value: "abcd", SecondLevel: []model.SecondLevelItem{{Content: "жж"}}},
+		{Sort: 1, Value: "abcdé", SecondLevel: []model.SecondLevelItem{{Content: "жжж"}}},
+	}})
+	assert.NoError(t, err)
+
+	doc, err := srv.GetByID(context.Background(), created.ID, false)
+	assert.NoError(t, err)
+
+	atLimit, over := doc.Items[0], doc.Items[1]
+	assert.Equal(t, "abcd", atLimit.Value)
+	assert.False(t, atLimit.ValueTruncated)
+	assert.Equal(t, "жж", atLimit.SecondLevel[0].Content)
+	assert.False(t, atLimit.SecondLevel[0].ContentTruncated)
+
+	assert.Equal(t, "abcd", over.Value)
+	assert.True(t, over.ValueTruncated)
+	assert.Equal(t, "жж", over.SecondLevel[0].Content)
+	assert.True(t, over.SecondLevel[0].ContentTruncated)
+
+	stored := store.docs[created.ID].Items[1]
+	assert.Equal(t, "abcdé", stored.Value)
+	assert.Equal(t, "жжж", stored.SecondLevel[0].Content)
+}
+
+func TestNormalizeItems_ClearsTruncationFlags(t *testing.T) {
+	items := normalizeItems([]model.FirstLevelItem{{
+		ValueTruncated: true,
+		SecondLevel:    []model.SecondLevelItem{{ContentTruncated: true}},
+	}})
+
+	assert.False(t, items[0].ValueTruncated)
+	assert.False(t, items[0].SecondLevel[0].ContentTruncated)
+}
+
+func TestService_Create_NoLimits(t *testing.T) {
+	srv := New(&MockStorage{}, &MockCache{})
+
+	_, err := srv.Create(context.Background(), model.CreateDocumentRequest{Title: strings.Repeat("t", 10000)})
+
+	assert.NoError(t, err)
+}
+
+func TestService_Create_ItemSortBounds(t *testing.T) {
+	srv := New(&MockStorage{}, &MockCache{}, WithLimits(Limits{MaxItemSort: 100}))
+
+	for sort, valid := range map[int]bool{-1: false, 0: true, 100: true, 101: false} {
+		_, err := srv.Create(context.Background(), model.CreateDocumentRequest{
+			Items: []model.FirstLevelItem{{Sort: 1}, {Sort: sort}},
+		})
+
+		if valid {
+			assert.NoError(t, err, "sort=%d", sort)
+			continue
+		}
+		var validation *apperror.ValidationError
+		if assert.ErrorAs(t, err, &validation, "sort=%d", sort) {
+			assert.Equal(t, "items[1].sort", validation.Field)
+			assert.Equal(t, 100, validation.Limit)
+		}
+	}
+}
+
+func TestService_Update_ItemSortBounds(t *testing.T) {
+	store := NewMemoryStorage("")
+	store.docs["doc-1"] = &model.Document{ID: "doc-1"}
+	srv := New(store, &MockCache{}, WithLimits(Limits{MaxItemSort: 100}))
+
+	items := []model.FirstLevelItem{{Sort: -5}}
+	_, err := srv.Update(context.Background(), "doc-1", model.UpdateDocumentRequest{Items: &items})
+
+	var validation *apperror.ValidationError
+	assert.ErrorAs(t, err, &validation)
+}
+
+func TestService_UpdateWhere_LengthLimits(t *testing.T) {
+	store := NewMemoryStorage("")
+	store.docs["doc-1"] = &model.Document{ID: "doc-1", Title: "draft"}
+	srv := New(store, &MockCache{}, WithLimits(Limits{MaxTitleLength: 5, MaxDescriptionLength: 10}))
+
+	for field, value := range map[string]string{"title": "too long", "description": strings.Repeat("d", 11)} {
+		_, err := srv.UpdateWhere(context.Background(), model.UpdateWhereRequest{
+			Filter: map[string]string{"title": "draft"},
+			Set:    map[string]string{field: value},
+		})
+
+		var validation *apperror.ValidationError
+		if assert.ErrorAs(t, err, &validation, field) {
+			assert.Equal(t, field, validation.Field)
+		}
+	}
+	assert.Equal(t, "draft", store.docs["doc-1"].Title)
+	assert.Empty(t, store.docs["doc-1"].Description)
+}
@@ -0,0 +1,252 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/fedorovmatvey/involta-test/internal/apperror"
+	"github.com/fedorovmatvey/involta-test/internal/model"
+)
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// CircuitBreaker is a DocumentStore decorator that fails fast while storage is
+// down. After threshold consecutive failures it opens and rejects every call
+// with apperror.UnavailableError for cooldown. It then half-opens and lets a
+// single call through: success closes the breaker, failure reopens it.
+//
+// Errors that describe the request rather than storage health, such as not
+// found, conflicts and cancelled contexts, do not count as failures.
+type CircuitBreaker struct {
+	next      DocumentStore
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+var _ DocumentStore = (*CircuitBreaker)(nil)
+
+// NewCircuitBreaker wraps next in a breaker that trips after threshold
+// consecutive failures and stays open for cooldown.
+func NewCircuitBreaker(next DocumentStore, threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		next:      next,
+		threshold: max(threshold, 1),
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// allow reports whether a call may go to storage. In the half-open state only
+// one probe is let through at a time.
+func (b *CircuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		remaining := b.cooldown - b.now().Sub(b.openedAt)
+		if remaining > 0 {
+			return apperror.Unavailable("storage is unavailable", remaining)
+		}
+		b.setState(breakerHalfOpen)
+		fallthrough
+	case breakerHalfOpen:
+		if b.probing {
+			return apperror.Unavailable("storage is unavailable", b.cooldown)
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// record updates the breaker with the outcome of a call let through by allow.
+func (b *CircuitBreaker) record(err error) {
+	failed := isStorageFailure(err)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == breakerHalfOpen {
+		b.probing = false
+		if failed {
+			b.trip()
+		} else {
+			b.failures = 0
+			b.setState(breakerClosed)
+		}
+		return
+	}
+
+	if !failed {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == breakerClosed && b.failures >= b.threshold {
+		b.trip()
+	}
+}
+
+func (b *CircuitBreaker) trip() {
+	b.openedAt = b.now()
+	b.failures = 0
+	b.setState(breakerOpen)
+}
+
+func (b *CircuitBreaker) setState(state breakerState) {
+	if b.state != state {
+		log.Printf("Storage circuit breaker %s -> %s", b.state, state)
+		b.state = state
+	}
+}
+
+// isStorageFailure reports whether err points at an unhealthy storage.
+func isStorageFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var notFound *apperror.NotFoundError
+	var conflict *apperror.ConflictError
+	var validation *apperror.ValidationError
+	var invalid *apperror.InvalidParameterError
+	return !errors.As(err, &notFound) && !errors.As(err, &conflict) &&
+		!errors.As(err, &validation) && !errors.As(err, &invalid)
+}
+
+func (b *CircuitBreaker) do(fn func() error) error {
+	if err := b.allow(); err != nil {
+		return err
+	}
+	err := fn()
+	b.record(err)
+	return err
+}
+
+func (b *CircuitBreaker) Create(ctx context.Context, doc *model.Document) error {
+	return b.do(func() error { return b.next.Create(ctx, doc) })
+}
+
+func (b *CircuitBreaker) Update(ctx context.Context, doc *model.Document) error {
+	return b.do(func() error { return b.next.Update(ctx, doc) })
+}
+
+func (b *CircuitBreaker) GetByID(ctx context.Context, id string) (doc *model.Document, err error) {
+	err = b.do(func() error {
+		doc, err = b.next.GetByID(ctx, id)
+		return err
+	})
+	return doc, err
+}
+
+func (b *CircuitBreaker) GetByIDs(ctx context.Context, ids []string) (docs []model.Document, err error) {
+	err = b.do(func() error {
+		docs, err = b.next.GetByIDs(ctx, ids)
+		return err
+	})
+	return docs, err
+}
+
+func (b *CircuitBreaker) GetMeta(ctx context.Context, id string) (meta *model.DocumentMeta, err error) {
+	err = b.do(func() error {
+		meta, err = b.next.GetMeta(ctx, id)
+		return err
+	})
+	return meta, err
+}
+
+func (b *CircuitBreaker) UpdateWhere(ctx context.Context, filter, set map[string]string) (ids []string, err error) {
+	err = b.do(func() error {
+		ids, err = b.next.UpdateWhere(ctx, filter, set)
+		return err
+	})
+	return ids, err
+}
+
+func (b *CircuitBreaker) Delete(ctx context.Context, id string) error {
+	return b.do(func() error { return b.next.Delete(ctx, id) })
+}
+
+func (b *CircuitBreaker) DeleteByIDs(ctx context.Context, ids []string) (deleted []string, err error) {
+	err = b.do(func() error {
+		deleted, err = b.next.DeleteByIDs(ctx, ids)
+		return err
+	})
+	return deleted, err
+}
+
+func (b *CircuitBreaker) List(ctx context.Context, params model.PaginationParams) (docs []model.Document, total int, err error) {
+	err = b.do(func() error {
+		docs, total, err = b.next.List(ctx, params)
+		return err
+	})
+	return docs, total, err
+}
+
+func (b *CircuitBreaker) CheckConnection(ctx context.Context) error {
+	return b.do(func() error { return b.next.CheckConnection(ctx) })
+}
+
+func (b *CircuitBreaker) WithTransaction(ctx context.Context, fn func(tx TxStore) error) error {
+	return b.do(func() error { return b.next.WithTransaction(ctx, fn) })
+}
+
+// Stream forwards to the wrapped store, falling back to a List of the page
+// when it cannot stream.
+func (b *CircuitBreaker) Stream(ctx context.Context, params model.PaginationParams, fn func(doc *model.Document) error) error {
+	if err := b.allow(); err != nil {
+		return err
+	}
+	// Errors returned by fn come from the consumer, not from storage.
+	var fnErr error
+	err := streamOrList(ctx, b.next, params, func(doc *model.Document) error {
+		fnErr = fn(doc)
+		return fnErr
+	})
+	if fnErr != nil && errors.Is(err, fnErr) {
+		b.record(nil)
+	} else {
+		b.record(err)
+	}
+	return err
+}
+
+// ExplainList forwards to the wrapped store when it can explain queries.
+func (b *CircuitBreaker) ExplainList(ctx context.Context, params model.PaginationParams) (explain *model.QueryExplain, err error) {
+	explainer, ok := b.next.(Explainer)
+	if !ok {
+		return nil, errExplainUnsupported
+	}
+	err = b.do(func() error {
+		explain, err = explainer.ExplainList(ctx, params)
+		return err
+	})
+	return explain, err
+}
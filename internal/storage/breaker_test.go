@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fedorovmatvey/involta-test/internal/apperror"
+	"github.com/fedorovmatvey/involta-test/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyStore fails GetByID with err while it is set and counts the calls.
+type flakyStore struct {
+	DocumentStore
+	err   error
+	calls int
+}
+
+func (f *flakyStore) GetByID(ctx context.Context, id string) (*model.Document, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &model.Document{ID: id}, nil
+}
+
+func newTestBreaker(store DocumentStore) (*CircuitBreaker, *time.Time) {
+	now := time.Unix(0, 0)
+	b := NewCircuitBreaker(store, 3, 10*time.Second)
+	b.now = func() time.Time { return now }
+	return b, &now
+}
+
+func TestCircuitBreaker_Transitions(t *testing.T) {
+	store := &flakyStore{err: errors.New("connection refused")}
+	b, now := newTestBreaker(store)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, breakerClosed, b.state)
+		_, err := b.GetByID(ctx, "doc-1")
+		assert.ErrorIs(t, err, store.err)
+	}
+	assert.Equal(t, breakerOpen, b.state)
+
+	_, err := b.GetByID(ctx, "doc-1")
+	var unavailable *apperror.UnavailableError
+	assert.ErrorAs(t, err, &unavailable)
+	assert.Equal(t, 10*time.Second, unavailable.RetryAfter)
+	assert.Equal(t, 3, store.calls)
+
+	*now = now.Add(10 * time.Second)
+	_, err = b.GetByID(ctx, "doc-1")
+	assert.ErrorIs(t, err, store.err)
+	assert.Equal(t, breakerOpen, b.state, "failed probe reopens")
+	assert.Equal(t, 4, store.calls)
+
+	*now = now.Add(10 * time.Second)
+	store.err = nil
+	doc, err := b.GetByID(ctx, "doc-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "doc-1", doc.ID)
+	assert.Equal(t, breakerClosed, b.state)
+}
+
+func TestCircuitBreaker_HalfOpenAllowsOneProbe(t *testing.T) {
+	b, now := newTestBreaker(&flakyStore{})
+	b.trip()
+	*now = now.Add(10 * time.Second)
+
+	assert.NoError(t, b.allow())
+	assert.Equal(t, breakerHalfOpen, b.state)
+	var unavailable *apperror.UnavailableError
+	assert.ErrorAs(t, b.allow(), &unavailable)
+
+	b.record(nil)
+	assert.Equal(t, breakerClosed, b.state)
+	assert.NoError(t, b.allow())
+}
+
+func TestCircuitBreaker_IgnoresRequestErrors(t *testing.T) {
+	store := &flakyStore{}
+	b, _ := newTestBreaker(store)
+
+	for _, err := range []error{
+		apperror.NotFound(apperror.ResourceDocument, "doc-1"),
+		apperror.Conflict(apperror.ResourceDocument, "doc-1"),
+		context.Canceled,
+	} {
+		store.err = err
+		for i := 0; i < 5; i++ {
+			_, _ = b.GetByID(context.Background(), "doc-1")
+		}
+		assert.Equal(t, breakerClosed, b.state, "%v", err)
+	}
+}
+
+func TestCircuitBreaker_SuccessResetsFailures(t *testing.T) {
+	store := &flakyStore{}
+	b, _ := newTestBreaker(store)
+	failure := errors.New("timeout")
+
+	for i := 0; i < 5; i++ {
+		store.err = failure
+		_, _ = b.GetByID(context.Background(), "doc-1")
+		_, _ = b.GetByID(context.Background(), "doc-1")
+		store.err = nil
+		_, _ = b.GetByID(context.Background(), "doc-1")
+	}
+	assert.Equal(t, breakerClosed, b.state)
+}
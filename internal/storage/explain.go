@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"github.com/fedorovmatvey/involta-test/internal/model"
+	"github.com/restream/reindexer/v3"
+)
+
+// toQueryExplain keeps the timings and selectors of a Reindexer query plan.
+func toQueryExplain(explain *reindexer.ExplainResults) *model.QueryExplain {
+	selectors := make([]model.QuerySelector, 0, len(explain.Selectors))
+	for _, sel := range explain.Selectors {
+		selectors = append(selectors, model.QuerySelector{
+			Field:     sel.Field,
+			Method:    sel.Method,
+			Condition: sel.Condition,
+			Keys:      sel.Keys,
+			Matched:   sel.Matched,
+			Items:     sel.Items,
+			Cost:      sel.Cost,
+		})
+	}
+
+	return &model.QueryExplain{
+		TotalUs:       explain.TotalUs,
+		PreselectUs:   explain.PreselectUs,
+		PrepareUs:     explain.PrepareUs,
+		IndexesUs:     explain.IndexesUs,
+		PostprocessUs: explain.PostprocessUS,
+		LoopUs:        explain.LoopUs,
+		GeneralSortUs: explain.GeneralSortUs,
+		SortIndex:     explain.SortIndex,
+		Selectors:     selectors,
+	}
+}
@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/fedorovmatvey/involta-test/internal/model"
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToQueryExplain(t *testing.T) {
+	explain := toQueryExplain(&reindexer.ExplainResults{
+		TotalUs:       120,
+		PreselectUs:   10,
+		IndexesUs:     30,
+		PostprocessUS: 5,
+		SortIndex:     "updated_at",
+		Selectors: []reindexer.ExplainSelector{
+			{Field: "updated_at", Method: "index", Condition: "updated_at > 2024-05-01", Keys: 1, Matched: 42, Items: 42, Cost: 1.5},
+		},
+	})
+
+	assert.Equal(t, &model.QueryExplain{
+		TotalUs:       120,
+		PreselectUs:   10,
+		IndexesUs:     30,
+		PostprocessUs: 5,
+		SortIndex:     "updated_at",
+		Selectors: []model.QuerySelector{
+			{Field: "updated_at", Method: "index", Condition: "updated_at > 2024-05-01", Keys: 1, Matched: 42, Items: 42, Cost: 1.5},
+		},
+	}, explain)
+}
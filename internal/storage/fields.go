@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/fedorovmatvey/involta-test/internal/apperror"
+	"github.com/fedorovmatvey/involta-test/internal/model"
+)
+
+// queryFields maps API field names accepted in filter parameters to Reindexer
+// index names. Nothing outside this list reaches a query.
+var queryFields = map[string]string{
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+	"title":      "title",
+}
+
+// sortIndexes maps the model.SortFields whose index name differs from the API
+// name. updated_at sorts on its numeric copy, since RFC3339Nano strings with
+// different offsets or trimmed zeros do not order lexicographically.
+var sortIndexes = map[string]string{
+	"updated_at": "updated_at_nano",
+}
+
+// publicFields are the stored document fields that API clients may see. List
+// and Stream select only these, so internal data is not loaded for them.
+var publicFields = []string{"id", "title", "description", "created_at", "updated_at", "items", "tags"}
+
+// updatableFields maps API field names that bulk updates may set to Reindexer
+// field names.
+var updatableFields = map[string]string{
+	"title":       "title",
+	"description": "description",
+}
+
+type fieldValue struct {
+	field string
+	value string
+}
+
+// resolveUpdateWhere validates a bulk update and translates it to Reindexer
+// field names. Both the filter and the set of changes must be non-empty.
+func resolveUpdateWhere(filter, set map[string]string) ([]fieldValue, []fieldValue, error) {
+	if len(filter) == 0 {
+		return nil, nil, apperror.InvalidParameter("filter", "at least one condition is required")
+	}
+	if len(set) == 0 {
+		return nil, nil, apperror.InvalidParameter("set", "at least one field is required")
+	}
+
+	conditions := make([]fieldValue, 0, len(filter))
+	for name, value := range filter {
+		index, err := resolveField("filter", name)
+		if err != nil {
+			return nil, nil, err
+		}
+		conditions = append(conditions, fieldValue{field: index, value: value})
+	}
+
+	changes := make([]fieldValue, 0, len(set))
+	for name, value := range set {
+		field, ok := updatableFields[name]
+		if !ok {
+			return nil, nil, apperror.InvalidParameter("set", fmt.Sprintf("field %q cannot be updated", name))
+		}
+		changes = append(changes, fieldValue{field: field, value: value})
+	}
+
+	return conditions, changes, nil
+}
+
+func resolveField(parameter, name string) (string, error) {
+	index, ok := queryFields[name]
+	if !ok {
+		return "", apperror.InvalidParameter(parameter, fmt.Sprintf("unsupported field %q", name))
+	}
+	return index, nil
+}
+
+func resolveSortField(name string) (string, error) {
+	if !slices.Contains(model.SortFields, name) {
+		return "", apperror.InvalidParameter("sort", fmt.Sprintf("unsupported field %q", name))
+	}
+	if index, ok := sortIndexes[name]; ok {
+		return index, nil
+	}
+	return name, nil
+}
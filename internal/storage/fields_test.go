@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/fedorovmatvey/involta-test/internal/apperror"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveField_Mapped(t *testing.T) {
+	index, err := resolveField("sort", "title")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "title", index)
+}
+
+func TestResolveField_Unknown(t *testing.T) {
+	_, err := resolveField("sort", "internal")
+
+	var invalid *apperror.InvalidParameterError
+	assert.ErrorAs(t, err, &invalid)
+	assert.Equal(t, "sort", invalid.Parameter)
+}
+
+func TestResolveSortField(t *testing.T) {
+	index, err := resolveSortField("updated_at")
+	assert.NoError(t, err)
+	assert.Equal(t, "updated_at_nano", index)
+
+	index, err = resolveSortField("title")
+	assert.NoError(t, err)
+	assert.Equal(t, "title", index)
+
+	_, err = resolveSortField("internal")
+	var invalid *apperror.InvalidParameterError
+	assert.ErrorAs(t, err, &invalid)
+	assert.Equal(t, "sort", invalid.Parameter)
+}
+
+func TestResolveUpdateWhere(t *testing.T) {
+	conditions, changes, err := resolveUpdateWhere(
+		map[string]string{"title": "draft"},
+		map[string]string{"description": "reviewed"},
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []fieldValue{{field: "title", value: "draft"}}, conditions)
+	assert.Equal(t, []fieldValue{{field: "description", value: "reviewed"}}, changes)
+}
+
+func TestResolveUpdateWhere_Rejects(t *testing.T) {
+	tests := []struct {
+		name      string
+		filter    map[string]string
+		set       map[string]string
+		parameter string
+	}{
+		{"empty filter", nil, map[string]string{"title": "x"}, "filter"},
+		{"empty set", map[string]string{"title": "x"}, nil, "set"},
+		{"unknown filter field", map[string]string{"internal": "x"}, map[string]string{"title": "x"}, "filter"},
+		{"non-updatable field", map[string]string{"title": "x"}, map[string]string{"id": "x"}, "set"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := resolveUpdateWhere(tt.filter, tt.set)
+
+			var invalid *apperror.InvalidParameterError
+			assert.ErrorAs(t, err, &invalid)
+			assert.Equal(t, tt.parameter, invalid.Parameter)
+		})
+	}
+}
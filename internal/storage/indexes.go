@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/fedorovmatvey/involta-test/internal/model"
+	"github.com/restream/reindexer/v3"
+	"github.com/restream/reindexer/v3/bindings"
+)
+
+// Index describes an extra index on a top-level document field, configured
+// at deploy time on top of the ones declared with struct tags. Collate sets
+// how a string index compares values; "ascii" and "utf8" are case-insensitive.
+// The title tag index already uses utf8, so a title sort puts "apple" before
+// "Zebra".
+type Index struct {
+	Field   string
+	Type    string
+	Collate string
+}
+
+type indexer interface {
+	AddIndex(namespace string, indexDef ...reindexer.IndexDef) error
+	UpdateIndex(namespace string, indexDef reindexer.IndexDef) error
+}
+
+var allowedCollations = map[string]bool{
+	"":        true,
+	"none":    true,
+	"ascii":   true,
+	"utf8":    true,
+	"numeric": true,
+}
+
+var allowedIndexTypes = map[string]bool{
+	"hash": true,
+	"tree": true,
+	"text": true,
+	"-":    true,
+}
+
+// applyIndexes creates every configured index, updating the definition in
+// place when an index with the same name already exists with other settings.
+// Any other AddIndex error is returned as is.
+func applyIndexes(db indexer, namespace string, indexes []Index) error {
+	defs, err := buildIndexDefs(indexes)
+	if err != nil {
+		return err
+	}
+
+	for _, def := range defs {
+		err := db.AddIndex(namespace, def)
+		if isIndexConflict(err) {
+			err = db.UpdateIndex(namespace, def)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to apply index %q: %w", def.Name, err)
+		}
+	}
+	return nil
+}
+
+// isIndexConflict reports the error Reindexer returns for an index that
+// already exists with different settings.
+func isIndexConflict(err error) bool {
+	var rxErr bindings.Error
+	return errors.As(err, &rxErr) && rxErr.Code() == bindings.ErrConflict
+}
+
+func buildIndexDefs(indexes []Index) ([]reindexer.IndexDef, error) {
+	fields := indexableFields()
+	defs := make([]reindexer.IndexDef, 0, len(indexes))
+
+	for _, idx := range indexes {
+		fieldType, ok := fields[idx.Field]
+		if !ok {
+			return nil, fmt.Errorf("unknown index field %q", idx.Field)
+		}
+
+		indexType := idx.Type
+		if indexType == "" {
+			indexType = "hash"
+		}
+		if !allowedIndexTypes[indexType] {
+			return nil, fmt.Errorf("unsupported index type %q for field %q", idx.Type, idx.Field)
+		}
+		if indexType == "text" && fieldType != "string" {
+			return nil, fmt.Errorf("full-text index requires a string field, got %q", idx.Field)
+		}
+		if !allowedCollations[idx.Collate] {
+			return nil, fmt.Errorf("unsupported collation %q for field %q", idx.Collate, idx.Field)
+		}
+		if idx.Collate != "" && fieldType != "string" {
+			return nil, fmt.Errorf("collation requires a string field, got %q", idx.Field)
+		}
+
+		defs = append(defs, reindexer.IndexDef{
+			Name:        idx.Field,
+			JSONPaths:   []string{idx.Field},
+			IndexType:   indexType,
+			FieldType:   fieldType,
+			CollateMode: idx.Collate,
+		})
+	}
+	return defs, nil
+}
+
+// indexableFields maps the JSON names of scalar Document fields to their
+// Reindexer field types.
+func indexableFields() map[string]string {
+	fields := make(map[string]string)
+	t := reflect.TypeOf(model.Document{})
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		if fieldType, ok := reindexerFieldType(f.Type); ok {
+			fields[name] = fieldType
+		}
+	}
+	return fields
+}
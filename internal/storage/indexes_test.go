@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/restream/reindexer/v3/bindings"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockIndexer struct {
+	existing map[string]bool
+	addErr   error
+	added    []reindexer.IndexDef
+	updated  []reindexer.IndexDef
+}
+
+func (m *MockIndexer) AddIndex(namespace string, indexDef ...reindexer.IndexDef) error {
+	if m.addErr != nil {
+		return m.addErr
+	}
+	for _, def := range indexDef {
+		if m.existing[def.Name] {
+			return bindings.NewError("Index 'documents."+def.Name+"' already exists with different settings", bindings.ErrConflict)
+		}
+		m.added = append(m.added, def)
+	}
+	return nil
+}
+
+func (m *MockIndexer) UpdateIndex(namespace string, indexDef reindexer.IndexDef) error {
+	m.updated = append(m.updated, indexDef)
+	return nil
+}
+
+func TestApplyIndexes_CreatesConfiguredIndexes(t *testing.T) {
+	db := &MockIndexer{existing: map[string]bool{"description": true}}
+
+	err := applyIndexes(db, "documents", []Index{
+		{Field: "title", Type: "tree"},
+		{Field: "description", Type: "text"},
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, db.added, 1)
+	assert.Equal(t, "title", db.added[0].Name)
+	assert.Equal(t, "tree", db.added[0].IndexType)
+	assert.Equal(t, "string", db.added[0].FieldType)
+
+	assert.Len(t, db.updated, 1)
+	assert.Equal(t, "description", db.updated[0].Name)
+	assert.Equal(t, "text", db.updated[0].IndexType)
+}
+
+func TestApplyIndexes_ReturnsOtherErrors(t *testing.T) {
+	db := &MockIndexer{addErr: bindings.NewError("connection reset", bindings.ErrNetwork)}
+
+	err := applyIndexes(db, "documents", []Index{{Field: "title", Type: "tree"}})
+
+	assert.ErrorIs(t, err, db.addErr)
+	assert.Empty(t, db.updated)
+}
+
+func TestApplyIndexes_RejectsUnknownField(t *testing.T) {
+	db := &MockIndexer{}
+
+	err := applyIndexes(db, "documents", []Index{{Field: "missing", Type: "hash"}})
+
+	assert.Error(t, err)
+	assert.Empty(t, db.added)
+}
+
+func TestApplyIndexes_RejectsUnsupportedType(t *testing.T) {
+	db := &MockIndexer{}
+
+	err := applyIndexes(db, "documents", []Index{{Field: "title", Type: "btree"}})
+
+	assert.Error(t, err)
+	assert.Empty(t, db.added)
+}
+
+func TestApplyIndexes_CaseInsensitiveTitle(t *testing.T) {
+	db := &MockIndexer{}
+
+	err := applyIndexes(db, "documents", []Index{{Field: "title", Type: "tree", Collate: "utf8"}})
+
+	assert.NoError(t, err)
+	assert.Len(t, db.added, 1)
+	assert.Equal(t, "title", db.added[0].Name)
+	assert.Equal(t, "tree", db.added[0].IndexType)
+	assert.Equal(t, "utf8", db.added[0].CollateMode)
+}
+
+func TestApplyIndexes_RejectsInvalidCollation(t *testing.T) {
+	for _, idx := range []Index{
+		{Field: "title", Type: "tree", Collate: "klingon"},
+		{Field: "created_at", Type: "tree", Collate: "utf8"},
+	} {
+		db := &MockIndexer{}
+
+		assert.Error(t, applyIndexes(db, "documents", []Index{idx}), idx.Field)
+		assert.Empty(t, db.added)
+	}
+}
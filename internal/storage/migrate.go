@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+
+	"github.com/fedorovmatvey/involta-test/internal/model"
+	"github.com/restream/reindexer/v3"
+	"github.com/restream/reindexer/v3/bindings"
+)
+
+type namespaceDescriber interface {
+	indexer
+	DescribeNamespace(namespace string) (*reindexer.NamespaceDescription, error)
+}
+
+// migrateIndexes brings the indexes of an existing namespace in line with the
+// reindex tags on model.Document: missing indexes are added and indexes whose
+// type changed are updated. It must run before OpenNamespace, which adds
+// missing indexes itself and fails on changed ones, so afterwards there is
+// nothing left to compare. A namespace that does not exist yet is skipped.
+func migrateIndexes(db namespaceDescriber, namespace string) error {
+	desc, err := db.DescribeNamespace(namespace)
+	var rxErr bindings.Error
+	if errors.As(err, &rxErr) && rxErr.Code() == bindings.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to describe namespace %q: %w", namespace, err)
+	}
+
+	existing := make(map[string]reindexer.IndexDef, len(desc.Indexes))
+	for _, idx := range desc.Indexes {
+		existing[idx.Name] = idx.IndexDef
+	}
+
+	for _, def := range modelIndexDefs() {
+		current, ok := existing[def.Name]
+		switch {
+		case !ok:
+			if err := db.AddIndex(namespace, def); err != nil {
+				return fmt.Errorf("failed to add index %q: %w", def.Name, err)
+			}
+			log.Printf("Migrated namespace '%s': added index '%s' (%s)", namespace, def.Name, def.IndexType)
+		case indexChanged(current, def):
+			if err := db.UpdateIndex(namespace, def); err != nil {
+				return fmt.Errorf("failed to update index %q: %w", def.Name, err)
+			}
+			log.Printf("Migrated namespace '%s': changed index '%s' from %s %s to %s %s",
+				namespace, def.Name, current.IndexType, current.FieldType, def.IndexType, def.FieldType)
+		}
+	}
+	return nil
+}
+
+// indexChanged reports whether the stored index differs from the model in a
+// way OpenNamespace would reject.
+func indexChanged(current, want reindexer.IndexDef) bool {
+	return current.IndexType != want.IndexType ||
+		current.FieldType != want.FieldType ||
+		current.IsArray != want.IsArray ||
+		collation(current.CollateMode) != collation(want.CollateMode)
+}
+
+// collation normalizes a collate mode; Reindexer describes an index without
+// one as "none".
+func collation(mode string) string {
+	if mode == "none" {
+		return ""
+	}
+	return mode
+}
+
+// modelIndexDefs builds index definitions from the reindex tags of scalar
+// model.Document fields and slices of scalars, which become array indexes.
+func modelIndexDefs() []reindexer.IndexDef {
+	t := reflect.TypeOf(model.Document{})
+	defs := make([]reindexer.IndexDef, 0, t.NumField())
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("reindex")
+		if tag == "" {
+			continue
+		}
+
+		isArray := f.Type.Kind() == reflect.Slice
+		elemType := f.Type
+		if isArray {
+			elemType = f.Type.Elem()
+		}
+		fieldType, ok := reindexerFieldType(elemType)
+		if !ok {
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		indexType := "hash"
+		if len(parts) > 1 && parts[1] != "" {
+			indexType = parts[1]
+		}
+
+		jsonPath, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if jsonPath == "" {
+			jsonPath = f.Name
+		}
+
+		def := reindexer.IndexDef{
+			Name:      parts[0],
+			JSONPaths: []string{jsonPath},
+			IndexType: indexType,
+			FieldType: fieldType,
+			IsArray:   isArray,
+		}
+		for _, opt := range parts[min(len(parts), 2):] {
+			if opt == "pk" {
+				def.IsPK = true
+			} else if mode, ok := strings.CutPrefix(opt, "collate_"); ok {
+				def.CollateMode = mode
+			}
+		}
+		defs = append(defs, def)
+	}
+	return defs
+}
+
+func reindexerFieldType(t reflect.Type) (string, bool) {
+	switch t.Kind() {
+	case reflect.String:
+		return "string", true
+	case reflect.Int:
+		return "int", true
+	case reflect.Int64:
+		return "int64", true
+	}
+	return "", false
+}
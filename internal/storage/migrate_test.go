@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/restream/reindexer/v3/bindings"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockDescriber struct {
+	MockIndexer
+	indexes []reindexer.IndexDef
+	err     error
+}
+
+func (m *MockDescriber) DescribeNamespace(namespace string) (*reindexer.NamespaceDescription, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	desc := &reindexer.NamespaceDescription{Name: namespace}
+	for _, def := range m.indexes {
+		desc.Indexes = append(desc.Indexes, reindexer.IndexDescription{IndexDef: def})
+	}
+	return desc, nil
+}
+
+// describerWith describes a namespace holding the model indexes, with change
+// applied to each of them. change returns false to leave an index out.
+func describerWith(change func(def *reindexer.IndexDef) bool) *MockDescriber {
+	db := &MockDescriber{}
+	for _, def := range modelIndexDefs() {
+		if change(&def) {
+			db.indexes = append(db.indexes, def)
+		}
+	}
+	return db
+}
+
+func TestMigrateIndexes_AddsMissingIndex(t *testing.T) {
+	db := describerWith(func(def *reindexer.IndexDef) bool { return def.Name != "description" })
+
+	err := migrateIndexes(db, "documents")
+
+	assert.NoError(t, err)
+	assert.Len(t, db.added, 1)
+	assert.Equal(t, "description", db.added[0].Name)
+	assert.Equal(t, []string{"description"}, db.added[0].JSONPaths)
+	assert.Equal(t, "hash", db.added[0].IndexType)
+	assert.Empty(t, db.updated)
+}
+
+func TestMigrateIndexes_NothingChanged(t *testing.T) {
+	db := describerWith(func(def *reindexer.IndexDef) bool { return true })
+
+	assert.NoError(t, migrateIndexes(db, "documents"))
+	assert.Empty(t, db.added)
+	assert.Empty(t, db.updated)
+}
+
+func TestMigrateIndexes_AddsArrayIndex(t *testing.T) {
+	db := describerWith(func(def *reindexer.IndexDef) bool { return def.Name != "tags" })
+
+	assert.NoError(t, migrateIndexes(db, "documents"))
+	assert.Len(t, db.added, 1)
+	assert.Equal(t, "tags", db.added[0].Name)
+	assert.Equal(t, "string", db.added[0].FieldType)
+	assert.True(t, db.added[0].IsArray)
+}
+
+func TestMigrateIndexes_UpdatesChangedType(t *testing.T) {
+	db := describerWith(func(def *reindexer.IndexDef) bool {
+		if def.Name == "updated_at_nano" {
+			def.IndexType, def.FieldType = "hash", "string"
+		}
+		return true
+	})
+
+	assert.NoError(t, migrateIndexes(db, "documents"))
+	assert.Empty(t, db.added)
+	assert.Len(t, db.updated, 1)
+	assert.Equal(t, "updated_at_nano", db.updated[0].Name)
+	assert.Equal(t, "tree", db.updated[0].IndexType)
+	assert.Equal(t, "int64", db.updated[0].FieldType)
+}
+
+func TestMigrateIndexes_UpdatesChangedCollation(t *testing.T) {
+	db := describerWith(func(def *reindexer.IndexDef) bool {
+		if def.Name == "title" {
+			def.IndexType, def.CollateMode = "hash", "none"
+		} else if def.CollateMode == "" {
+			def.CollateMode = "none"
+		}
+		return true
+	})
+
+	assert.NoError(t, migrateIndexes(db, "documents"))
+	assert.Empty(t, db.added)
+	assert.Len(t, db.updated, 1)
+	assert.Equal(t, "title", db.updated[0].Name)
+	assert.Equal(t, "tree", db.updated[0].IndexType)
+	assert.Equal(t, "utf8", db.updated[0].CollateMode)
+}
+
+func TestMigrateIndexes_SkipsMissingNamespace(t *testing.T) {
+	db := &MockDescriber{err: reindexer.ErrNotFound}
+
+	assert.NoError(t, migrateIndexes(db, "documents"))
+	assert.Empty(t, db.added)
+}
+
+func TestMigrateIndexes_DescribeError(t *testing.T) {
+	db := &MockDescriber{err: bindings.NewError("connection reset", bindings.ErrNetwork)}
+
+	assert.ErrorIs(t, migrateIndexes(db, "documents"), db.err)
+}
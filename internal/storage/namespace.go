@@ -0,0 +1,36 @@
+package storage
+
+import "github.com/restream/reindexer/v3"
+
+// NamespaceOptions configures how the documents namespace is opened. The zero
+// value matches reindexer.DefaultNamespaceOptions.
+type NamespaceOptions struct {
+	// InMemory keeps the namespace out of on-disk storage, for tests and
+	// ephemeral deployments.
+	InMemory              bool
+	DropOnIndexesConflict bool
+	DropOnFileFormatError bool
+	DisableObjCache       bool
+	// ObjCacheSize overrides the object cache size when positive.
+	ObjCacheSize int
+}
+
+func (o NamespaceOptions) reindexerOptions() *reindexer.NamespaceOptions {
+	opts := reindexer.DefaultNamespaceOptions()
+	if o.InMemory {
+		opts = opts.NoStorage()
+	}
+	if o.DropOnIndexesConflict {
+		opts = opts.DropOnIndexesConflict()
+	}
+	if o.DropOnFileFormatError {
+		opts = opts.DropOnFileFormatError()
+	}
+	if o.DisableObjCache {
+		opts = opts.DisableObjCache()
+	}
+	if o.ObjCacheSize > 0 {
+		opts = opts.ObjCacheSize(o.ObjCacheSize)
+	}
+	return opts
+}
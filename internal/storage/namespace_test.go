@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"os"
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamespaceOptions_ZeroValueIsDefault(t *testing.T) {
+	assert.Equal(t, reindexer.DefaultNamespaceOptions(), NamespaceOptions{}.reindexerOptions())
+}
+
+func TestNamespaceOptions_MapsFlags(t *testing.T) {
+	opts := NamespaceOptions{
+		InMemory:              true,
+		DropOnIndexesConflict: true,
+		ObjCacheSize:          1000,
+	}.reindexerOptions()
+
+	assert.Equal(t, reindexer.DefaultNamespaceOptions().NoStorage().DropOnIndexesConflict().ObjCacheSize(1000), opts)
+}
+
+func TestNew_InMemoryNamespace(t *testing.T) {
+	dsn := os.Getenv("REINDEXER_TEST_DSN")
+	if dsn == "" {
+		t.Skip("REINDEXER_TEST_DSN is not set")
+	}
+
+	store, err := New(dsn, "documents_test_in_memory", nil, false, NamespaceOptions{InMemory: true})
+	assert.NoError(t, err)
+	defer store.Close()
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fedorovmatvey/involta-test/internal/model"
+)
+
+// ReadSplit is a DocumentStore that sends document reads to a read replica and
+// everything else to the primary. Replicas lag behind the primary, so a read
+// right after a write may not see it. GetMeta stays on the primary because
+// the service uses it for existence checks before writes, and reads whose
+// context is marked with ReadPrimary go there too.
+type ReadSplit struct {
+	primary DocumentStore
+	replica DocumentStore
+}
+
+var _ DocumentStore = (*ReadSplit)(nil)
+
+func NewReadSplit(primary, replica DocumentStore) *ReadSplit {
+	return &ReadSplit{primary: primary, replica: replica}
+}
+
+type readPrimaryKey struct{}
+
+// ReadPrimary marks ctx so a ReadSplit serves its reads from the primary.
+// Read-modify-write paths use it, since merging a write into a lagging
+// replica's copy would overwrite newer changes with stale fields, and so do
+// reads whose result is cached, which would keep a stale copy for the TTL.
+func ReadPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readPrimaryKey{}, true)
+}
+
+// reader returns the store that serves reads for ctx.
+func (s *ReadSplit) reader(ctx context.Context) DocumentStore {
+	if primary, _ := ctx.Value(readPrimaryKey{}).(bool); primary {
+		return s.primary
+	}
+	return s.replica
+}
+
+func (s *ReadSplit) Create(ctx context.Context, doc *model.Document) error {
+	return s.primary.Create(ctx, doc)
+}
+
+func (s *ReadSplit) Update(ctx context.Context, doc *model.Document) error {
+	return s.primary.Update(ctx, doc)
+}
+
+func (s *ReadSplit) GetByID(ctx context.Context, id string) (*model.Document, error) {
+	return s.reader(ctx).GetByID(ctx, id)
+}
+
+func (s *ReadSplit) GetByIDs(ctx context.Context, ids []string) ([]model.Document, error) {
+	return s.reader(ctx).GetByIDs(ctx, ids)
+}
+
+func (s *ReadSplit) GetMeta(ctx context.Context, id string) (*model.DocumentMeta, error) {
+	return s.primary.GetMeta(ctx, id)
+}
+
+func (s *ReadSplit) UpdateWhere(ctx context.Context, filter, set map[string]string) ([]string, error) {
+	return s.primary.UpdateWhere(ctx, filter, set)
+}
+
+func (s *ReadSplit) Delete(ctx context.Context, id string) error {
+	return s.primary.Delete(ctx, id)
+}
+
+func (s *ReadSplit) DeleteByIDs(ctx context.Context, ids []string) ([]string, error) {
+	return s.primary.DeleteByIDs(ctx, ids)
+}
+
+func (s *ReadSplit) List(ctx context.Context, params model.PaginationParams) ([]model.Document, int, error) {
+	return s.reader(ctx).List(ctx, params)
+}
+
+// CheckConnection fails when either connection is down, since reads and
+// writes each depend on one of them.
+func (s *ReadSplit) CheckConnection(ctx context.Context) error {
+	if err := s.primary.CheckConnection(ctx); err != nil {
+		return err
+	}
+	if err := s.replica.CheckConnection(ctx); err != nil {
+		return fmt.Errorf("read replica: %w", err)
+	}
+	return nil
+}
+
+func (s *ReadSplit) WithTransaction(ctx context.Context, fn func(tx TxStore) error) error {
+	return s.primary.WithTransaction(ctx, fn)
+}
+
+// Stream reads from the replica, falling back to a List of the page when it
+// cannot stream.
+func (s *ReadSplit) Stream(ctx context.Context, params model.PaginationParams, fn func(doc *model.Document) error) error {
+	return streamOrList(ctx, s.replica, params, fn)
+}
+
+// ExplainList explains the list query on the replica, where it runs.
+func (s *ReadSplit) ExplainList(ctx context.Context, params model.PaginationParams) (*model.QueryExplain, error) {
+	explainer, ok := s.replica.(Explainer)
+	if !ok {
+		return nil, errExplainUnsupported
+	}
+	return explainer.ExplainList(ctx, params)
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fedorovmatvey/involta-test/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingStore records the calls it receives.
+type recordingStore struct {
+	DocumentStore
+	calls []string
+}
+
+func (r *recordingStore) Create(ctx context.Context, doc *model.Document) error {
+	r.calls = append(r.calls, "Create")
+	return nil
+}
+
+func (r *recordingStore) Update(ctx context.Context, doc *model.Document) error {
+	r.calls = append(r.calls, "Update")
+	return nil
+}
+
+func (r *recordingStore) Delete(ctx context.Context, id string) error {
+	r.calls = append(r.calls, "Delete")
+	return nil
+}
+
+func (r *recordingStore) GetByID(ctx context.Context, id string) (*model.Document, error) {
+	r.calls = append(r.calls, "GetByID")
+	return &model.Document{ID: id}, nil
+}
+
+func (r *recordingStore) GetMeta(ctx context.Context, id string) (*model.DocumentMeta, error) {
+	r.calls = append(r.calls, "GetMeta")
+	return &model.DocumentMeta{ID: id}, nil
+}
+
+func (r *recordingStore) List(ctx context.Context, params model.PaginationParams) ([]model.Document, int, error) {
+	r.calls = append(r.calls, "List")
+	return []model.Document{{ID: "doc-1"}}, 1, nil
+}
+
+func TestReadSplit_Routing(t *testing.T) {
+	primary, replica := &recordingStore{}, &recordingStore{}
+	s := NewReadSplit(primary, replica)
+	ctx := context.Background()
+
+	_, _ = s.GetByID(ctx, "doc-1")
+	_, _, _ = s.List(ctx, model.PaginationParams{})
+	var streamed []string
+	_ = s.Stream(ctx, model.PaginationParams{}, func(doc *model.Document) error {
+		streamed = append(streamed, doc.ID)
+		return nil
+	})
+
+	_ = s.Create(ctx, &model.Document{ID: "doc-2"})
+	_ = s.Update(ctx, &model.Document{ID: "doc-2"})
+	_ = s.Delete(ctx, "doc-2")
+	_, _ = s.GetMeta(ctx, "doc-2")
+
+	assert.Equal(t, []string{"GetByID", "List", "List"}, replica.calls)
+	assert.Equal(t, []string{"Create", "Update", "Delete", "GetMeta"}, primary.calls)
+	assert.Equal(t, []string{"doc-1"}, streamed)
+}
+
+func TestReadSplit_ReadPrimary(t *testing.T) {
+	primary, replica := &recordingStore{}, &recordingStore{}
+	s := NewReadSplit(primary, replica)
+
+	ctx := ReadPrimary(context.Background())
+	_, _ = s.GetByID(ctx, "doc-1")
+	_, _, _ = s.List(ctx, model.PaginationParams{})
+
+	assert.Equal(t, []string{"GetByID", "List"}, primary.calls)
+	assert.Empty(t, replica.calls)
+}
@@ -0,0 +1,254 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/fedorovmatvey/involta-test/internal/model"
+	"github.com/restream/reindexer/v3/bindings"
+)
+
+// Reconnecting is a DocumentStore decorator that survives a dropped storage
+// connection. When a call fails with a connection error it dials a new store,
+// with exponential backoff between attempts, swaps it in and retries the call
+// once. Concurrent calls that fail on the same connection share one reconnect.
+//
+// A retried write may already have been applied before the connection
+// dropped, so a retried Create can report a conflict for its own document.
+type Reconnecting struct {
+	dial       func() (DocumentStore, error)
+	attempts   int
+	backoff    time.Duration
+	maxBackoff time.Duration
+	sleep      func(ctx context.Context, d time.Duration) error
+
+	mu         sync.RWMutex
+	current    DocumentStore
+	generation uint64
+	// reconnectMu serializes reconnects.
+	reconnectMu sync.Mutex
+}
+
+var _ DocumentStore = (*Reconnecting)(nil)
+
+// NewReconnecting wraps current, replacing it with a store from dial when its
+// connection drops. A reconnect makes up to attempts dials, waiting backoff
+// after the first failure and doubling the wait up to maxBackoff.
+func NewReconnecting(current DocumentStore, dial func() (DocumentStore, error), attempts int, backoff, maxBackoff time.Duration) *Reconnecting {
+	return &Reconnecting{
+		dial:       dial,
+		attempts:   max(attempts, 1),
+		backoff:    backoff,
+		maxBackoff: max(maxBackoff, backoff),
+		sleep:      sleepContext,
+		current:    current,
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// isConnectionError reports whether err means the storage connection is gone
+// rather than that the request failed. Cancelled and timed-out contexts are
+// request errors: context.DeadlineExceeded satisfies net.Error, and treating
+// it as a dropped connection would make every slow query reconnect and close
+// the store under the other in-flight calls.
+func isConnectionError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var rxErr bindings.Error
+	if errors.As(err, &rxErr) {
+		return rxErr.Code() == bindings.ErrNetwork
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+func (r *Reconnecting) store() (DocumentStore, uint64) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current, r.generation
+}
+
+// reconnect replaces the store of the given generation. It returns at once
+// when another call has already replaced it.
+func (r *Reconnecting) reconnect(ctx context.Context, generation uint64) error {
+	r.reconnectMu.Lock()
+	defer r.reconnectMu.Unlock()
+
+	if _, current := r.store(); current != generation {
+		return nil
+	}
+
+	wait := r.backoff
+	var err error
+	for attempt := 1; attempt <= r.attempts; attempt++ {
+		if attempt > 1 {
+			if err := r.sleep(ctx, wait); err != nil {
+				return err
+			}
+			wait = min(wait*2, r.maxBackoff)
+		}
+
+		var next DocumentStore
+		next, err = r.dial()
+		if err != nil {
+			log.Printf("Storage reconnect attempt %d/%d failed: %v", attempt, r.attempts, err)
+			continue
+		}
+
+		r.mu.Lock()
+		old := r.current
+		r.current = next
+		r.generation++
+		r.mu.Unlock()
+
+		if closer, ok := old.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				log.Printf("Failed to close dropped storage connection: %v", err)
+			}
+		}
+		log.Printf("Storage reconnected after %d attempt(s)", attempt)
+		return nil
+	}
+	return fmt.Errorf("failed to reconnect to storage: %w", err)
+}
+
+// do runs fn against the current store, reconnecting and running it once more
+// when it fails with a connection error.
+func (r *Reconnecting) do(ctx context.Context, fn func(store DocumentStore) error) error {
+	store, generation := r.store()
+	err := fn(store)
+	if !isConnectionError(err) {
+		return err
+	}
+
+	if rerr := r.reconnect(ctx, generation); rerr != nil {
+		log.Printf("Storage connection lost: %v", rerr)
+		return err
+	}
+	store, _ = r.store()
+	return fn(store)
+}
+
+// Close closes the current store.
+func (r *Reconnecting) Close() error {
+	store, _ := r.store()
+	if closer, ok := store.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func (r *Reconnecting) Create(ctx context.Context, doc *model.Document) error {
+	return r.do(ctx, func(store DocumentStore) error { return store.Create(ctx, doc) })
+}
+
+func (r *Reconnecting) Update(ctx context.Context, doc *model.Document) error {
+	return r.do(ctx, func(store DocumentStore) error { return store.Update(ctx, doc) })
+}
+
+func (r *Reconnecting) GetByID(ctx context.Context, id string) (doc *model.Document, err error) {
+	err = r.do(ctx, func(store DocumentStore) error {
+		doc, err = store.GetByID(ctx, id)
+		return err
+	})
+	return doc, err
+}
+
+func (r *Reconnecting) GetByIDs(ctx context.Context, ids []string) (docs []model.Document, err error) {
+	err = r.do(ctx, func(store DocumentStore) error {
+		docs, err = store.GetByIDs(ctx, ids)
+		return err
+	})
+	return docs, err
+}
+
+func (r *Reconnecting) GetMeta(ctx context.Context, id string) (meta *model.DocumentMeta, err error) {
+	err = r.do(ctx, func(store DocumentStore) error {
+		meta, err = store.GetMeta(ctx, id)
+		return err
+	})
+	return meta, err
+}
+
+func (r *Reconnecting) UpdateWhere(ctx context.Context, filter, set map[string]string) (ids []string, err error) {
+	err = r.do(ctx, func(store DocumentStore) error {
+		ids, err = store.UpdateWhere(ctx, filter, set)
+		return err
+	})
+	return ids, err
+}
+
+func (r *Reconnecting) Delete(ctx context.Context, id string) error {
+	return r.do(ctx, func(store DocumentStore) error { return store.Delete(ctx, id) })
+}
+
+func (r *Reconnecting) DeleteByIDs(ctx context.Context, ids []string) (deleted []string, err error) {
+	err = r.do(ctx, func(store DocumentStore) error {
+		deleted, err = store.DeleteByIDs(ctx, ids)
+		return err
+	})
+	return deleted, err
+}
+
+func (r *Reconnecting) List(ctx context.Context, params model.PaginationParams) (docs []model.Document, total int, err error) {
+	err = r.do(ctx, func(store DocumentStore) error {
+		docs, total, err = store.List(ctx, params)
+		return err
+	})
+	return docs, total, err
+}
+
+func (r *Reconnecting) CheckConnection(ctx context.Context) error {
+	return r.do(ctx, func(store DocumentStore) error { return store.CheckConnection(ctx) })
+}
+
+func (r *Reconnecting) WithTransaction(ctx context.Context, fn func(tx TxStore) error) error {
+	return r.do(ctx, func(store DocumentStore) error { return store.WithTransaction(ctx, fn) })
+}
+
+// Stream forwards to the current store, falling back to a List of the page
+// when it cannot stream. A stream is only retried when the connection dropped
+// before any document reached fn.
+func (r *Reconnecting) Stream(ctx context.Context, params model.PaginationParams, fn func(doc *model.Document) error) error {
+	delivered := false
+	return r.do(ctx, func(store DocumentStore) error {
+		err := streamOrList(ctx, store, params, func(doc *model.Document) error {
+			delivered = true
+			return fn(doc)
+		})
+		if delivered && isConnectionError(err) {
+			return fmt.Errorf("stream interrupted: %s", err)
+		}
+		return err
+	})
+}
+
+// ExplainList forwards to the current store when it can explain queries.
+func (r *Reconnecting) ExplainList(ctx context.Context, params model.PaginationParams) (explain *model.QueryExplain, err error) {
+	err = r.do(ctx, func(store DocumentStore) error {
+		explainer, ok := store.(Explainer)
+		if !ok {
+			return errExplainUnsupported
+		}
+		explain, err = explainer.ExplainList(ctx, params)
+		return err
+	})
+	return explain, err
+}
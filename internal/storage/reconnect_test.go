@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/restream/reindexer/v3/bindings"
+	"github.com/stretchr/testify/assert"
+)
+
+// closingStore records whether the dropped connection was closed.
+type closingStore struct {
+	flakyStore
+	closed bool
+}
+
+func (c *closingStore) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestReconnecting_RecoversAndRetriesOnce(t *testing.T) {
+	dropped := &closingStore{flakyStore: flakyStore{err: bindings.NewError("connection reset", bindings.ErrNetwork)}}
+	recovered := &flakyStore{}
+	dials := 0
+	var waits []time.Duration
+	r := NewReconnecting(dropped, func() (DocumentStore, error) {
+		dials++
+		if dials < 3 {
+			return nil, errors.New("connection refused")
+		}
+		return recovered, nil
+	}, 5, 100*time.Millisecond, 150*time.Millisecond)
+	r.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+
+	doc, err := r.GetByID(context.Background(), "doc-1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "doc-1", doc.ID)
+	assert.Equal(t, 1, dropped.calls)
+	assert.Equal(t, 1, recovered.calls)
+	assert.True(t, dropped.closed)
+	assert.Equal(t, 3, dials)
+	assert.Equal(t, []time.Duration{100 * time.Millisecond, 150 * time.Millisecond}, waits)
+
+	_, err = r.GetByID(context.Background(), "doc-2")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, dials, "the new connection is kept")
+}
+
+func TestReconnecting_GivesUp(t *testing.T) {
+	netErr := bindings.NewError("connection reset", bindings.ErrNetwork)
+	store := &flakyStore{err: netErr}
+	r := NewReconnecting(store, func() (DocumentStore, error) {
+		return nil, errors.New("connection refused")
+	}, 2, time.Millisecond, time.Millisecond)
+	r.sleep = func(ctx context.Context, d time.Duration) error { return nil }
+
+	_, err := r.GetByID(context.Background(), "doc-1")
+
+	assert.ErrorIs(t, err, netErr)
+	assert.Equal(t, 1, store.calls)
+}
+
+func TestReconnecting_IgnoresRequestErrors(t *testing.T) {
+	store := &flakyStore{err: errors.New("invalid query")}
+	r := NewReconnecting(store, func() (DocumentStore, error) {
+		t.Fatal("must not reconnect")
+		return nil, nil
+	}, 3, time.Millisecond, time.Millisecond)
+
+	_, err := r.GetByID(context.Background(), "doc-1")
+
+	assert.ErrorIs(t, err, store.err)
+	assert.Equal(t, 1, store.calls)
+}
+
+func TestReconnecting_IgnoresContextErrors(t *testing.T) {
+	for _, err := range []error{
+		fmt.Errorf("failed to get document: %w", context.DeadlineExceeded),
+		fmt.Errorf("failed to get document: %w", context.Canceled),
+	} {
+		store := &flakyStore{err: err}
+		r := NewReconnecting(store, func() (DocumentStore, error) {
+			t.Fatal("must not reconnect")
+			return nil, nil
+		}, 3, time.Millisecond, time.Millisecond)
+
+		_, got := r.GetByID(context.Background(), "doc-1")
+
+		assert.ErrorIs(t, got, err)
+		assert.Equal(t, 1, store.calls)
+	}
+}
+
+func TestIsConnectionError(t *testing.T) {
+	assert.True(t, isConnectionError(bindings.NewError("connection reset", bindings.ErrNetwork)))
+	assert.True(t, isConnectionError(&net.OpError{Op: "read", Err: errors.New("connection reset by peer")}))
+	assert.True(t, isConnectionError(fmt.Errorf("read: %w", io.ErrUnexpectedEOF)))
+	assert.False(t, isConnectionError(fmt.Errorf("query: %w", context.DeadlineExceeded)))
+	assert.False(t, isConnectionError(bindings.NewError("bad query", bindings.ErrParams)))
+}
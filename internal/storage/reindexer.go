@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"time"
 
+	"github.com/fedorovmatvey/involta-test/internal/apperror"
 	"github.com/fedorovmatvey/involta-test/internal/model"
 	"github.com/restream/reindexer/v3"
 	_ "github.com/restream/reindexer/v3/bindings/cproto"
@@ -17,37 +19,94 @@ type Storage struct {
 	namespace string
 }
 
-func New(dsn, namespace string) (*Storage, error) {
-	db := reindexer.NewReindex(dsn, reindexer.WithCreateDBIfMissing())
+func New(dsn, namespace string, indexes []Index, autoMigrate bool, nsOpts NamespaceOptions) (*Storage, error) {
+	storage := &Storage{
+		db:        reindexer.NewReindex(dsn, reindexer.WithCreateDBIfMissing()),
+		namespace: namespace,
+	}
+
+	// The client holds connections and goroutines from the start, and the
+	// reconnect dial calls New repeatedly while storage is down.
+	if err := storage.open(dsn, indexes, autoMigrate, nsOpts); err != nil {
+		storage.Close()
+		return nil, err
+	}
+
+	log.Printf("Successfully connected to Reindexer, namespace: %s", namespace)
+
+	return storage, nil
+}
 
-	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to connect to database: %v", err)
+// open checks the connection and prepares the namespace for New.
+func (s *Storage) open(dsn string, indexes []Index, autoMigrate bool, nsOpts NamespaceOptions) error {
+	if err := s.db.Ping(); err != nil {
+		return fmt.Errorf("failed to connect to database: %v", err)
 	}
 
-	status := db.Status()
+	status := s.db.Status()
 	if status.Err != nil {
-		return nil, fmt.Errorf("failed to check reindexer status with dsn %q: %w", dsn, status.Err)
+		return fmt.Errorf("failed to check reindexer status with dsn %q: %w", dsn, status.Err)
 	}
 
-	err := db.OpenNamespace(namespace, reindexer.DefaultNamespaceOptions(), model.Document{})
-	if err != nil {
-		return nil, fmt.Errorf("failed to open namespace %q: %w", namespace, err)
+	if autoMigrate {
+		if err := migrateIndexes(s.db, s.namespace); err != nil {
+			return fmt.Errorf("failed to migrate namespace: %w", err)
+		}
 	}
 
-	storage := &Storage{
-		db:        db,
-		namespace: namespace,
+	err := s.db.OpenNamespace(s.namespace, nsOpts.reindexerOptions(), model.Document{})
+	if err != nil {
+		return fmt.Errorf("failed to open namespace %q: %w", s.namespace, err)
 	}
 
-	log.Printf("Successfully connected to Reindexer, namespace: %s", namespace)
+	if err := s.backfillUpdatedAtNano(); err != nil {
+		return fmt.Errorf("failed to migrate namespace: %w", err)
+	}
 
-	return storage, nil
+	if err := s.initIndexes(indexes); err != nil {
+		return fmt.Errorf("failed to init indexes: %w", err)
+	}
+	return nil
 }
 
-func (s *Storage) initIndexes() error {
+// backfillUpdatedAtNano sets UpdatedAtNano on documents written before the
+// field existed, which updated_since would otherwise never match. It runs on
+// every start, not only with auto_migrate: once the namespace is migrated it
+// is a single indexed lookup that finds nothing.
+func (s *Storage) backfillUpdatedAtNano() error {
+	it := s.db.Query(s.namespace).
+		Where("updated_at_nano", reindexer.EQ, 0).
+		Exec()
+	defer it.Close()
+
+	var docs []*model.Document
+	for it.Next() {
+		doc, err := toDocument(it.Object())
+		if err != nil {
+			return err
+		}
+		docs = append(docs, doc)
+	}
+	if err := it.Error(); err != nil {
+		return fmt.Errorf("failed query Reindexer: %w", err)
+	}
+
+	for _, doc := range docs {
+		stampUpdatedAtNano(doc)
+		if _, err := s.db.Update(s.namespace, doc); err != nil {
+			return fmt.Errorf("failed to backfill document %s: %w", doc.ID, err)
+		}
+	}
+	if len(docs) > 0 {
+		log.Printf("Migrated namespace '%s': set updated_at_nano on %d document(s)", s.namespace, len(docs))
+	}
 	return nil
 }
 
+func (s *Storage) initIndexes(indexes []Index) error {
+	return applyIndexes(s.db, s.namespace, indexes)
+}
+
 func (s *Storage) Close() error {
 	if s.db != nil {
 		s.db.Close()
@@ -55,10 +114,28 @@ func (s *Storage) Close() error {
 	return nil
 }
 
+// Create inserts doc and fails with a conflict error when its ID is taken.
 func (s *Storage) Create(ctx context.Context, doc *model.Document) error {
-	if res, err := s.db.Insert(s.namespace, doc); err != nil && res == 0 {
+	stampUpdatedAtNano(doc)
+	res, err := s.db.Insert(s.namespace, doc)
+	return insertResult(doc.ID, res, err)
+}
+
+// stampUpdatedAtNano syncs the numeric copy of UpdatedAt that updated_since
+// filters on. Every write path calls it.
+func stampUpdatedAtNano(doc *model.Document) {
+	doc.UpdatedAtNano = doc.UpdatedAt.UnixNano()
+}
+
+// insertResult interprets the result of Insert, which reports a duplicate
+// primary key as zero inserted items rather than as an error.
+func insertResult(id string, res int, err error) error {
+	if err != nil {
 		return fmt.Errorf("failed to insert document: %w", err)
 	}
+	if res == 0 {
+		return apperror.Conflict(apperror.ResourceDocument, id)
+	}
 	return nil
 }
 
@@ -72,20 +149,171 @@ func (s *Storage) GetByID(ctx context.Context, id string) (*model.Document, erro
 	defer it.Close()
 
 	if !it.Next() {
-		return nil, fmt.Errorf("document not found")
+		if err := it.Error(); err != nil {
+			return nil, fmt.Errorf("failed query Reindexer: %w", err)
+		}
+		return nil, apperror.NotFound(apperror.ResourceDocument, id)
+	}
+
+	doc, err := toDocument(it.Object())
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// GetByIDs fetches the documents with the given IDs in the order of ids.
+// IDs that do not exist are skipped.
+func (s *Storage) GetByIDs(ctx context.Context, ids []string) ([]model.Document, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	query := s.db.Query(s.namespace).
+		SetContext(ctx).
+		Where("id", reindexer.SET, ids)
+
+	it := query.Exec()
+	defer it.Close()
+
+	if err := it.Error(); err != nil {
+		return nil, fmt.Errorf("failed query Reindexer: %w", err)
+	}
+
+	documents := make([]model.Document, 0, len(ids))
+	for it.Next() {
+		doc, err := toDocument(it.Object())
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, *doc)
+	}
+
+	if it.Error() != nil {
+		return nil, fmt.Errorf("failed while iterating document: %w", it.Error())
 	}
 
-	doc := it.Object().(*model.Document)
+	return orderByIDs(ids, documents), nil
+}
+
+// toDocument asserts a Reindexer iterator object to the namespace item type.
+// A mismatch means the namespace was registered with another type and is
+// reported as an error rather than a panic.
+func toDocument(obj interface{}) (*model.Document, error) {
+	doc, ok := obj.(*model.Document)
+	if !ok || doc == nil {
+		return nil, fmt.Errorf("unexpected type %T, want *model.Document", obj)
+	}
 	return doc, nil
 }
 
+// orderByIDs arranges documents to follow ids, dropping IDs without a match.
+func orderByIDs(ids []string, documents []model.Document) []model.Document {
+	byID := make(map[string]model.Document, len(documents))
+	for _, doc := range documents {
+		byID[doc.ID] = doc
+	}
+
+	ordered := make([]model.Document, 0, len(documents))
+	for _, id := range ids {
+		if doc, ok := byID[id]; ok {
+			ordered = append(ordered, doc)
+			delete(byID, id)
+		}
+	}
+	return ordered
+}
+
+// GetMeta loads only the fields needed to answer existence checks.
+func (s *Storage) GetMeta(ctx context.Context, id string) (*model.DocumentMeta, error) {
+	query := s.db.Query(s.namespace).
+		SetContext(ctx).
+		Select("id", "updated_at").
+		Where("id", reindexer.EQ, id).
+		Limit(1)
+
+	it := query.Exec()
+	defer it.Close()
+
+	if !it.Next() {
+		if err := it.Error(); err != nil {
+			return nil, fmt.Errorf("failed query Reindexer: %w", err)
+		}
+		return nil, apperror.NotFound(apperror.ResourceDocument, id)
+	}
+
+	doc, err := toDocument(it.Object())
+	if err != nil {
+		return nil, err
+	}
+	return &model.DocumentMeta{ID: doc.ID, UpdatedAt: doc.UpdatedAt}, nil
+}
+
+// Update replaces the stored document and fails with a not-found error when
+// it no longer exists.
 func (s *Storage) Update(ctx context.Context, doc *model.Document) error {
-	if res, err := s.db.Update(s.namespace, doc); err != nil && res == 0 {
+	stampUpdatedAtNano(doc)
+	res, err := s.db.Update(s.namespace, doc)
+	return updateResult(doc.ID, res, err)
+}
+
+// updateResult interprets the result of Update, which reports a missing
+// primary key as zero updated items rather than as an error.
+func updateResult(id string, res int, err error) error {
+	if err != nil {
 		return fmt.Errorf("failed to update document: %w", err)
 	}
+	if res == 0 {
+		return apperror.NotFound(apperror.ResourceDocument, id)
+	}
 	return nil
 }
 
+// UpdateWhere applies set to every document matching filter server-side,
+// without loading the documents, and returns the IDs that were updated.
+func (s *Storage) UpdateWhere(ctx context.Context, filter, set map[string]string) ([]string, error) {
+	conditions, changes, err := resolveUpdateWhere(filter, set)
+	if err != nil {
+		return nil, err
+	}
+
+	query := s.db.Query(s.namespace).
+		SetContext(ctx)
+
+	for _, c := range conditions {
+		query = query.Where(c.field, reindexer.EQ, c.value)
+	}
+	for _, c := range changes {
+		query = query.Set(c.field, c.value)
+	}
+	now := time.Now().UTC()
+	query = query.
+		Set("updated_at", now.Format(time.RFC3339Nano)).
+		Set("updated_at_nano", now.UnixNano())
+
+	it := query.Update()
+	defer it.Close()
+
+	if err := it.Error(); err != nil {
+		return nil, fmt.Errorf("failed to update documents: %w", err)
+	}
+
+	ids := make([]string, 0, it.Count())
+	for it.Next() {
+		doc, err := toDocument(it.Object())
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, doc.ID)
+	}
+
+	if it.Error() != nil {
+		return nil, fmt.Errorf("failed while iterating document: %w", it.Error())
+	}
+
+	return ids, nil
+}
+
 func (s *Storage) Delete(ctx context.Context, id string) error {
 	query := s.db.Query(s.namespace).
 		SetContext(ctx).
@@ -97,13 +325,53 @@ func (s *Storage) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
-func (s *Storage) List(ctx context.Context, params model.PaginationParams) ([]model.Document, int, error) {
-	query := s.db.Query(s.namespace).
+// DeleteByIDs deletes the documents with the given IDs and returns the IDs
+// that existed.
+func (s *Storage) DeleteByIDs(ctx context.Context, ids []string) ([]string, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	it := s.db.Query(s.namespace).
 		SetContext(ctx).
-		Sort("created_at", desc).
-		Limit(params.PerPage).
-		Offset(params.GetOffset()).
-		ReqTotal()
+		Select("id").
+		Where("id", reindexer.SET, ids).
+		Exec()
+	defer it.Close()
+
+	if err := it.Error(); err != nil {
+		return nil, fmt.Errorf("failed query Reindexer: %w", err)
+	}
+
+	found := make([]string, 0, it.Count())
+	for it.Next() {
+		doc, err := toDocument(it.Object())
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, doc.ID)
+	}
+	if it.Error() != nil {
+		return nil, fmt.Errorf("failed while iterating document: %w", it.Error())
+	}
+	if len(found) == 0 {
+		return found, nil
+	}
+
+	if _, err := s.db.Query(s.namespace).
+		SetContext(ctx).
+		Where("id", reindexer.SET, found).
+		Delete(); err != nil {
+		return nil, fmt.Errorf("failed to delete documents: %w", err)
+	}
+	return found, nil
+}
+
+func (s *Storage) List(ctx context.Context, params model.PaginationParams) ([]model.Document, int, error) {
+	query, err := s.pageQuery(ctx, params)
+	if err != nil {
+		return nil, 0, err
+	}
 
 	it := query.Exec()
 
@@ -117,9 +385,9 @@ func (s *Storage) List(ctx context.Context, params model.PaginationParams) ([]mo
 	var documents []model.Document
 
 	for it.Next() {
-		doc, ok := it.Object().(*model.Document)
-		if !ok {
-			return nil, 0, fmt.Errorf("unexpected type %T", it.Object())
+		doc, err := toDocument(it.Object())
+		if err != nil {
+			return nil, 0, err
 		}
 		documents = append(documents, *doc)
 	}
@@ -131,6 +399,112 @@ func (s *Storage) List(ctx context.Context, params model.PaginationParams) ([]mo
 	return documents, totalCount, nil
 }
 
+// secondLevelStatusPath addresses the status of every second-level item. A
+// condition on it matches documents where any of them has the value.
+const secondLevelStatusPath = "items.second_level.status"
+
+// listQuery builds the filtered and sorted query behind List.
+func (s *Storage) listQuery(ctx context.Context, params model.PaginationParams) (*reindexer.Query, error) {
+	sortField, sortDesc := "created_at", desc
+	if params.SortBy != "" {
+		index, err := resolveSortField(params.SortBy)
+		if err != nil {
+			return nil, err
+		}
+		sortField, sortDesc = index, params.SortDesc
+	} else if !params.UpdatedSince.IsZero() {
+		sortField = "updated_at_nano"
+	}
+
+	query := s.db.Query(s.namespace).
+		SetContext(ctx).
+		Select(publicFields...)
+
+	if !params.UpdatedSince.IsZero() {
+		// Строки RFC3339Nano с разными смещениями и обрезанными нулями
+		// не сравниваются лексикографически, поэтому фильтр идёт по числу
+		query = query.Where("updated_at_nano", reindexer.GT, params.UpdatedSince.UnixNano())
+	}
+	if params.HasStatus != "" {
+		// Неиндексированное поле во вложенном массиве: Reindexer проверяет его
+		// перебором документов, но пагинация и total остаются точными
+		query = query.Where(secondLevelStatusPath, reindexer.EQ, params.HasStatus)
+	}
+	if params.Tag != "" {
+		query = query.Where("tags", reindexer.SET, []string{params.Tag})
+	}
+
+	return query.Sort(sortField, sortDesc), nil
+}
+
+// pageQuery is listQuery limited to the requested page.
+func (s *Storage) pageQuery(ctx context.Context, params model.PaginationParams) (*reindexer.Query, error) {
+	query, err := s.listQuery(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return query.
+		Limit(params.PerPage).
+		Offset(params.GetOffset()).
+		ReqTotal(), nil
+}
+
+// Stream calls fn for every document matching the List filter and sort, one
+// at a time, without a page limit. Iteration stops at the first error.
+func (s *Storage) Stream(ctx context.Context, params model.PaginationParams, fn func(doc *model.Document) error) error {
+	query, err := s.listQuery(ctx, params)
+	if err != nil {
+		return err
+	}
+
+	it := query.Exec()
+	defer it.Close()
+
+	if err := it.Error(); err != nil {
+		return fmt.Errorf("failed query Reindexer: %w", err)
+	}
+
+	for it.Next() {
+		doc, err := toDocument(it.Object())
+		if err != nil {
+			return err
+		}
+		if err := fn(doc); err != nil {
+			return err
+		}
+	}
+
+	if it.Error() != nil {
+		return fmt.Errorf("failed while iterating document: %w", it.Error())
+	}
+	return nil
+}
+
+// ExplainList runs the List query with explain enabled and returns its plan.
+func (s *Storage) ExplainList(ctx context.Context, params model.PaginationParams) (*model.QueryExplain, error) {
+	query, err := s.pageQuery(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	it := query.Explain().Exec()
+	defer it.Close()
+
+	if err := it.Error(); err != nil {
+		return nil, fmt.Errorf("failed query Reindexer: %w", err)
+	}
+
+	explain, err := it.GetExplainResults()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read explain results: %w", err)
+	}
+	if explain == nil {
+		return nil, fmt.Errorf("reindexer returned no explain results")
+	}
+	return toQueryExplain(explain), nil
+}
+
 func (s *Storage) CheckConnection(ctx context.Context) error {
 	query := s.db.Query(s.namespace).SetContext(ctx).
 		Limit(1)
@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/fedorovmatvey/involta-test/internal/apperror"
+	"github.com/fedorovmatvey/involta-test/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func documentIDs(docs []model.Document) []string {
+	ids := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		ids = append(ids, doc.ID)
+	}
+	return ids
+}
+
+func TestOrderByIDs_PreservesInputOrder(t *testing.T) {
+	docs := []model.Document{{ID: "b"}, {ID: "c"}, {ID: "a"}}
+
+	ordered := orderByIDs([]string{"a", "b", "c"}, docs)
+
+	assert.Equal(t, []string{"a", "b", "c"}, documentIDs(ordered))
+}
+
+func TestOrderByIDs_SkipsUnknownIDs(t *testing.T) {
+	docs := []model.Document{{ID: "c"}, {ID: "a"}}
+
+	ordered := orderByIDs([]string{"missing", "c", "b", "a", "c"}, docs)
+
+	assert.Equal(t, []string{"c", "a"}, documentIDs(ordered))
+}
+
+func TestToDocument(t *testing.T) {
+	doc, err := toDocument(&model.Document{ID: "doc-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "doc-1", doc.ID)
+
+	type legacyDocument struct{ ID string }
+	for _, obj := range []interface{}{&legacyDocument{ID: "doc-1"}, model.Document{}, (*model.Document)(nil), nil} {
+		assert.NotPanics(t, func() {
+			_, err := toDocument(obj)
+			assert.ErrorContains(t, err, "unexpected type")
+		})
+	}
+}
+
+func TestInsertResult(t *testing.T) {
+	assert.NoError(t, insertResult("doc-1", 1, nil))
+
+	var conflict *apperror.ConflictError
+	assert.ErrorAs(t, insertResult("doc-1", 0, nil), &conflict)
+	assert.Equal(t, "doc-1", conflict.ID)
+
+	failure := errors.New("connection reset")
+	for _, res := range []int{0, 1} {
+		err := insertResult("doc-1", res, failure)
+		assert.ErrorIs(t, err, failure)
+		assert.False(t, errors.As(err, &conflict))
+	}
+}
+
+func TestUpdateResult(t *testing.T) {
+	assert.NoError(t, updateResult("doc-1", 1, nil))
+
+	var notFound *apperror.NotFoundError
+	assert.ErrorAs(t, updateResult("doc-1", 0, nil), &notFound)
+	assert.Equal(t, "doc-1", notFound.ID)
+
+	failure := errors.New("connection reset")
+	for _, res := range []int{0, 1} {
+		err := updateResult("doc-1", res, failure)
+		assert.ErrorIs(t, err, failure)
+		assert.False(t, errors.As(err, &notFound))
+	}
+}
+
+// updatedSinceCases mixes offsets and whole seconds, which RFC3339Nano
+// strings misorder: "12:00:00Z" sorts after "12:00:00.5Z", and "14:00:00+03:00"
+// after "12:00:00Z" although it is earlier.
+func updatedSinceCases() (since time.Time, newer, older []model.Document) {
+	moscow := time.FixedZone("MSK", 3*60*60)
+	since = time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	newer = []model.Document{
+		{ID: "later-whole-second", UpdatedAt: time.Date(2026, 1, 1, 12, 0, 1, 0, time.UTC)},
+		{ID: "later-offset", UpdatedAt: time.Date(2026, 1, 1, 15, 0, 0, 500, moscow)},
+	}
+	older = []model.Document{
+		{ID: "earlier-fraction", UpdatedAt: time.Date(2026, 1, 1, 11, 59, 59, 500_000_000, time.UTC)},
+		{ID: "earlier-offset", UpdatedAt: time.Date(2026, 1, 1, 14, 0, 0, 0, moscow)},
+		{ID: "equal", UpdatedAt: since},
+	}
+	return since, newer, older
+}
+
+func TestStampUpdatedAtNano_OrdersAcrossOffsets(t *testing.T) {
+	since, newer, older := updatedSinceCases()
+
+	for _, doc := range newer {
+		stampUpdatedAtNano(&doc)
+		assert.Greater(t, doc.UpdatedAtNano, since.UnixNano(), doc.ID)
+	}
+	for _, doc := range older {
+		stampUpdatedAtNano(&doc)
+		assert.LessOrEqual(t, doc.UpdatedAtNano, since.UnixNano(), doc.ID)
+	}
+}
+
+func TestStorage_List_UpdatedSince(t *testing.T) {
+	dsn := os.Getenv("REINDEXER_TEST_DSN")
+	if dsn == "" {
+		t.Skip("REINDEXER_TEST_DSN is not set")
+	}
+	store, err := New(dsn, "documents_test_updated_since", nil, false, NamespaceOptions{InMemory: true})
+	require.NoError(t, err)
+	defer store.Close()
+
+	since, newer, older := updatedSinceCases()
+	ctx := context.Background()
+	for _, doc := range append(slices.Clone(newer), older...) {
+		require.NoError(t, store.Create(ctx, &doc))
+	}
+
+	docs, total, err := store.List(ctx, model.PaginationParams{Page: 1, PerPage: 10, UpdatedSince: since})
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+	assert.ElementsMatch(t, []string{"later-whole-second", "later-offset"}, documentIDs(docs))
+}
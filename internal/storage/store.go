@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"github.com/fedorovmatvey/involta-test/internal/model"
+)
+
+// DocumentStore is the backend the service layer runs on. Storage implements
+// it on top of Reindexer; other drivers can be plugged in by implementing it
+// as well.
+type DocumentStore interface {
+	TxStore
+	GetByID(ctx context.Context, id string) (*model.Document, error)
+	GetByIDs(ctx context.Context, ids []string) ([]model.Document, error)
+	GetMeta(ctx context.Context, id string) (*model.DocumentMeta, error)
+	UpdateWhere(ctx context.Context, filter, set map[string]string) ([]string, error)
+	Delete(ctx context.Context, id string) error
+	DeleteByIDs(ctx context.Context, ids []string) ([]string, error)
+	List(ctx context.Context, params model.PaginationParams) ([]model.Document, int, error)
+	CheckConnection(ctx context.Context) error
+	WithTransaction(ctx context.Context, fn func(tx TxStore) error) error
+}
+
+var _ DocumentStore = (*Storage)(nil)
+
+// Streamer is implemented by stores that can iterate over a page of List
+// results without loading it whole.
+type Streamer interface {
+	Stream(ctx context.Context, params model.PaginationParams, fn func(doc *model.Document) error) error
+}
+
+// Explainer is implemented by stores that can report the plan of the List
+// query.
+type Explainer interface {
+	ExplainList(ctx context.Context, params model.PaginationParams) (*model.QueryExplain, error)
+}
+
+var errExplainUnsupported = errors.New("storage does not support query explain")
+
+// streamOrList streams the page from store, or hands fn the documents of a
+// List of the page when store cannot stream.
+func streamOrList(ctx context.Context, store DocumentStore, params model.PaginationParams, fn func(doc *model.Document) error) error {
+	if streamer, ok := store.(Streamer); ok {
+		return streamer.Stream(ctx, params, fn)
+	}
+
+	docs, _, err := store.List(ctx, params)
+	if err != nil {
+		return err
+	}
+	for i := range docs {
+		if err := fn(&docs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
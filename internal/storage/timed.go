@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/fedorovmatvey/involta-test/internal/model"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Timed is a DocumentStore decorator that records the latency of every
+// storage operation in a histogram labelled by operation. Failed calls are
+// recorded too, since slow failures are as interesting as slow successes.
+type Timed struct {
+	next     DocumentStore
+	duration *prometheus.HistogramVec
+}
+
+var _ DocumentStore = (*Timed)(nil)
+
+// NewTimed wraps next and registers its histogram on reg.
+func NewTimed(next DocumentStore, reg prometheus.Registerer) *Timed {
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "storage_operation_duration_seconds",
+		Help:    "Time spent in storage operations, by operation.",
+		Buckets: []float64{.0005, .001, .005, .01, .05, .1, .5, 1, 5},
+	}, []string{"operation"})
+	reg.MustRegister(duration)
+	return &Timed{next: next, duration: duration}
+}
+
+// observe starts a timer for operation; call the result when it is done.
+func (t *Timed) observe(operation string) func() {
+	timer := prometheus.NewTimer(t.duration.WithLabelValues(operation))
+	return func() { timer.ObserveDuration() }
+}
+
+func (t *Timed) Create(ctx context.Context, doc *model.Document) error {
+	defer t.observe("create")()
+	return t.next.Create(ctx, doc)
+}
+
+func (t *Timed) Update(ctx context.Context, doc *model.Document) error {
+	defer t.observe("update")()
+	return t.next.Update(ctx, doc)
+}
+
+func (t *Timed) GetByID(ctx context.Context, id string) (*model.Document, error) {
+	defer t.observe("get")()
+	return t.next.GetByID(ctx, id)
+}
+
+func (t *Timed) GetByIDs(ctx context.Context, ids []string) ([]model.Document, error) {
+	defer t.observe("get_many")()
+	return t.next.GetByIDs(ctx, ids)
+}
+
+func (t *Timed) GetMeta(ctx context.Context, id string) (*model.DocumentMeta, error) {
+	defer t.observe("get_meta")()
+	return t.next.GetMeta(ctx, id)
+}
+
+func (t *Timed) UpdateWhere(ctx context.Context, filter, set map[string]string) ([]string, error) {
+	defer t.observe("update_where")()
+	return t.next.UpdateWhere(ctx, filter, set)
+}
+
+func (t *Timed) Delete(ctx context.Context, id string) error {
+	defer t.observe("delete")()
+	return t.next.Delete(ctx, id)
+}
+
+func (t *Timed) DeleteByIDs(ctx context.Context, ids []string) ([]string, error) {
+	defer t.observe("delete_many")()
+	return t.next.DeleteByIDs(ctx, ids)
+}
+
+func (t *Timed) List(ctx context.Context, params model.PaginationParams) ([]model.Document, int, error) {
+	defer t.observe("list")()
+	return t.next.List(ctx, params)
+}
+
+// CheckConnection is not timed; health checks would drown out real traffic.
+func (t *Timed) CheckConnection(ctx context.Context) error {
+	return t.next.CheckConnection(ctx)
+}
+
+// WithTransaction records the whole transaction, including the time fn
+// spends between its writes.
+func (t *Timed) WithTransaction(ctx context.Context, fn func(tx TxStore) error) error {
+	defer t.observe("transaction")()
+	return t.next.WithTransaction(ctx, fn)
+}
+
+// Stream forwards to the wrapped store, falling back to a List of the page
+// when it cannot stream. The time fn spends on each document is left out of
+// the sample: it is mostly spent writing to the client, and would make the
+// histogram measure client bandwidth rather than storage.
+func (t *Timed) Stream(ctx context.Context, params model.PaginationParams, fn func(doc *model.Document) error) error {
+	start := time.Now()
+	var consumer time.Duration
+	err := streamOrList(ctx, t.next, params, func(doc *model.Document) error {
+		fnStart := time.Now()
+		defer func() { consumer += time.Since(fnStart) }()
+		return fn(doc)
+	})
+	t.duration.WithLabelValues("stream").Observe((time.Since(start) - consumer).Seconds())
+	return err
+}
+
+// ExplainList forwards to the wrapped store when it can explain queries.
+func (t *Timed) ExplainList(ctx context.Context, params model.PaginationParams) (*model.QueryExplain, error) {
+	explainer, ok := t.next.(Explainer)
+	if !ok {
+		return nil, errExplainUnsupported
+	}
+	defer t.observe("explain_list")()
+	return explainer.ExplainList(ctx, params)
+}
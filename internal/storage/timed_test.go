@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fedorovmatvey/involta-test/internal/model"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimed_RecordsOperations(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	s := NewTimed(&recordingStore{}, reg)
+	ctx := context.Background()
+
+	_ = s.Create(ctx, &model.Document{ID: "doc-1"})
+	_, _ = s.GetByID(ctx, "doc-1")
+	_, _ = s.GetByID(ctx, "doc-1")
+	_ = s.Update(ctx, &model.Document{ID: "doc-1"})
+	_, _, _ = s.List(ctx, model.PaginationParams{})
+	_ = s.Delete(ctx, "doc-1")
+
+	assert.Equal(t, 5, testutil.CollectAndCount(s.duration, "storage_operation_duration_seconds"))
+	for operation, count := range map[string]uint64{"create": 1, "get": 2, "update": 1, "list": 1, "delete": 1} {
+		assert.Equal(t, count, sampleCount(t, s, operation), operation)
+	}
+}
+
+func sampleCount(t *testing.T, s *Timed, operation string) uint64 {
+	t.Helper()
+	metric := &dto.Metric{}
+	if err := s.duration.WithLabelValues(operation).(prometheus.Histogram).Write(metric); err != nil {
+		t.Fatal(err)
+	}
+	return metric.GetHistogram().GetSampleCount()
+}
+
+func TestTimed_StreamExcludesConsumer(t *testing.T) {
+	s := NewTimed(&recordingStore{}, prometheus.NewRegistry())
+
+	err := s.Stream(context.Background(), model.PaginationParams{}, func(doc *model.Document) error {
+		time.Sleep(200 * time.Millisecond)
+		return nil
+	})
+	assert.NoError(t, err)
+
+	metric := &dto.Metric{}
+	assert.NoError(t, s.duration.WithLabelValues("stream").(prometheus.Histogram).Write(metric))
+	assert.Equal(t, uint64(1), metric.GetHistogram().GetSampleCount())
+	assert.Less(t, metric.GetHistogram().GetSampleSum(), 0.1, "time spent in the consumer must not be recorded")
+}
@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/fedorovmatvey/involta-test/internal/model"
+	"github.com/restream/reindexer/v3"
+)
+
+// TxStore is the set of write operations available inside a transaction.
+type TxStore interface {
+	Create(ctx context.Context, doc *model.Document) error
+	Update(ctx context.Context, doc *model.Document) error
+}
+
+type txStore struct {
+	tx *reindexer.Tx
+}
+
+func (t *txStore) Create(ctx context.Context, doc *model.Document) error {
+	stampUpdatedAtNano(doc)
+	if err := t.tx.Insert(doc); err != nil {
+		return fmt.Errorf("failed to insert document in transaction: %w", err)
+	}
+	return nil
+}
+
+func (t *txStore) Update(ctx context.Context, doc *model.Document) error {
+	stampUpdatedAtNano(doc)
+	if err := t.tx.Update(doc); err != nil {
+		return fmt.Errorf("failed to update document in transaction: %w", err)
+	}
+	return nil
+}
+
+// WithTransaction runs fn inside a Reindexer transaction. The transaction is
+// committed if fn returns nil and rolled back otherwise.
+func (s *Storage) WithTransaction(ctx context.Context, fn func(tx TxStore) error) error {
+	tx, err := s.db.WithContext(ctx).BeginTx(s.namespace)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if err := fn(&txStore{tx: tx}); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			log.Printf("Failed to rollback transaction: %v", rbErr)
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
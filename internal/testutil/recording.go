@@ -0,0 +1,143 @@
+// Package testutil holds helpers shared by tests across packages.
+package testutil
+
+import (
+	"context"
+	"sync"
+
+	"github.com/fedorovmatvey/involta-test/internal/model"
+	"github.com/fedorovmatvey/involta-test/internal/storage"
+)
+
+// Call is one recorded storage call. Args holds every argument except the
+// context, in declaration order.
+type Call struct {
+	Method string
+	Args   []any
+}
+
+// RecordingStore wraps a storage.DocumentStore and records every call before
+// delegating to it, so tests can assert on the queries the service builds.
+// Calls made through a transaction are recorded as well. Optional storage
+// capabilities such as streaming are not forwarded.
+type RecordingStore struct {
+	store storage.DocumentStore
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+var _ storage.DocumentStore = (*RecordingStore)(nil)
+
+func NewRecordingStore(store storage.DocumentStore) *RecordingStore {
+	return &RecordingStore{store: store}
+}
+
+// Calls returns a copy of the calls recorded so far.
+func (r *RecordingStore) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Call(nil), r.calls...)
+}
+
+// CallsTo returns the recorded calls of method.
+func (r *RecordingStore) CallsTo(method string) []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var calls []Call
+	for _, call := range r.calls {
+		if call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// Reset forgets the recorded calls.
+func (r *RecordingStore) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = nil
+}
+
+func (r *RecordingStore) record(method string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = append(r.calls, Call{Method: method, Args: args})
+}
+
+func (r *RecordingStore) Create(ctx context.Context, doc *model.Document) error {
+	r.record("Create", doc)
+	return r.store.Create(ctx, doc)
+}
+
+func (r *RecordingStore) Update(ctx context.Context, doc *model.Document) error {
+	r.record("Update", doc)
+	return r.store.Update(ctx, doc)
+}
+
+func (r *RecordingStore) GetByID(ctx context.Context, id string) (*model.Document, error) {
+	r.record("GetByID", id)
+	return r.store.GetByID(ctx, id)
+}
+
+func (r *RecordingStore) GetByIDs(ctx context.Context, ids []string) ([]model.Document, error) {
+	r.record("GetByIDs", ids)
+	return r.store.GetByIDs(ctx, ids)
+}
+
+func (r *RecordingStore) GetMeta(ctx context.Context, id string) (*model.DocumentMeta, error) {
+	r.record("GetMeta", id)
+	return r.store.GetMeta(ctx, id)
+}
+
+func (r *RecordingStore) UpdateWhere(ctx context.Context, filter, set map[string]string) ([]string, error) {
+	r.record("UpdateWhere", filter, set)
+	return r.store.UpdateWhere(ctx, filter, set)
+}
+
+func (r *RecordingStore) Delete(ctx context.Context, id string) error {
+	r.record("Delete", id)
+	return r.store.Delete(ctx, id)
+}
+
+func (r *RecordingStore) DeleteByIDs(ctx context.Context, ids []string) ([]string, error) {
+	r.record("DeleteByIDs", ids)
+	return r.store.DeleteByIDs(ctx, ids)
+}
+
+func (r *RecordingStore) List(ctx context.Context, params model.PaginationParams) ([]model.Document, int, error) {
+	r.record("List", params)
+	return r.store.List(ctx, params)
+}
+
+func (r *RecordingStore) CheckConnection(ctx context.Context) error {
+	r.record("CheckConnection")
+	return r.store.CheckConnection(ctx)
+}
+
+func (r *RecordingStore) WithTransaction(ctx context.Context, fn func(tx storage.TxStore) error) error {
+	r.record("WithTransaction")
+	return r.store.WithTransaction(ctx, func(tx storage.TxStore) error {
+		return fn(&recordingTx{tx: tx, recorder: r})
+	})
+}
+
+type recordingTx struct {
+	tx       storage.TxStore
+	recorder *RecordingStore
+}
+
+func (t *recordingTx) Create(ctx context.Context, doc *model.Document) error {
+	t.recorder.record("Create", doc)
+	return t.tx.Create(ctx, doc)
+}
+
+func (t *recordingTx) Update(ctx context.Context, doc *model.Document) error {
+	t.recorder.record("Update", doc)
+	return t.tx.Update(ctx, doc)
+}